@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"monitor-trade/config"
+	"monitor-trade/controller"
+	"monitor-trade/controller/binance"
+	"monitor-trade/model"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runBacktest 回测模式：monitor-trade backtest -monitors monitors.json -klines 'data/BTCUSDT-1m-*.csv'
+func runBacktest(args []string) {
+	flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+	monitorsFile := flags.String("monitors", "", "监控定义 JSON 文件，内容为监控数组，格式与 Redis 中保存的监控相同")
+	klines := flags.String("klines", "", "Binance 历史K线 CSV，逗号分隔，支持通配符")
+	ticks := flags.String("ticks", "", "行情录制文件，逗号分隔，支持通配符")
+	funding := flags.Float64("funding", 0, "评估资金费率条件时使用的固定资金费率(%)，与 FUNDING_RATE 和 funding 条件的单位相同，如 0.01 表示 0.01%")
+	asJSON := flags.Bool("json", false, "以 JSON 格式输出结果")
+	flags.Parse(args)
+
+	if *monitorsFile == "" || (*klines == "" && *ticks == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	monitors, err := readMonitors(*monitorsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	binanceController := binance.NewBinanceController()
+	var series map[string][]model.PairData
+	if *klines != "" {
+		series, err = binanceController.LoadKlineCSV(strings.Split(*klines, ",")...)
+	} else {
+		series, err = binanceController.LoadRecordedPairData(strings.Split(*ticks, ",")...)
+	}
+	if err != nil {
+		log.Fatalf("加载历史数据失败: %v", err)
+	}
+
+	report := controller.Backtest(config.LoadFromEnv(), monitors, series, *funding)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+		return
+	}
+	printBacktestReport(report)
+}
+
+// readMonitors 读取监控定义文件，内容为监控数组，格式与 Redis 中保存的监控相同
+func readMonitors(path string) ([]model.PairMonitorData, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取监控定义失败: %v", err)
+	}
+	var monitors []model.PairMonitorData
+	if err := json.Unmarshal(content, &monitors); err != nil {
+		return nil, fmt.Errorf("解析监控定义失败: %v", err)
+	}
+	return monitors, nil
+}
+
+// printBacktestReport 以表格输出回测结果
+func printBacktestReport(report model.BacktestReport) {
+	fmt.Printf("回放价格点: %d，触发: %d 次\n\n", report.Points, len(report.Fires))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "时间\t交易对\t方向\t档位\t限价\t入场价")
+	for _, horizon := range model.BacktestHorizons {
+		fmt.Fprintf(w, "\t%s", horizon.Name)
+	}
+	fmt.Fprintln(w)
+	for _, fire := range report.Fires {
+		rung := "-"
+		if fire.Rung > 0 {
+			rung = fmt.Sprintf("%d", fire.Rung)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.6f\t%.6f",
+			time.UnixMilli(fire.Time).Format("2006-01-02 15:04:05"), fire.Pair, fire.Direct, rung, fire.Level, fire.Price)
+		for _, horizon := range model.BacktestHorizons {
+			if ret, ok := fire.Returns[horizon.Name]; ok {
+				fmt.Fprintf(w, "\t%+.2f%%", ret)
+			} else {
+				fmt.Fprint(w, "\t-")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+
+	if len(report.NotFired) > 0 {
+		fmt.Println("\n未触发的监控:")
+		for _, data := range report.NotFired {
+			fmt.Printf("  %s %s 限价 %.6f\n", data.Pair, data.Direct, data.Price)
+		}
+	}
+	if len(report.Skipped) > 0 {
+		fmt.Println("\n跳过的监控:")
+		for _, data := range report.Skipped {
+			fmt.Printf("  %s %s 限价 %.6f\n", data.Pair, data.Direct, data.Price)
+		}
+	}
+}
@@ -6,14 +6,48 @@ import (
 )
 
 type Config struct {
-	Redis             RedisConfig `json:"redis"`          // Redis configuration
-	TelegramToken     string      `json:"telegram_token"` // Telegram configuration
-	TelegramId        int64       `json:"telegram_id"`    // Telegram configuration
-	FundingRate       float64     `json:"funding_rate"`   // Funding rate threshold
-	BotBaseUrl        string      `json:"freqtrade_base_url"`
-	BotUsername       string      `json:"bot_username"`
-	BotPasswd         string      `json:"bot_passwd"`
-	BotAdjustEntryTag string      `json:"bot_adjust_entry_tag"`
+	Redis             RedisConfig  `json:"redis"`          // Redis configuration
+	TelegramToken     string       `json:"telegram_token"` // Telegram configuration
+	TelegramId        int64        `json:"telegram_id"`    // Telegram configuration
+	FundingRate       float64      `json:"funding_rate"`   // Funding rate threshold
+	Exchange          string       `json:"exchange"`       // 行情数据源 binance / bybit
+	BotBaseUrl        string       `json:"freqtrade_base_url"`
+	BotUsername       string       `json:"bot_username"`
+	BotPasswd         string       `json:"bot_passwd"`
+	BotAdjustEntryTag string       `json:"bot_adjust_entry_tag"`
+	FeedStaleSeconds  int          `json:"feed_stale_seconds"` // 超过该秒数没有行情推送时重连并告警
+	PriceMaxAgeMs     int          `json:"price_max_age_ms"`   // 价格数据超过该毫秒数时不触发交易，0 表示不检查
+	Record            RecordConfig `json:"record"`             // 行情录制与回放配置
+	Paper             PaperConfig  `json:"paper"`              // 模拟交易配置
+	Risk              RiskConfig   `json:"risk"`               // 风控配置
+}
+
+// RecordConfig 行情录制与回放配置
+type RecordConfig struct {
+	Dir            string  `json:"dir"`             // 录制目录，为空时不录制
+	RotateMinutes  int     `json:"rotate_minutes"`  // 每个录制文件覆盖的分钟数
+	ReplayFiles    string  `json:"replay_files"`    // 回放的录制文件，逗号分隔，支持通配符；设置后不连接 Binance 行情
+	ReplaySpeed    float64 `json:"replay_speed"`    // 回放倍速，0 表示不等待直接回放
+	ReplayMonitors string  `json:"replay_monitors"` // 回放使用的监控定义 JSON 文件，格式与 backtest -monitors 相同
+	ReplayFunding  float64 `json:"replay_funding"`  // 回放时评估资金费率条件使用的固定资金费率(%)
+}
+
+// RiskConfig 开仓前的全局风控限制，为0表示不限制
+type RiskConfig struct {
+	MaxTotalStake     float64 `json:"max_total_stake"`      // 所有持仓的最大投入金额
+	MaxEntriesPerHour int     `json:"max_entries_per_hour"` // 每小时最多新开仓次数
+	MaxSameDirection  int     `json:"max_same_direction"`   // 同方向最多持仓数量
+	DailyLossLimit    float64 `json:"daily_loss_limit"`     // 当日已实现亏损上限（正数）
+	DefaultStake      float64 `json:"default_stake"`        // Freqtrade 金额为 unlimited 时，未指定金额的开仓计入总投入的金额
+}
+
+// PaperConfig 模拟交易配置，开启后交易请求不再发送到 Freqtrade
+type PaperConfig struct {
+	Enabled       bool    `json:"enabled"`
+	Fee           float64 `json:"fee"`             // 手续费率，按成交金额收取
+	StakeAmount   float64 `json:"stake_amount"`    // 未指定金额时每笔开仓的金额
+	MaxOpenTrades int     `json:"max_open_trades"` // 最大持仓数量
+	Whitelist     string  `json:"whitelist"`       // 监听的交易对，逗号分隔，如 BTC/USDT:USDT；模拟交易不登录 Freqtrade，不从 Freqtrade 获取白名单
 }
 
 type RedisConfig struct {
@@ -46,6 +80,20 @@ func getEnvInt(key string, defaultValue int) int {
 	return intValue
 }
 
+// getEnvBool 获取布尔类型环境变量，如不存在或格式错误则使用默认值
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	boolVal, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return boolVal
+}
+
 // getEnvFloat64
 func getEnvFloat64(key string, defaultValue float64) float64 {
 	value := os.Getenv(key)
@@ -73,10 +121,35 @@ func LoadFromEnv() *Config {
 		TelegramToken:     getEnvString("TELEGRAM_TOKEN", ""),
 		TelegramId:        int64(getEnvInt("TELEGRAM_ID", 0)),
 		FundingRate:       getEnvFloat64("FUNDING_RATE", -0.1),
+		Exchange:          getEnvString("EXCHANGE", "binance"),
 		BotBaseUrl:        getEnvString("BOT_BASE_URL", "http://127.0.0.1:8080"),
 		BotUsername:       getEnvString("BOT_USER_NAME", ""),
 		BotPasswd:         getEnvString("BOT_PASSWD", ""),
 		BotAdjustEntryTag: getEnvString("BOT_ADJUST_ENTRY_TAG", "grind_3_entry"),
+		FeedStaleSeconds:  getEnvInt("FEED_STALE_SECONDS", 30),
+		PriceMaxAgeMs:     getEnvInt("PRICE_MAX_AGE_MS", 5000),
+		Record: RecordConfig{
+			Dir:            getEnvString("RECORD_DIR", ""),
+			RotateMinutes:  getEnvInt("RECORD_ROTATE_MINUTES", 60),
+			ReplayFiles:    getEnvString("REPLAY_FILES", ""),
+			ReplaySpeed:    getEnvFloat64("REPLAY_SPEED", 1),
+			ReplayMonitors: getEnvString("REPLAY_MONITORS", ""),
+			ReplayFunding:  getEnvFloat64("REPLAY_FUNDING_RATE", 0),
+		},
+		Paper: PaperConfig{
+			Enabled:       getEnvBool("DRY_RUN", false),
+			Fee:           getEnvFloat64("PAPER_FEE", 0.0005),
+			StakeAmount:   getEnvFloat64("PAPER_STAKE_AMOUNT", 100),
+			MaxOpenTrades: getEnvInt("PAPER_MAX_OPEN_TRADES", 5),
+			Whitelist:     getEnvString("PAPER_WHITELIST", ""),
+		},
+		Risk: RiskConfig{
+			MaxTotalStake:     getEnvFloat64("RISK_MAX_TOTAL_STAKE", 0),
+			MaxEntriesPerHour: getEnvInt("RISK_MAX_ENTRIES_PER_HOUR", 0),
+			MaxSameDirection:  getEnvInt("RISK_MAX_SAME_DIRECTION", 0),
+			DailyLossLimit:    getEnvFloat64("RISK_DAILY_LOSS_LIMIT", 0),
+			DefaultStake:      getEnvFloat64("RISK_DEFAULT_STAKE", 0),
+		},
 	}
 	return config
 }
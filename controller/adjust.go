@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+)
+
+// HandleAdd 处理价格触发的加仓监控，满足条件时与入场监控一样交给交易通道执行 forceadjustbuy
+func (c *MainController) HandleAdd(pairData *model.PairData, lastPrice float64) {
+	addData, exists := c.RedisController.GetMonitorPair(pairData.Pair, tg.AddDirect)
+	if !exists {
+		return
+	}
+	if addData.Price <= 0 || !addData.HasTrade() || !addData.IsArmed() {
+		return
+	}
+
+	triggered, err := c.checkCondition(addData, pairData, lastPrice)
+	if err != nil {
+		log.Printf("交易对 %s 加仓条件评估失败: %v", pairData.Pair, err)
+		c.TgController.SendMessage(fmt.Sprintf("❌ %s 加仓操作失败: %v", pairData.Pair, err))
+		return
+	}
+	if !triggered || c.RedisController.OcoPeerPending(addData) {
+		return
+	}
+	// 关联交易的入场订单还未成交时不加仓，保持 armed 等待成交
+	if c.Freqtrade != nil {
+		if trade, exists := c.Freqtrade.GetOpenTrade(addData.TradeId); exists && trade.HasOpenEntryOrder() {
+			return
+		}
+	}
+	// 只有从 armed 切换到 triggered 成功的协程才能发出交易请求
+	if !c.RedisController.TransitionMonitorState(pairData.Pair, tg.AddDirect, model.MonitorStateTriggered, model.MonitorStateArmed) {
+		return
+	}
+
+	payload := model.ForceBuyPayload{
+		Pair:        pairData.Pair,
+		Price:       pairData.BidPrice,
+		Side:        addData.Side,
+		EntryTag:    c.Conf.BotAdjustEntryTag,
+		OrderType:   "limit",
+		StakeAmount: addData.StakeAmount,
+		Monitor:     tg.AddDirect,
+		Adjust:      true,
+		TradeId:     addData.TradeId,
+	}
+	if addData.Side == tg.ShortDirect {
+		payload.Price = pairData.AskPrice
+	}
+
+	log.Printf("时间戳 %s 交易对 %s 的%s加仓满足条件，限价 %.6f，金额 %.2f，执行加仓操作",
+		pairData.Timestamp, pairData.Pair, addData.Side, addData.Price, addData.StakeAmount)
+
+	c.submitTrade(payload)
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+)
+
+// HandleAlert 处理只提醒不交易的监控，触发时直接发送 Telegram 通知，
+// 不经过交易通道，因此不会获取交易锁，也不占用仓位限制。
+// 只有价格从设置时记录的一侧越过提醒价才触发，价格停留在提醒价另一侧（如重复提醒重新布防后）不会反复提醒
+func (c *MainController) HandleAlert(pairData *model.PairData, lastPrice float64, direct string) {
+	alertData, exists := c.RedisController.GetMonitorPair(pairData.Pair, direct)
+	if !exists || alertData.Price <= 0 {
+		return
+	}
+
+	// 重复提醒：距离上次提醒超过间隔后重新布防
+	if alertData.State == model.MonitorStateTriggered && alertData.RepeatSeconds > 0 {
+		if c.now().Unix()-alertData.StateTime < int64(alertData.RepeatSeconds) {
+			return
+		}
+		if !c.RedisController.TransitionMonitorState(pairData.Pair, direct, model.MonitorStateArmed, model.MonitorStateTriggered) {
+			return
+		}
+		alertData.State = model.MonitorStateArmed
+	}
+	if !alertData.IsArmed() {
+		return
+	}
+
+	from, to := model.PriceSideBelow, model.PriceSideAbove
+	if direct == tg.AlertDownDirect {
+		from, to = model.PriceSideAbove, model.PriceSideBelow
+	}
+	mid := midPrice(pairData)
+	side := model.PriceSideOf(mid, alertData.Price)
+	if alertData.PriceSide != from {
+		// 价格回到起始一侧后才能再次触发；没有记录位置的旧监控从当前价格开始记录
+		if side == from || (alertData.PriceSide == "" && side != "") {
+			c.RedisController.UpdateMonitorPriceSide(pairData.Pair, direct, side)
+		}
+		return
+	}
+	if mid <= 0 || side == from {
+		return
+	}
+
+	triggered, err := c.checkCondition(alertData, pairData, lastPrice)
+	if err != nil {
+		log.Printf("交易对 %s 提醒条件评估失败: %v", pairData.Pair, err)
+		return
+	}
+	if !triggered {
+		return
+	}
+	if !c.RedisController.TransitionMonitorState(pairData.Pair, direct, model.MonitorStateTriggered, model.MonitorStateArmed) {
+		return
+	}
+
+	action := "上穿"
+	if direct == tg.AlertDownDirect {
+		action = "下穿"
+	}
+	log.Printf("时间戳 %s 交易对 %s 价格%s提醒价 %.6f", pairData.Timestamp, pairData.Pair, action, alertData.Price)
+
+	resultMsg := fmt.Sprintf("🔔 %s 价格%s %.6f，当前买价: %.6f，卖价: %.6f",
+		pairData.Pair, action, alertData.Price, pairData.BidPrice, pairData.AskPrice)
+	if alertData.RepeatSeconds > 0 {
+		resultMsg += fmt.Sprintf("，%d 秒后价格重新%s时再次提醒", alertData.RepeatSeconds, action)
+		c.RedisController.UpdateMonitorPriceSide(pairData.Pair, direct, to)
+	} else {
+		// 只提醒一次的监控触发后删除
+		c.RedisController.DeleteMonitorPair(pairData.Pair, direct)
+	}
+	c.TgController.SendMessage(resultMsg)
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"log"
+	"monitor-trade/model"
+)
+
+// refreshATRLevel 按最新的 ATR 重新计算限价，限价变化时同步保存到Redis。
+// 本地K线不足时沿用之前保存的限价
+func (c *MainController) refreshATRLevel(data *model.PairMonitorData) {
+	if data.ATRMultiple == 0 {
+		return
+	}
+	atr, ok := c.RedisController.GetATR(data.Pair, data.ATRTimeframe)
+	if !ok {
+		return
+	}
+	level := c.Feed.RoundPrice(data.Pair, data.ATRLevel(atr))
+	if level <= 0 || level == data.Price {
+		return
+	}
+	if c.RedisController.UpdateMonitorPrice(data.Pair, data.Direct, level) {
+		log.Printf("交易对 %s %s ATR(%s) 变为 %.6f，限价 %.6f -> %.6f", data.Pair, data.Direct, data.ATRTimeframe, atr, data.Price, level)
+	}
+	data.Price = level
+}
+
+// LoadATRCandles 启动时为 ATR 限价的监控拉取历史K线，避免等待本地K线积累
+func (c *MainController) LoadATRCandles() {
+	loaded := make(map[string]bool)
+	for _, data := range c.RedisController.ListMonitorPairs() {
+		if data.ATRMultiple == 0 || model.IsSyntheticPair(data.Pair) {
+			continue
+		}
+		key := data.Pair + ":" + data.ATRTimeframe
+		if loaded[key] {
+			continue
+		}
+		loaded[key] = true
+		if err := c.Feed.LoadCandles(data.Pair, data.ATRTimeframe); err != nil {
+			log.Printf("加载 %s %s 历史K线失败: %v", data.Pair, data.ATRTimeframe, err)
+		}
+	}
+}
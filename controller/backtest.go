@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"log"
+	"monitor-trade/config"
+	"monitor-trade/controller/binance"
+	"monitor-trade/controller/redis"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+	"sort"
+	"time"
+)
+
+// Backtest 用历史价格回放入场监控，返回每次触发的时间、价格以及之后 1h/4h/24h 的收益。
+// 触发判断直接调用 HandleShort/HandleLong，与实时行情完全相同；监控只保存在本地，不连接 Redis、Binance 和 Freqtrade。
+// series 为各交易对按时间排序的价格数据，fundingRate 为评估资金费率条件时使用的固定资金费率(%)
+func Backtest(conf *config.Config, monitors []model.PairMonitorData, series map[string][]model.PairData, fundingRate float64) model.BacktestReport {
+	var report model.BacktestReport
+
+	byPair := make(map[string][]model.PairMonitorData)
+	for _, data := range monitors {
+		if data.Direct != tg.ShortDirect && data.Direct != tg.LongDirect || model.IsSyntheticPair(data.Pair) {
+			log.Printf("回测只支持普通交易对的做空/做多监控，跳过 %s %s", data.Pair, data.Direct)
+			report.Skipped = append(report.Skipped, data)
+			continue
+		}
+		if len(series[data.Pair]) == 0 {
+			log.Printf("交易对 %s 没有历史数据，跳过", data.Pair)
+			report.Skipped = append(report.Skipped, data)
+			continue
+		}
+		byPair[data.Pair] = append(byPair[data.Pair], data)
+	}
+	pairs := make([]string, 0, len(byPair))
+	for pair := range byPair {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	redisController := redis.NewLocalRedisController(conf)
+	tradeChan := make(chan model.ForceBuyPayload, 100)
+	c := NewMainController(nil, redisController, conf, binance.NewBinanceController(), nil, tradeChan)
+	var now time.Time
+	c.clock = func() time.Time { return now }
+	c.funding = func(string) (float64, error) { return fundingRate, nil }
+
+	fired := make(map[string]bool)
+	for _, pair := range pairs {
+		for _, data := range byPair[pair] {
+			redisController.SetLocalMonitorPair(rearmForBacktest(data))
+		}
+
+		points := series[pair]
+		lastPrice := 0.0
+		for i := range points {
+			pairData := points[i]
+			now = time.UnixMilli(pairData.EventTime)
+			redisController.UpdateCandles(pair, &pairData, now)
+			c.HandleShort(&pairData, lastPrice)
+			c.HandleLong(&pairData, lastPrice)
+			for drained := false; !drained; {
+				select {
+				case payload := <-tradeChan:
+					fire := c.backtestFire(payload, now)
+					fire.Returns = forwardReturns(fire, points[i:])
+					report.Fires = append(report.Fires, fire)
+					fired[payload.Pair+":"+payload.Side] = true
+					// 回测没有 Freqtrade，触发即视为交易被接受并成交：取消 OCO 关联的另一方，分批入场的档位标记为成交
+					redisController.CancelOcoPeer(payload.Pair, payload.Side)
+					if payload.Rung > 0 {
+						redisController.TransitionRungState(payload.Pair, payload.Side, payload.Rung-1, model.MonitorStateFilled, model.MonitorStateTriggered)
+					}
+				default:
+					drained = true
+				}
+			}
+			lastPrice = midPrice(&pairData)
+		}
+		report.Points += len(points)
+
+		for _, data := range byPair[pair] {
+			if !fired[data.Pair+":"+data.Direct] {
+				report.NotFired = append(report.NotFired, data)
+			}
+		}
+	}
+	return report
+}
+
+// rearmForBacktest 将监控恢复为刚设置时的状态，从历史数据的第一条开始评估
+func rearmForBacktest(data model.PairMonitorData) model.PairMonitorData {
+	data.State = model.MonitorStateArmed
+	data.StateTime = 0
+	data.TrailExtreme = 0
+	if len(data.Ladder) > 0 {
+		ladder := make([]model.LadderRung, len(data.Ladder))
+		copy(ladder, data.Ladder)
+		for i := range ladder {
+			ladder[i].State = model.MonitorStateArmed
+			ladder[i].StateTime = 0
+		}
+		data.Ladder = ladder
+	}
+	return data
+}
+
+// backtestFire 根据提交的交易请求生成触发记录，限价取触发时的监控限价（ATR 限价为当时重新计算后的值）
+func (c *MainController) backtestFire(payload model.ForceBuyPayload, now time.Time) model.BacktestFire {
+	fire := model.BacktestFire{
+		Pair:   payload.Pair,
+		Direct: payload.Side,
+		Rung:   payload.Rung,
+		Time:   now.UnixMilli(),
+		Price:  payload.Price,
+	}
+	if data, exists := c.RedisController.GetMonitorPair(payload.Pair, payload.Side); exists {
+		fire.Level = data.Price
+		if payload.Rung > 0 && payload.Rung <= len(data.Ladder) {
+			fire.Level = data.Ladder[payload.Rung-1].Price
+		}
+	}
+	return fire
+}
+
+// forwardReturns 计算触发后各时长的收益率，取到达该时长后第一条价格的中间价，做空时收益取反
+func forwardReturns(fire model.BacktestFire, points []model.PairData) map[string]float64 {
+	returns := make(map[string]float64, len(model.BacktestHorizons))
+	if fire.Price <= 0 {
+		return returns
+	}
+	for _, horizon := range model.BacktestHorizons {
+		target := fire.Time + horizon.Duration.Milliseconds()
+		i := sort.Search(len(points), func(i int) bool {
+			return points[i].EventTime >= target
+		})
+		if i == len(points) {
+			continue
+		}
+		ret := (midPrice(&points[i])/fire.Price - 1) * 100
+		if fire.Direct == tg.ShortDirect {
+			ret = -ret
+		}
+		returns[horizon.Name] = ret
+	}
+	return returns
+}
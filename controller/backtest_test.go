@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"math"
+	"monitor-trade/config"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+	"testing"
+	"time"
+)
+
+// TestBacktest 测试回测只触发一次做空，计算之后的收益，并区分未触发和跳过的监控
+func TestBacktest(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pair := "BTC/USDT:USDT"
+	mids := []float64{100, 102, 106, 108, 104, 103}
+	var points []model.PairData
+	for i, mid := range mids {
+		ts := start.Add(time.Duration(i) * 30 * time.Minute).UnixMilli()
+		points = append(points, model.PairData{Pair: pair, BidPrice: mid, AskPrice: mid, Close: mid, EventTime: ts, ReceiveTime: ts})
+	}
+
+	level := &model.Condition{Type: model.ConditionLevel}
+	monitors := []model.PairMonitorData{
+		{Pair: pair, Direct: tg.ShortDirect, Price: 105, Condition: level, State: model.MonitorStateTriggered},
+		{Pair: pair, Direct: tg.LongDirect, Price: 90},
+		{Pair: pair, Direct: tg.AlertUpDirect, Price: 110},
+		{Pair: "ETH/USDT:USDT", Direct: tg.ShortDirect, Price: 3000},
+	}
+
+	conf := &config.Config{FundingRate: -0.1}
+	report := Backtest(conf, monitors, map[string][]model.PairData{pair: points}, 0)
+
+	if report.Points != len(points) {
+		t.Errorf("期望回放 %d 个价格点，实际 %d", len(points), report.Points)
+	}
+	if len(report.Fires) != 1 {
+		t.Fatalf("期望触发 1 次，实际 %+v", report.Fires)
+	}
+	fire := report.Fires[0]
+	if fire.Direct != tg.ShortDirect || fire.Price != 106 || fire.Level != 105 || fire.Time != points[2].EventTime {
+		t.Errorf("触发记录错误: %+v", fire)
+	}
+	// 1h 后的价格为 104，做空收益约 +1.89%
+	if ret, ok := fire.Returns["1h"]; !ok || math.Abs(ret-(1-104.0/106)*100) > 1e-9 {
+		t.Errorf("1h 收益错误: %v", fire.Returns)
+	}
+	if _, ok := fire.Returns["4h"]; ok {
+		t.Error("历史数据不足 4h 时不应有收益")
+	}
+
+	if len(report.NotFired) != 1 || report.NotFired[0].Direct != tg.LongDirect {
+		t.Errorf("未触发的监控错误: %+v", report.NotFired)
+	}
+	if len(report.Skipped) != 2 {
+		t.Errorf("期望跳过提醒和没有数据的监控，实际 %+v", report.Skipped)
+	}
+}
+
+// TestBacktestConfirmUsesDataTime 测试确认窗口按历史数据的时间计算，而不是回测运行的时间
+func TestBacktestConfirmUsesDataTime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pair := "ETH/USDT:USDT"
+	var points []model.PairData
+	for i := 0; i < 4; i++ {
+		ts := start.Add(time.Duration(i) * 10 * time.Minute).UnixMilli()
+		points = append(points, model.PairData{Pair: pair, BidPrice: 2990, AskPrice: 2991, EventTime: ts, ReceiveTime: ts})
+	}
+
+	monitors := []model.PairMonitorData{
+		{Pair: pair, Direct: tg.LongDirect, Price: 3000, ConfirmSeconds: 1200},
+	}
+	report := Backtest(&config.Config{FundingRate: -0.1}, monitors, map[string][]model.PairData{pair: points}, 0)
+	if len(report.Fires) != 1 || report.Fires[0].Time != points[2].EventTime {
+		t.Errorf("期望在持续 20 分钟后的第 3 个价格点触发，实际 %+v", report.Fires)
+	}
+}
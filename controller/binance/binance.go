@@ -4,26 +4,52 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"monitor-trade/controller/feed"
 	"monitor-trade/controller/redis"
 	"monitor-trade/model"
 	"net/http"
-	"net/url"
 	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/gorilla/websocket"
 )
 
+// BinanceController Binance U本位永续合约行情源
 type BinanceController struct {
-	conn               *websocket.Conn
 	redisController    *redis.RedisController
 	changePairDataChan chan model.PairData
+	messageChan        chan string // Telegram 告警通道
 	ctx                context.Context
 	cancel             context.CancelFunc
 	httpClient         *http.Client // HTTP客户端用于REST API调用
+
+	mutexShards sync.Mutex            // 保护 shards、streamShard 和 watching
+	shards      []*feedShard          // 行情推送连接，每条连接订阅不超过 maxStreams 个 stream
+	streamShard map[string]*feedShard // stream -> 订阅它的连接
+	nextShardID int
+	watching    bool           // Watch 是否已启动，启动前只记录订阅不建立连接
+	wgShards    sync.WaitGroup // 等待所有连接协程退出
+	lastTick    atomic.Int64   // 任意连接最近一次收到推送的时间（Unix纳秒）
+
+	recorder  *TickRecorder // 不为空时录制收到的原始推送
+	replaying bool          // 已切换为回放模式，通知通道满时等待而不是丢弃；与 Watch 互斥，切换后不能恢复
+
+	mutexSymbols sync.RWMutex          // 保护 registry
+	registry     *model.SymbolRegistry // 交易对与合约名称的映射及交易规则，来自 exchangeInfo
+
+	wsURL             string        // 行情推送地址
+	maxStreams        int           // 单条连接最多订阅的 stream 数
+	subscribeInterval time.Duration // 两次更新订阅之间的最短间隔，避免超过控制消息频率限制
+	pingInterval      time.Duration // 发送 ping 的间隔
+	readTimeout       time.Duration // 读超时，收到消息或 pong 后顺延
+	staleTimeout      time.Duration // 所有连接都超过该时长没有收到推送视为行情停滞
+	maxConnAge        time.Duration // 连接的最长使用时间，在 Binance 24 小时断开前主动重连
+	minBackoff        time.Duration // 重连等待的初始时长
+	maxBackoff        time.Duration // 重连等待的最长时长
 }
 
+var _ feed.PriceFeed = (*BinanceController)(nil)
+
 func NewBinanceController() *BinanceController {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &BinanceController{
@@ -31,6 +57,17 @@ func NewBinanceController() *BinanceController {
 		ctx:                ctx,
 		cancel:             cancel,
 		httpClient:         &http.Client{Timeout: 10 * time.Second},
+		streamShard:        make(map[string]*feedShard),
+		registry:           model.NewSymbolRegistry(nil),
+		wsURL:              "wss://fstream.binance.com/ws",
+		maxStreams:         200,
+		subscribeInterval:  time.Second,
+		pingInterval:       30 * time.Second,
+		readTimeout:        90 * time.Second,
+		staleTimeout:       30 * time.Second,
+		maxConnAge:         23 * time.Hour,
+		minBackoff:         time.Second,
+		maxBackoff:         time.Minute,
 	}
 }
 
@@ -39,72 +76,15 @@ func (b *BinanceController) SetRedisController(redisController *redis.RedisContr
 	b.redisController = redisController
 }
 
-// 连接到Binance WebSocket推送流
-func (b *BinanceController) Connect() error {
-	// 使用期货合约的全市场最优挂单信息流
-	wsURL := "wss://fstream.binance.com/ws/!bookTicker"
-
-	u, err := url.Parse(wsURL)
-	if err != nil {
-		return fmt.Errorf("解析WebSocket URL失败: %v", err)
-	}
-
-	log.Printf("正在连接到Binance期货合约最优挂单推送流 (每5秒推送): %s", wsURL)
-
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
-	if err != nil {
-		return fmt.Errorf("连接Binance期货WebSocket推送流失败: %v", err)
-	}
-
-	b.conn = conn
-	log.Println("成功连接到Binance期货合约最优挂单推送流")
-
-	return nil
-}
-
-// 启动价格监听
-func (b *BinanceController) Watch(changePairDataChan chan model.PairData) {
-	b.changePairDataChan = changePairDataChan
-
-	if err := b.Connect(); err != nil {
-		log.Printf("连接Binance失败: %v", err)
-		return
-	}
-
-	defer b.conn.Close()
-
-	// 启动重连机制
-	go b.handleReconnect()
-
-	log.Println("开始监听Binance最优挂单推送流...")
-
-	for {
-		select {
-		case <-b.ctx.Done():
-			return
-		default:
-			var ticker model.BookTickerData
-			err := b.conn.ReadJSON(&ticker)
-			if err != nil {
-				log.Printf("读取Binance推送数据失败: %v", err)
-				time.Sleep(time.Second)
-				continue
-			}
-
-			go b.processBookTicker(ticker)
-		}
-	}
+// SetHTTPClient 设置 REST 请求使用的 HTTP 客户端
+func (b *BinanceController) SetHTTPClient(client *http.Client) {
+	b.httpClient = client
 }
 
 // 处理BookTicker推送数据
 func (b *BinanceController) processBookTicker(ticker model.BookTickerData) {
-	// ticker pair 以USDT结尾的交易对
-	if !strings.HasSuffix(ticker.Symbol, "USDT") {
-		return
-	}
-
-	// 转换符号格式，从BTCUSDT到BTC/USDT
-	pair := b.formatPairSymbol(ticker.Symbol)
+	// 转换符号格式，从BTCUSDT到BTC/USDT:USDT
+	pair := b.Registry().Pair(ticker.Symbol)
 
 	// 解析价格数据
 	pairData, err := b.convertBookTickerToPairData(ticker)
@@ -112,48 +92,29 @@ func (b *BinanceController) processBookTicker(ticker model.BookTickerData) {
 		log.Printf("转换价格数据失败 %s: %v", pair, err)
 		return
 	}
+	b.publish(ticker, pairData)
+}
 
-	// 更新价格数据（RedisController 内部有锁保护）- 所有数据都存储
-	if b.redisController != nil {
-		b.redisController.UpdatePairPrice(pair, pairData)
+// publish 保存价格并通知价格变化，实时推送和回放共用
+func (b *BinanceController) publish(ticker model.BookTickerData, pairData *model.PairData) {
+	// 保存价格并聚合需要处理的交易对的K线，其他交易对只保存价格
+	ts := time.Now()
+	if ticker.TransactionTime > 0 {
+		ts = time.UnixMilli(ticker.TransactionTime)
 	}
-
-	// 如果设置了监听列表，只向 WatchKey channel 发送列表中的交易对
-	if b.redisController != nil && !b.redisController.IsWatchedPair(pair) {
-		return // 跳过不在监听列表中的交易对，但数据已经存储了
+	if !feed.Store(b.redisController, pairData, ts) {
+		return
 	}
 
-	// 通知价格更新
-	go func() {
+	// 回放时不能丢弃推送，否则结果与录制时不一致
+	if b.replaying {
 		select {
 		case b.changePairDataChan <- *pairData:
-		default:
-			// 如果channel满了，跳过这次通知
-			log.Printf("价格更新通知channel满了，跳过这次通知: %s", pair)
+		case <-b.ctx.Done():
 		}
-	}()
-}
-
-// 转换Binance符号格式
-func (b *BinanceController) formatPairSymbol(symbol string) string {
-	// 将BTCUSDT转换为BTC/USDT:USDT格式
-	if len(symbol) >= 4 && symbol[len(symbol)-4:] == "USDT" {
-		base := symbol[:len(symbol)-4]
-		return fmt.Sprintf("%s/USDT:USDT", base)
-	}
-	if len(symbol) >= 3 && symbol[len(symbol)-3:] == "BTC" {
-		base := symbol[:len(symbol)-3]
-		return fmt.Sprintf("%s/BTC:BTC", base)
-	}
-	if len(symbol) >= 3 && symbol[len(symbol)-3:] == "ETH" {
-		base := symbol[:len(symbol)-3]
-		return fmt.Sprintf("%s/ETH:ETH", base)
-	}
-	if len(symbol) >= 3 && symbol[len(symbol)-4:] == "USDC" {
-		base := symbol[:len(symbol)-4]
-		return fmt.Sprintf("%s/USDC:USDC", base)
+		return
 	}
-	return symbol
+	feed.Notify(b.changePairDataChan, *pairData)
 }
 
 // 转换BookTicker数据为PairData格式
@@ -175,67 +136,20 @@ func (b *BinanceController) convertBookTickerToPairData(ticker model.BookTickerD
 	}
 
 	pairData.Close = (pairData.AskPrice + pairData.BidPrice) / 2
-	pairData.Pair = b.formatPairSymbol(ticker.Symbol)
+	pairData.Pair = b.Registry().Pair(ticker.Symbol)
+	now := time.Now()
+	pairData.ReceiveTime = now.UnixMilli()
+	// 优先使用事件推送时间，其次撮合时间
+	pairData.EventTime = ticker.EventTime
+	if pairData.EventTime <= 0 {
+		pairData.EventTime = ticker.TransactionTime
+	}
 	// 使用 ticker 中的撮合时间戳，将毫秒转换为秒
 	if ticker.TransactionTime > 0 {
 		pairData.Timestamp = time.Unix(ticker.TransactionTime/1000, 0).Format("2006-01-02 15:04:05")
 	} else {
 		// 如果时间戳无效，回退到当前时间
-		pairData.Timestamp = time.Now().Format("2006-01-02 15:04:05")
+		pairData.Timestamp = now.Format("2006-01-02 15:04:05")
 	}
 	return &pairData, nil
 }
-
-// 处理重连
-func (b *BinanceController) handleReconnect() {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-b.ctx.Done():
-			return
-		case <-ticker.C:
-			// 发送ping保持连接
-			if b.conn != nil {
-				if err := b.conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(time.Second)); err != nil {
-					log.Printf("发送ping失败: %v", err)
-					b.reconnect()
-				}
-			}
-		}
-	}
-}
-
-// 重新连接
-func (b *BinanceController) reconnect() {
-	log.Println("尝试重新连接Binance WebSocket...")
-
-	if b.conn != nil {
-		b.conn.Close()
-	}
-
-	for i := 0; i < 5; i++ {
-		if err := b.Connect(); err != nil {
-			log.Printf("重连失败 (attempt %d/5): %v", i+1, err)
-			time.Sleep(time.Duration(i+1) * time.Second)
-			continue
-		}
-		log.Println("重连成功")
-		return
-	}
-
-	log.Println("重连失败，将在60秒后重试")
-	time.Sleep(60 * time.Second)
-	go b.reconnect()
-}
-
-// 停止监听
-func (b *BinanceController) Stop() {
-	if b.cancel != nil {
-		b.cancel()
-	}
-	if b.conn != nil {
-		b.conn.Close()
-	}
-}
@@ -0,0 +1,129 @@
+package binance
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"monitor-trade/model"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadKlineCSV 读取 Binance 历史K线 CSV（data.binance.vision 格式），按交易对返回价格数据，用于回测。
+// 交易对取自文件名中第一个 "-" 之前的合约名，如 BTCUSDT-1m-2024-01.csv。
+// 每根K线展开为开盘、最高、最低、收盘四个价格点，阳线先到最低价再到最高价，阴线相反；买卖价都取该价格
+func (b *BinanceController) LoadKlineCSV(paths ...string) (map[string][]model.PairData, error) {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(map[string][]model.PairData)
+	for _, path := range files {
+		symbol := strings.ToUpper(strings.SplitN(filepath.Base(path), "-", 2)[0])
+		pair := b.Registry().Pair(symbol)
+		klines, err := readKlineCSV(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, kline := range klines {
+			series[pair] = append(series[pair], klinePoints(pair, kline)...)
+		}
+	}
+	sortPairData(series)
+	return series, nil
+}
+
+// csvKline CSV 中的一根K线
+type csvKline struct {
+	model.Candle
+	CloseTime int64 // 收盘时间（Unix毫秒）
+}
+
+// readKlineCSV 解析K线 CSV，跳过表头，时间为微秒时换算为毫秒
+func readKlineCSV(path string) ([]csvKline, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开K线文件失败: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	var klines []csvKline
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取K线文件失败 %s: %v", path, err)
+		}
+		if len(row) < 7 {
+			return nil, fmt.Errorf("K线文件 %s 第 %d 行格式错误", path, line)
+		}
+		openTime, err := strconv.ParseInt(row[0], 10, 64)
+		if err != nil {
+			if line == 1 {
+				continue // 表头
+			}
+			return nil, fmt.Errorf("K线文件 %s 第 %d 行开盘时间错误: %s", path, line, row[0])
+		}
+		closeTime, err := strconv.ParseInt(row[6], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("K线文件 %s 第 %d 行收盘时间错误: %s", path, line, row[6])
+		}
+		if openTime > 1e14 {
+			openTime /= 1000
+			closeTime /= 1000
+		}
+
+		var prices [4]float64
+		for i := range prices {
+			price, err := strconv.ParseFloat(row[i+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("K线文件 %s 第 %d 行价格错误: %s", path, line, row[i+1])
+			}
+			prices[i] = price
+		}
+		klines = append(klines, csvKline{
+			Candle: model.Candle{
+				OpenTime: openTime,
+				Open:     prices[0],
+				High:     prices[1],
+				Low:      prices[2],
+				Close:    prices[3],
+				Closed:   true,
+			},
+			CloseTime: closeTime,
+		})
+	}
+	return klines, nil
+}
+
+// klinePoints 将一根K线展开为按时间排列的四个价格点
+func klinePoints(pair string, candle csvKline) []model.PairData {
+	step := (candle.CloseTime - candle.OpenTime) / 3
+	prices := []float64{candle.Open, candle.Low, candle.High, candle.Close}
+	if candle.Close < candle.Open {
+		prices[1], prices[2] = candle.High, candle.Low
+	}
+	times := []int64{candle.OpenTime, candle.OpenTime + step, candle.OpenTime + 2*step, candle.CloseTime}
+
+	points := make([]model.PairData, len(prices))
+	for i, price := range prices {
+		points[i] = model.PairData{
+			Pair:        pair,
+			BidPrice:    price,
+			AskPrice:    price,
+			Close:       price,
+			EventTime:   times[i],
+			ReceiveTime: times[i],
+			Timestamp:   time.UnixMilli(times[i]).Format("2006-01-02 15:04:05"),
+		}
+	}
+	return points
+}
@@ -0,0 +1,45 @@
+package binance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadKlineCSV 测试K线 CSV 按文件名识别交易对，阳线先到最低价、阴线先到最高价
+func TestLoadKlineCSV(t *testing.T) {
+	dir := t.TempDir()
+	content := "open_time,open,high,low,close,volume,close_time,quote_volume,count,taker_buy_volume,taker_buy_quote_volume,ignore\n" +
+		"1704067260000,101,103,100.5,100.8,8,1704067319999,800,80,4,400,0\n" +
+		"1704067200000,100,102,99,101,10,1704067259999,1000,100,5,500,0\n"
+	if err := os.WriteFile(filepath.Join(dir, "BTCUSDT-1m-2024-01-01.csv"), []byte(content), 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	controller := NewBinanceController()
+	series, err := controller.LoadKlineCSV(filepath.Join(dir, "*.csv"))
+	if err != nil {
+		t.Fatalf("加载K线失败: %v", err)
+	}
+	points := series["BTC/USDT:USDT"]
+	if len(points) != 8 {
+		t.Fatalf("期望 8 个价格点，实际 %d: %v", len(points), series)
+	}
+
+	expected := []float64{100, 99, 102, 101, 101, 103, 100.5, 100.8}
+	for i, price := range expected {
+		if points[i].BidPrice != price || points[i].AskPrice != price {
+			t.Errorf("第 %d 个价格点期望 %.1f，实际 %+v", i+1, price, points[i])
+		}
+		if i > 0 && points[i].EventTime < points[i-1].EventTime {
+			t.Errorf("价格点应按时间排序: %d < %d", points[i].EventTime, points[i-1].EventTime)
+		}
+	}
+	if points[0].EventTime != 1704067200000 || points[3].EventTime != 1704067259999 {
+		t.Errorf("开盘和收盘时间错误: %d %d", points[0].EventTime, points[3].EventTime)
+	}
+
+	if _, err := controller.LoadKlineCSV(filepath.Join(dir, "missing-*.csv")); err == nil {
+		t.Error("没有匹配的文件时应返回错误")
+	}
+}
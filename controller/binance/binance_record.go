@@ -0,0 +1,285 @@
+package binance
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"monitor-trade/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recordFilePrefix 录制文件名前缀，完整文件名形如 bookticker-20240101-0300.jsonl.gz
+const recordFilePrefix = "bookticker-"
+
+// TickRecorder 将收到的原始 bookTicker 推送按时间段写入 gzip 压缩的 JSON Lines 文件
+type TickRecorder struct {
+	mutex         sync.Mutex
+	dir           string
+	rotate        time.Duration // 每个文件覆盖的时长
+	flushInterval time.Duration // 定期刷新压缩缓冲区，进程异常退出时最多丢失这段时间的数据
+
+	file      *os.File
+	gz        *gzip.Writer
+	encoder   *json.Encoder
+	bucket    time.Time // 当前文件对应时间段的起点
+	lastFlush time.Time
+	failed    time.Time // 创建文件失败的时间段，该时间段内不再重试
+}
+
+// NewTickRecorder 创建录制器，rotate 不大于0时按小时切分文件
+func NewTickRecorder(dir string, rotate time.Duration) (*TickRecorder, error) {
+	if rotate <= 0 {
+		rotate = time.Hour
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建录制目录失败: %v", err)
+	}
+	return &TickRecorder{
+		dir:           dir,
+		rotate:        rotate,
+		flushInterval: 5 * time.Second,
+	}, nil
+}
+
+// Record 写入一条推送，超过当前时间段时切换到新文件。写入失败只记录日志，不影响行情处理
+func (r *TickRecorder) Record(ticker model.BookTickerData, now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	bucket := now.UTC().Truncate(r.rotate)
+	if r.file == nil || !bucket.Equal(r.bucket) {
+		if bucket.Equal(r.failed) {
+			return
+		}
+		r.closeFile()
+		if err := r.openFile(bucket); err != nil {
+			log.Printf("创建行情录制文件失败: %v", err)
+			r.failed = bucket
+			return
+		}
+		r.lastFlush = now
+	}
+
+	if err := r.encoder.Encode(model.RecordedTicker{ReceiveTime: now.UnixMilli(), Ticker: ticker}); err != nil {
+		log.Printf("写入行情录制文件失败 %s: %v", r.file.Name(), err)
+		return
+	}
+	if now.Sub(r.lastFlush) >= r.flushInterval {
+		if err := r.gz.Flush(); err != nil {
+			log.Printf("刷新行情录制文件失败 %s: %v", r.file.Name(), err)
+		}
+		r.lastFlush = now
+	}
+}
+
+// Close 关闭当前文件，写入 gzip 结尾
+func (r *TickRecorder) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.closeFile()
+}
+
+// openFile 打开时间段对应的文件，重启后同一时间段追加为新的 gzip 成员，读取时会按顺序连续解压
+func (r *TickRecorder) openFile(bucket time.Time) error {
+	name := filepath.Join(r.dir, recordFilePrefix+bucket.Format("20060102-1504")+".jsonl.gz")
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.gz = gzip.NewWriter(file)
+	r.encoder = json.NewEncoder(r.gz)
+	r.bucket = bucket
+	log.Printf("行情录制写入文件: %s", name)
+	return nil
+}
+
+func (r *TickRecorder) closeFile() {
+	if r.file == nil {
+		return
+	}
+	if err := r.gz.Close(); err != nil {
+		log.Printf("关闭行情录制文件失败 %s: %v", r.file.Name(), err)
+	}
+	r.file.Close()
+	r.file = nil
+	r.gz = nil
+	r.encoder = nil
+}
+
+// SetRecorder 设置录制器，之后收到的每条 bookTicker 推送都会写入录制文件
+func (b *BinanceController) SetRecorder(recorder *TickRecorder) {
+	b.recorder = recorder
+}
+
+// ReadRecording 读取录制文件中的全部推送。文件末尾不完整（进程异常退出）时返回已读到的部分
+func ReadRecording(path string) ([]model.RecordedTicker, error) {
+	var records []model.RecordedTicker
+	err := readRecording(path, func(record model.RecordedTicker) bool {
+		records = append(records, record)
+		return true
+	})
+	return records, err
+}
+
+// readRecording 逐条读取录制文件，handle 返回 false 时停止
+func readRecording(path string, handle func(model.RecordedTicker) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开录制文件失败: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("解压录制文件失败 %s: %v", path, err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var record model.RecordedTicker
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// 异常退出时最后一行可能不完整
+			log.Printf("跳过录制文件 %s 第 %d 行: %v", path, line, err)
+			continue
+		}
+		if !handle(record) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("读取录制文件失败 %s: %v", path, err)
+	}
+	return nil
+}
+
+// Replay 按录制时的时间间隔将录制文件中的推送送入与实时行情相同的处理流程。
+// speed 为回放倍速，1 为实际速度，不大于0时不等待直接回放。paths 支持通配符，同一通配符匹配的文件按文件名排序。
+// 回放不连接 Binance，价格的接收时间为录制时的接收时间，K线按录制时的撮合时间聚合。
+// 回放会把控制器切换为回放模式，之后不能再调用 Watch，也不能再次回放；每次回放需要新的控制器
+func (b *BinanceController) Replay(changePairDataChan chan model.PairData, speed float64, paths ...string) error {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return err
+	}
+
+	b.mutexShards.Lock()
+	if b.watching || b.replaying {
+		b.mutexShards.Unlock()
+		return fmt.Errorf("行情源已在使用，每次回放需要新的控制器")
+	}
+	b.changePairDataChan = changePairDataChan
+	b.replaying = true
+	b.mutexShards.Unlock()
+
+	var last int64
+	count := 0
+	for _, path := range files {
+		log.Printf("开始回放录制文件: %s", path)
+		err := readRecording(path, func(record model.RecordedTicker) bool {
+			if speed > 0 && last > 0 && record.ReceiveTime > last {
+				wait := time.Duration(float64(record.ReceiveTime-last) * float64(time.Millisecond) / speed)
+				if !sleep(b.ctx, wait) {
+					return false
+				}
+			}
+			if record.ReceiveTime > 0 {
+				last = record.ReceiveTime
+			}
+			b.replayTicker(record)
+			count++
+			return b.ctx.Err() == nil
+		})
+		if err != nil {
+			return err
+		}
+		if b.ctx.Err() != nil {
+			break
+		}
+	}
+	log.Printf("回放结束，共 %d 条推送", count)
+	return nil
+}
+
+// replayTicker 以录制时的接收时间送入一条推送
+func (b *BinanceController) replayTicker(record model.RecordedTicker) {
+	pairData, err := b.convertBookTickerToPairData(record.Ticker)
+	if err != nil {
+		log.Printf("转换价格数据失败 %s: %v", record.Ticker.Symbol, err)
+		return
+	}
+	if record.ReceiveTime > 0 {
+		pairData.ReceiveTime = record.ReceiveTime
+		if pairData.EventTime <= 0 {
+			pairData.EventTime = record.ReceiveTime
+		}
+	}
+	b.publish(record.Ticker, pairData)
+}
+
+// LoadRecordedPairData 读取录制文件并按交易对返回价格数据，价格的接收时间使用录制时的本地接收时间，用于回测
+func (b *BinanceController) LoadRecordedPairData(paths ...string) (map[string][]model.PairData, error) {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(map[string][]model.PairData)
+	for _, path := range files {
+		err := readRecording(path, func(record model.RecordedTicker) bool {
+			pairData, err := b.convertBookTickerToPairData(record.Ticker)
+			if err != nil {
+				return true
+			}
+			pairData.ReceiveTime = record.ReceiveTime
+			if pairData.EventTime <= 0 {
+				pairData.EventTime = record.ReceiveTime
+			}
+			series[pairData.Pair] = append(series[pairData.Pair], *pairData)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sortPairData(series)
+	return series, nil
+}
+
+// expandPaths 展开通配符，同一通配符匹配的文件按文件名排序，没有匹配的文件时返回错误
+func expandPaths(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("文件路径格式错误 %s: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("没有找到文件: %s", pattern)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// sortPairData 将每个交易对的价格数据按事件时间排序
+func sortPairData(series map[string][]model.PairData) {
+	for _, points := range series {
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].EventTime < points[j].EventTime
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package binance
+
+import (
+	"monitor-trade/model"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestRecorderRotateAndReplay 测试录制文件按时间段切分，回放时按顺序送入处理流程且不丢弃推送
+func TestRecorderRotateAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewTickRecorder(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("创建录制器失败: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 3, 59, 58, 0, time.UTC)
+	bids := []string{"65000", "65001", "65002", "65003"}
+	for i, bid := range bids {
+		recorder.Record(model.BookTickerData{
+			EventType:       "bookTicker",
+			Symbol:          "BTCUSDT",
+			BidPrice:        bid,
+			AskPrice:        "65010",
+			TransactionTime: start.Add(time.Duration(i) * time.Second).UnixMilli(),
+		}, start.Add(time.Duration(i)*time.Second))
+	}
+	recorder.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl.gz"))
+	if len(files) != 2 {
+		t.Fatalf("跨越整点应切分为 2 个文件，实际 %v", files)
+	}
+	if filepath.Base(files[0]) != "bookticker-20240101-0300.jsonl.gz" {
+		t.Errorf("文件名错误: %s", files[0])
+	}
+	records, err := ReadRecording(files[1])
+	if err != nil {
+		t.Fatalf("读取录制文件失败: %v", err)
+	}
+	if len(records) != 2 || records[0].Ticker.BidPrice != "65002" || records[0].ReceiveTime != start.Add(2*time.Second).UnixMilli() {
+		t.Errorf("第二个文件内容错误: %+v", records)
+	}
+
+	// 通知通道容量为1，回放时应等待消费而不是丢弃
+	controller := NewBinanceController()
+	defer controller.Stop()
+	out := make(chan model.PairData, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- controller.Replay(out, 0, filepath.Join(dir, "bookticker-*.jsonl.gz"))
+	}()
+	for i, bid := range bids {
+		select {
+		case data := <-out:
+			if data.Pair != "BTC/USDT:USDT" || strconv.FormatFloat(data.BidPrice, 'f', -1, 64) != bid {
+				t.Errorf("第 %d 条推送错误: %+v", i+1, data)
+			}
+			if data.ReceiveTime != start.Add(time.Duration(i)*time.Second).UnixMilli() {
+				t.Errorf("回放的价格接收时间应为录制时的接收时间，实际 %d", data.ReceiveTime)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待第 %d 条推送超时", i+1)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Errorf("回放失败: %v", err)
+	}
+
+	if err := controller.Replay(out, 0, filepath.Join(dir, "bookticker-*.jsonl.gz")); err == nil {
+		t.Error("同一个控制器不能再次回放")
+	}
+	if err := NewBinanceController().Replay(out, 0, filepath.Join(dir, "missing-*.jsonl.gz")); err == nil {
+		t.Error("没有匹配的录制文件时应返回错误")
+	}
+}
+
+// TestReadRecordingTruncated 测试进程异常退出留下的不完整文件仍能读出已刷新的部分
+func TestReadRecordingTruncated(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewTickRecorder(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("创建录制器失败: %v", err)
+	}
+	recorder.flushInterval = 0
+
+	now := time.Date(2024, 1, 1, 3, 12, 0, 0, time.UTC)
+	recorder.Record(model.BookTickerData{Symbol: "ETHUSDT", BidPrice: "3000", AskPrice: "3001"}, now)
+	recorder.Record(model.BookTickerData{Symbol: "ETHUSDT", BidPrice: "3002", AskPrice: "3003"}, now.Add(time.Second))
+
+	// 不调用 Close，模拟没有写入 gzip 结尾
+	path := recorder.file.Name()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取文件失败: %v", err)
+	}
+	truncated := filepath.Join(dir, "truncated.jsonl.gz")
+	if err := os.WriteFile(truncated, data, 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	recorder.Close()
+
+	records, err := ReadRecording(truncated)
+	if err != nil {
+		t.Fatalf("不完整的文件不应返回错误: %v", err)
+	}
+	if len(records) != 2 || records[1].Ticker.BidPrice != "3002" {
+		t.Errorf("期望读出 2 条推送，实际 %+v", records)
+	}
+}
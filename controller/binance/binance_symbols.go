@@ -0,0 +1,136 @@
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"monitor-trade/model"
+	"net/http"
+	"time"
+)
+
+// GetExchangeInfo 获取所有永续合约的交易规则
+func (b *BinanceController) GetExchangeInfo() ([]model.SymbolFilter, error) {
+	resp, err := b.httpClient.Get("https://fapi.binance.com/fapi/v1/exchangeInfo")
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取交易规则API错误，状态码: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取交易规则响应失败: %v", err)
+	}
+	return parseExchangeInfo(body)
+}
+
+// parseExchangeInfo 解析交易规则接口返回的数据，忽略非永续合约
+func parseExchangeInfo(body []byte) ([]model.SymbolFilter, error) {
+	var info model.ExchangeInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("解析交易规则数据失败: %v", err)
+	}
+
+	filters := make([]model.SymbolFilter, 0, len(info.Symbols))
+	for _, symbol := range info.Symbols {
+		if filter, ok := model.NewSymbolFilter(symbol); ok {
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+// LoadExchangeInfo 拉取交易规则并替换本地缓存
+func (b *BinanceController) LoadExchangeInfo() error {
+	filters, err := b.GetExchangeInfo()
+	if err != nil {
+		return err
+	}
+	b.SetSymbolFilters(filters)
+	log.Printf("已加载 %d 个合约的交易规则", len(filters))
+	return nil
+}
+
+// RefreshExchangeInfo 启动时加载交易规则，之后每隔 interval 刷新一次，直到调用 Stop
+func (b *BinanceController) RefreshExchangeInfo(interval time.Duration) {
+	if err := b.LoadExchangeInfo(); err != nil {
+		log.Printf("加载交易规则失败: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.LoadExchangeInfo(); err != nil {
+				log.Printf("刷新交易规则失败，继续使用上次的数据: %v", err)
+			}
+		}
+	}
+}
+
+// SetSymbolFilters 用交易规则重建交易对映射
+func (b *BinanceController) SetSymbolFilters(filters []model.SymbolFilter) {
+	registry := model.NewSymbolRegistry(filters)
+
+	b.mutexSymbols.Lock()
+	defer b.mutexSymbols.Unlock()
+	b.registry = registry
+}
+
+// Registry 当前的交易对映射，交易规则加载前为空映射，按合约名称的报价资产推断交易对
+func (b *BinanceController) Registry() *model.SymbolRegistry {
+	b.mutexSymbols.RLock()
+	defer b.mutexSymbols.RUnlock()
+	return b.registry
+}
+
+// ResolvePair 将用户输入解析为交易对
+func (b *BinanceController) ResolvePair(input string) string {
+	return b.Registry().Resolve(input)
+}
+
+// SymbolFilter 获取交易对的交易规则
+func (b *BinanceController) SymbolFilter(pair string) (model.SymbolFilter, bool) {
+	return b.Registry().Filter(pair)
+}
+
+// CheckSymbol 检查交易对是否存在且可以交易，合成交易对检查两条腿。
+// 交易规则还没有加载成功时不做检查
+func (b *BinanceController) CheckSymbol(pair string) error {
+	registry := b.Registry()
+	if registry.Len() == 0 {
+		return nil
+	}
+
+	pairs := []string{pair}
+	if base, quote, ok := model.ParseSyntheticPair(pair); ok {
+		pairs = []string{base, quote}
+	}
+	for _, p := range pairs {
+		filter, exists := registry.Filter(p)
+		if !exists {
+			return fmt.Errorf("交易所没有 %s 合约", p)
+		}
+		if !filter.IsTrading() {
+			return fmt.Errorf("%s 当前状态为 %s，不能交易", p, filter.Status)
+		}
+	}
+	return nil
+}
+
+// RoundPrice 将价格按交易对的 tickSize 取整，没有交易规则（如合成交易对）时原样返回
+func (b *BinanceController) RoundPrice(pair string, price float64) float64 {
+	filter, exists := b.SymbolFilter(pair)
+	if !exists {
+		return price
+	}
+	return filter.RoundPrice(price)
+}
@@ -0,0 +1,156 @@
+package binance
+
+import (
+	"monitor-trade/model"
+	"testing"
+)
+
+const testExchangeInfo = `{"symbols":[
+	{"symbol":"BTCUSDT","contractType":"PERPETUAL","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT","marginAsset":"USDT",
+	 "filters":[{"filterType":"PRICE_FILTER","tickSize":"0.10"},{"filterType":"LOT_SIZE","stepSize":"0.001"},{"filterType":"MIN_NOTIONAL","notional":"100"}]},
+	{"symbol":"1000PEPEUSDT","contractType":"PERPETUAL","status":"TRADING","baseAsset":"1000PEPE","quoteAsset":"USDT","marginAsset":"USDT",
+	 "filters":[{"filterType":"PRICE_FILTER","tickSize":"0.0000001"},{"filterType":"LOT_SIZE","stepSize":"1"},{"filterType":"MIN_NOTIONAL","notional":"5"}]},
+	{"symbol":"ETHUSDC","contractType":"PERPETUAL","status":"TRADING","baseAsset":"ETH","quoteAsset":"USDC","marginAsset":"USDC",
+	 "filters":[{"filterType":"PRICE_FILTER","tickSize":"0.01"}]},
+	{"symbol":"FTTUSDT","contractType":"PERPETUAL","status":"SETTLING","baseAsset":"FTT","quoteAsset":"USDT","marginAsset":"USDT",
+	 "filters":[{"filterType":"PRICE_FILTER","tickSize":"0.001"}]},
+	{"symbol":"BTCUSDT_250328","contractType":"CURRENT_QUARTER","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT","marginAsset":"USDT",
+	 "filters":[{"filterType":"PRICE_FILTER","tickSize":"0.10"}]}
+]}`
+
+// TestParseExchangeInfo 测试解析交易规则，只保留永续合约
+func TestParseExchangeInfo(t *testing.T) {
+	filters, err := parseExchangeInfo([]byte(testExchangeInfo))
+	if err != nil {
+		t.Fatalf("解析交易规则失败: %v", err)
+	}
+	if len(filters) != 4 {
+		t.Fatalf("期望 4 个永续合约，实际 %d 个", len(filters))
+	}
+
+	btc := filters[0]
+	if btc.Pair != "BTC/USDT:USDT" || btc.TickSize != 0.1 || btc.StepSize != 0.001 || btc.MinNotional != 100 || btc.PricePrecision != 1 {
+		t.Errorf("BTCUSDT 交易规则不正确: %+v", btc)
+	}
+	if pepe := filters[1]; pepe.Pair != "1000PEPE/USDT:USDT" || pepe.PricePrecision != 7 {
+		t.Errorf("1000PEPEUSDT 交易规则不正确: %+v", pepe)
+	}
+}
+
+// TestRoundPriceAndCheckSymbol 测试按 tickSize 取整，以及拒绝不存在或不可交易的合约
+func TestRoundPriceAndCheckSymbol(t *testing.T) {
+	controller := NewBinanceController()
+
+	// 交易规则加载前不做检查，价格原样返回
+	if err := controller.CheckSymbol("XYZ/USDT:USDT"); err != nil {
+		t.Errorf("交易规则加载前不应拒绝: %v", err)
+	}
+	if price := controller.RoundPrice("BTC/USDT:USDT", 50000.123); price != 50000.123 {
+		t.Errorf("交易规则加载前价格不应变化: %v", price)
+	}
+
+	filters, _ := parseExchangeInfo([]byte(testExchangeInfo))
+	controller.SetSymbolFilters(filters)
+
+	tests := []struct {
+		pair     string
+		price    float64
+		expected float64
+	}{
+		{"BTC/USDT:USDT", 50000.123, 50000.1},
+		{"BTC/USDT:USDT", 50000.16, 50000.2},
+		{"1000PEPE/USDT:USDT", 0.012345678, 0.0123457},
+		{"ETH/BTC", 0.0512345, 0.0512345},
+	}
+	for _, tt := range tests {
+		if price := controller.RoundPrice(tt.pair, tt.price); price != tt.expected {
+			t.Errorf("%s 价格 %v 取整期望 %v，实际 %v", tt.pair, tt.price, tt.expected, price)
+		}
+	}
+
+	if err := controller.CheckSymbol("BTC/USDT:USDT"); err != nil {
+		t.Errorf("BTC/USDT:USDT 应该可以交易: %v", err)
+	}
+	if err := controller.CheckSymbol("XYZ/USDT:USDT"); err == nil {
+		t.Error("不存在的合约应该被拒绝")
+	}
+	if err := controller.CheckSymbol("FTT/USDT:USDT"); err == nil {
+		t.Error("非 TRADING 状态的合约应该被拒绝")
+	}
+	if err := controller.CheckSymbol("FTT/BTC"); err == nil {
+		t.Error("合成交易对的腿不可交易时应该被拒绝")
+	}
+}
+
+// TestSymbolRegistryResolve 测试按交易所元数据解析 Telegram 输入的交易对
+func TestSymbolRegistryResolve(t *testing.T) {
+	controller := NewBinanceController()
+	filters, _ := parseExchangeInfo([]byte(testExchangeInfo))
+	controller.SetSymbolFilters(filters)
+	registry := controller.Registry()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"btc", "BTC/USDT:USDT"},
+		{"BTCUSDT", "BTC/USDT:USDT"},
+		{"BTC/USDT", "BTC/USDT:USDT"},
+		{"BTC/USDT:USDT", "BTC/USDT:USDT"},
+		{"pepe", "1000PEPE/USDT:USDT"},
+		{"1000PEPE", "1000PEPE/USDT:USDT"},
+		{"1000pepeusdt", "1000PEPE/USDT:USDT"},
+		{"ETHUSDC", "ETH/USDC:USDC"},
+		{"eth/usdc", "ETH/USDC:USDC"},
+		{"ETH/BTC", "ETH/BTC"}, // 合成交易对
+		{"xyz", "XYZ/USDT:USDT"},
+	}
+	for _, tt := range tests {
+		if pair := registry.Resolve(tt.input); pair != tt.expected {
+			t.Errorf("Resolve(%s) = %s, 期望 %s", tt.input, pair, tt.expected)
+		}
+	}
+
+	if symbol := registry.Symbol("1000PEPE/USDT:USDT"); symbol != "1000PEPEUSDT" {
+		t.Errorf("期望合约名称 1000PEPEUSDT，实际 %s", symbol)
+	}
+	if pair := registry.Pair("ETHUSDC"); pair != "ETH/USDC:USDC" {
+		t.Errorf("期望交易对 ETH/USDC:USDC，实际 %s", pair)
+	}
+
+	// 没有元数据时按合约名称的报价资产拆分
+	empty := NewBinanceController().Registry()
+	if pair := empty.Resolve("ETHUSDC"); pair != "ETH/USDC:USDC" {
+		t.Errorf("没有元数据时 ETHUSDC 应解析为 ETH/USDC:USDC，实际 %s", pair)
+	}
+	if pair := empty.Resolve("pepe"); pair != "PEPE/USDT:USDT" {
+		t.Errorf("没有元数据时 pepe 应解析为 PEPE/USDT:USDT，实际 %s", pair)
+	}
+}
+
+// TestSymbolRegistryResolveRealContract 测试交易所存在 ETHBTC 合约时，ETH/BTC 仍为合成交易对，合约名称和完整交易对解析为真实合约
+func TestSymbolRegistryResolveRealContract(t *testing.T) {
+	controller := NewBinanceController()
+	filters, _ := parseExchangeInfo([]byte(testExchangeInfo))
+	filters = append(filters, model.SymbolFilter{Symbol: "ETHBTC", Pair: "ETH/BTC:BTC", Status: model.SymbolStatusTrading, TickSize: 0.00001})
+	controller.SetSymbolFilters(filters)
+	registry := controller.Registry()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ETH/BTC", "ETH/BTC"}, // 合成交易对不受真实合约影响
+		{"eth/btc", "ETH/BTC"},
+		{"ETHBTC", "ETH/BTC:BTC"},
+		{"ethbtc", "ETH/BTC:BTC"},
+		{"ETH/BTC:BTC", "ETH/BTC:BTC"},
+		{"SOL/BTC", "SOL/BTC"},
+		{"BTC/ETH", "BTC/ETH"},
+	}
+	for _, tt := range tests {
+		if pair := registry.Resolve(tt.input); pair != tt.expected {
+			t.Errorf("Resolve(%s) = %s, 期望 %s", tt.input, pair, tt.expected)
+		}
+	}
+}
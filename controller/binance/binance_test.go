@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"monitor-trade/config"
 	"monitor-trade/controller/redis"
 	"monitor-trade/model"
@@ -79,13 +80,16 @@ func TestFormatPairSymbol(t *testing.T) {
 		{"ETHBTC", "ETH/BTC:BTC"},
 		{"BNBBTC", "BNB/BTC:BTC"},
 		{"ADAETH", "ADA/ETH:ETH"},
+		{"ETHUSDC", "ETH/USDC:USDC"},
+		{"1000PEPEUSDT", "1000PEPE/USDT:USDT"},
+		{"USDC", "USDC"},       // 只有报价资产
 		{"UNKNOWN", "UNKNOWN"}, // 无法识别的格式
 	}
 
 	for _, tc := range testCases {
-		result := controller.formatPairSymbol(tc.input)
+		result := controller.Registry().Pair(tc.input)
 		if result != tc.expected {
-			t.Errorf("formatPairSymbol(%s) = %s, 期望 %s", tc.input, result, tc.expected)
+			t.Errorf("Registry().Pair(%s) = %s, 期望 %s", tc.input, result, tc.expected)
 		}
 	}
 }
@@ -132,6 +136,14 @@ func TestConvertBookTickerToPairData(t *testing.T) {
 	if pairData.Timestamp == "" {
 		t.Error("时间戳不应为空")
 	}
+
+	// 验证事件时间和接收时间
+	if pairData.EventTime != ticker.EventTime {
+		t.Errorf("期望事件时间 %d，实际 %d", ticker.EventTime, pairData.EventTime)
+	}
+	if pairData.ReceiveTime <= 0 {
+		t.Error("接收时间不应为空")
+	}
 }
 
 // TestConvertBookTickerToPairDataInvalidPrice 测试无效价格数据
@@ -396,7 +408,7 @@ func BenchmarkFormatPairSymbol(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		controller.formatPairSymbol("BTCUSDT")
+		controller.Registry().Pair("BTCUSDT")
 	}
 }
 
@@ -437,7 +449,7 @@ func TestGetFundingRate(t *testing.T) {
 
 	// 创建测试用的资金费率获取方法
 	testGetFundingRate := func(symbol string) (float64, error) {
-		binanceSymbol := controller.convertToBinanceSymbol(symbol)
+		binanceSymbol := controller.Registry().Symbol(symbol)
 		url := fmt.Sprintf("%s/fapi/v1/premiumIndex?symbol=%s", server.URL, binanceSymbol)
 
 		resp, err := controller.httpClient.Get(url)
@@ -493,7 +505,7 @@ func TestGetFundingRateError(t *testing.T) {
 
 	// 创建测试用的资金费率获取方法
 	testGetFundingRate := func(symbol string) (float64, error) {
-		binanceSymbol := controller.convertToBinanceSymbol(symbol)
+		binanceSymbol := controller.Registry().Symbol(symbol)
 		url := fmt.Sprintf("%s/fapi/v1/premiumIndex?symbol=%s", server.URL, binanceSymbol)
 
 		resp, err := controller.httpClient.Get(url)
@@ -531,9 +543,9 @@ func TestConvertToBinanceSymbol(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		result := controller.convertToBinanceSymbol(tc.input)
+		result := controller.Registry().Symbol(tc.input)
 		if result != tc.expected {
-			t.Errorf("convertToBinanceSymbol(%s) = %s, 期望 %s", tc.input, result, tc.expected)
+			t.Errorf("Registry().Symbol(%s) = %s, 期望 %s", tc.input, result, tc.expected)
 		}
 	}
 }
@@ -566,3 +578,94 @@ func TestConvertBookTickerToPairDataWithFundingRate(t *testing.T) {
 		t.Errorf("期望卖单价 50100.00，实际 %f", pairData.AskPrice)
 	}
 }
+
+// TestProcessBookTickerCandles 测试最优挂单推送聚合为K线
+func TestProcessBookTickerCandles(t *testing.T) {
+	controller := NewBinanceController()
+
+	conf := &config.Config{
+		Redis: config.RedisConfig{
+			Addr:      "localhost:6379",
+			Password:  "",
+			DB:        0,
+			KeyExpire: 300,
+		},
+	}
+	redisController := redis.NewRedisController(conf)
+	controller.SetRedisController(redisController)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	tickers := []model.BookTickerData{
+		{Symbol: "BTCUSDT", BidPrice: "100", AskPrice: "102", TransactionTime: start + 1000},
+		{Symbol: "BTCUSDT", BidPrice: "104", AskPrice: "106", TransactionTime: start + 20000},
+		{Symbol: "BTCUSDT", BidPrice: "96", AskPrice: "98", TransactionTime: start + 40000},
+		{Symbol: "BTCUSDT", BidPrice: "99", AskPrice: "101", TransactionTime: start + 61000},
+	}
+	for _, ticker := range tickers {
+		controller.processBookTicker(ticker)
+	}
+
+	candles := redisController.GetCandles("BTC/USDT:USDT", "1m")
+	if len(candles) != 2 {
+		t.Fatalf("期望 2 根1分钟K线，实际 %d", len(candles))
+	}
+
+	first := candles[0]
+	if !first.Closed || first.Open != 101 || first.High != 105 || first.Low != 97 || first.Close != 97 || first.Ticks != 3 {
+		t.Errorf("第一根K线数据不正确: %+v", first)
+	}
+	if first.BidLow != 96 || first.AskHigh != 106 {
+		t.Errorf("买卖价极值不正确: %+v", first)
+	}
+	if candles[1].Closed {
+		t.Error("最后一根K线不应收盘")
+	}
+
+	closed, ok := redisController.GetLastClosedCandle("BTC/USDT:USDT", "1m")
+	if !ok || closed.OpenTime != start {
+		t.Errorf("最近收盘K线不正确: %+v", closed)
+	}
+
+	// 5分钟K线尚未收盘
+	if _, ok := redisController.GetLastClosedCandle("BTC/USDT:USDT", "5m"); ok {
+		t.Error("5分钟K线不应有已收盘的数据")
+	}
+}
+
+// TestParseKlinesATR 测试解析历史K线并补齐本地K线后计算 ATR
+func TestParseKlinesATR(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	minute := time.Minute.Milliseconds()
+
+	// 每根K线高低差为 2，收盘价与下一根开盘价相同，ATR 应为 2
+	var rows [][]interface{}
+	for i := 0; i < model.ATRPeriod+2; i++ {
+		openTime := start + int64(i)*minute
+		rows = append(rows, []interface{}{openTime, "100", "101", "99", "100", "10", openTime + minute - 1})
+	}
+	body, _ := json.Marshal(rows)
+
+	now := time.UnixMilli(start + int64(model.ATRPeriod+1)*minute + 1000)
+	candles, err := parseKlines(body, now)
+	if err != nil {
+		t.Fatalf("解析K线失败: %v", err)
+	}
+	if len(candles) != model.ATRPeriod+2 || candles[0].High != 101 || candles[0].Low != 99 {
+		t.Fatalf("K线数据不正确: %+v", candles[0])
+	}
+	if candles[len(candles)-1].Closed {
+		t.Error("最后一根K线尚未收盘")
+	}
+
+	conf := &config.Config{Redis: config.RedisConfig{Addr: "localhost:6379", KeyExpire: 300}}
+	redisController := redis.NewRedisController(conf)
+	if _, ok := redisController.GetATR("BTC/USDT:USDT", "1m"); ok {
+		t.Error("没有K线时不应计算出 ATR")
+	}
+
+	redisController.SeedCandles("BTC/USDT:USDT", "1m", candles)
+	atr, ok := redisController.GetATR("BTC/USDT:USDT", "1m")
+	if !ok || math.Abs(atr-2) > 1e-9 {
+		t.Errorf("期望 ATR 为 2，实际 %.4f (%v)", atr, ok)
+	}
+}
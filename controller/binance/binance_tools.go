@@ -4,16 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"monitor-trade/model"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 )
 
-// 获取资金费率
+// GetFundingRate 获取资金费率(%)
 func (b *BinanceController) GetFundingRate(symbol string) (float64, error) {
 	// 转换交易对格式：BTC/USDT:USDT -> BTCUSDT
-	binanceSymbol := b.convertToBinanceSymbol(symbol)
+	binanceSymbol := b.Registry().Symbol(symbol)
 
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/premiumIndex?symbol=%s", binanceSymbol)
 
@@ -45,13 +46,129 @@ func (b *BinanceController) GetFundingRate(symbol string) (float64, error) {
 	return fundingRate * 100, nil
 }
 
-// 转换交易对格式：BTC/USDT:USDT -> BTCUSDT
-func (b *BinanceController) convertToBinanceSymbol(pair string) string {
-	// 移除后缀":USDT"等
-	if colonIndex := strings.Index(pair, ":"); colonIndex != -1 {
-		pair = pair[:colonIndex]
+// GetKlines 获取交易对的历史K线，OHLC 使用成交价
+func (b *BinanceController) GetKlines(pair, timeframe string, limit int) ([]model.Candle, error) {
+	binanceSymbol := b.Registry().Symbol(pair)
+	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&limit=%d", binanceSymbol, timeframe, limit)
+
+	resp, err := b.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("获取K线请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取K线API错误，状态码: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取K线响应失败: %v", err)
+	}
+	return parseKlines(body, time.Now())
+}
+
+// GetBookTicker 通过 REST 接口获取交易对的最优挂单价格
+func (b *BinanceController) GetBookTicker(pair string) (*model.PairData, error) {
+	binanceSymbol := b.Registry().Symbol(pair)
+	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/ticker/bookTicker?symbol=%s", binanceSymbol)
+
+	resp, err := b.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("获取最优挂单请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取最优挂单API错误，状态码: %d", resp.StatusCode)
 	}
 
-	// 移除斜杠
-	return strings.ReplaceAll(pair, "/", "")
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取最优挂单响应失败: %v", err)
+	}
+
+	var ticker model.BookTickerREST
+	if err := json.Unmarshal(body, &ticker); err != nil {
+		return nil, fmt.Errorf("解析最优挂单数据失败: %v", err)
+	}
+	return b.convertBookTickerToPairData(model.BookTickerData{
+		Symbol:          ticker.Symbol,
+		BidPrice:        ticker.BidPrice,
+		AskPrice:        ticker.AskPrice,
+		TransactionTime: ticker.Time,
+	})
+}
+
+// PairPrice 获取交易对最新价格，行情推送还没有订阅该交易对（或合成交易对的某条腿）时通过 REST 接口补齐
+func (b *BinanceController) PairPrice(pair string) model.PairData {
+	if data := b.redisController.GetPairPrice(pair); data.BidPrice > 0 && data.AskPrice > 0 {
+		return data
+	}
+
+	pairs := []string{pair}
+	if base, quote, ok := model.ParseSyntheticPair(pair); ok {
+		pairs = []string{base, quote}
+	}
+	for _, p := range pairs {
+		if data := b.redisController.GetPairPrice(p); data.BidPrice > 0 {
+			continue
+		}
+		data, err := b.GetBookTicker(p)
+		if err != nil {
+			log.Printf("获取 %s 最优挂单失败: %v", p, err)
+			return model.PairData{}
+		}
+		b.redisController.UpdatePairPrice(p, data)
+	}
+	return b.redisController.GetPairPrice(pair)
+}
+
+// parseKlines 解析K线接口返回的数组：[开盘时间, 开, 高, 低, 收, 成交量, 收盘时间, ...]
+func parseKlines(body []byte, now time.Time) ([]model.Candle, error) {
+	var rows [][]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return nil, fmt.Errorf("解析K线数据失败: %v", err)
+	}
+
+	candles := make([]model.Candle, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 7 {
+			return nil, fmt.Errorf("K线数据格式错误: %v", row)
+		}
+		var prices [4]float64
+		for i := range prices {
+			str, _ := row[i+1].(string)
+			price, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return nil, fmt.Errorf("解析K线价格失败: %v", row[i+1])
+			}
+			prices[i] = price
+		}
+		openTime, _ := row[0].(float64)
+		closeTime, _ := row[6].(float64)
+		candles = append(candles, model.Candle{
+			OpenTime: int64(openTime),
+			Open:     prices[0],
+			High:     prices[1],
+			Low:      prices[2],
+			Close:    prices[3],
+			Closed:   int64(closeTime) < now.UnixMilli(),
+		})
+	}
+	return candles, nil
+}
+
+// LoadCandles 从 Binance 拉取历史K线补齐本地K线，用于刚启动时计算 ATR
+func (b *BinanceController) LoadCandles(pair, timeframe string) error {
+	if b.redisController == nil {
+		return fmt.Errorf("未设置 RedisController")
+	}
+	candles, err := b.GetKlines(pair, timeframe, model.ATRPeriod*3)
+	if err != nil {
+		return err
+	}
+	b.redisController.SeedCandles(pair, timeframe, candles)
+	log.Printf("已加载 %s %s 历史K线 %d 根", pair, timeframe, len(candles))
+	return nil
 }
@@ -0,0 +1,466 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"monitor-trade/model"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 连接结束的原因
+var (
+	errFeedStale   = errors.New("行情推送停滞")
+	errConnExpired = errors.New("连接达到最长使用时间")
+	errStopped     = errors.New("已停止")
+)
+
+// feedShard 一条行情推送连接及其订阅的 stream，订阅数超过单连接上限时拆分到多条连接
+type feedShard struct {
+	id        int
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mutex     sync.Mutex // 保护 conn、streams 和 requestID，控制消息的写入也在锁内完成
+	conn      *websocket.Conn
+	streams   map[string]bool
+	requestID int64
+	running   bool         // 连接协程是否已启动，由 mutexShards 保护
+	lastTick  atomic.Int64 // 最近一次收到推送或建立连接的时间（Unix纳秒）
+}
+
+// SetMessageChan 设置 Telegram 告警通道
+func (b *BinanceController) SetMessageChan(messageChan chan string) {
+	b.messageChan = messageChan
+}
+
+// SetStaleTimeout 设置行情停滞的判定时长，超过该时长没有推送时重连并告警
+func (b *BinanceController) SetStaleTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		b.staleTimeout = timeout
+	}
+}
+
+// bookTickerStream 交易对对应的最优挂单 stream，如 BTC/USDT:USDT -> btcusdt@bookTicker，
+// 合成交易对等不是合约格式的交易对返回空字符串
+func (b *BinanceController) bookTickerStream(pair string) string {
+	if !strings.Contains(pair, ":") {
+		return ""
+	}
+	return strings.ToLower(b.Registry().Symbol(pair)) + "@bookTicker"
+}
+
+// SetSubscriptions 将订阅的交易对更新为 pairs：取消不再需要的 stream，新增的 stream 优先放入已有连接的空位，
+// 超过单连接上限时新建连接；已建立的连接通过 SUBSCRIBE/UNSUBSCRIBE 控制消息更新，不需要重连
+func (b *BinanceController) SetSubscriptions(pairs []string) {
+	desired := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		if stream := b.bookTickerStream(pair); stream != "" {
+			desired[stream] = true
+		}
+	}
+
+	b.mutexShards.Lock()
+	defer b.mutexShards.Unlock()
+
+	removed := make(map[*feedShard][]string)
+	removedCount := 0
+	for stream, shard := range b.streamShard {
+		if !desired[stream] {
+			removed[shard] = append(removed[shard], stream)
+			delete(b.streamShard, stream)
+			removedCount++
+		}
+	}
+	for shard, streams := range removed {
+		shard.update("UNSUBSCRIBE", streams)
+	}
+
+	var added []string
+	for stream := range desired {
+		if _, exists := b.streamShard[stream]; !exists {
+			added = append(added, stream)
+		}
+	}
+	sort.Strings(added)
+	pending := make(map[*feedShard][]string)
+	for _, stream := range added {
+		shard := b.shardWithCapacity(pending)
+		pending[shard] = append(pending[shard], stream)
+		b.streamShard[stream] = shard
+	}
+	for shard, streams := range pending {
+		shard.update("SUBSCRIBE", streams)
+	}
+
+	// 没有订阅的连接直接关闭
+	kept := b.shards[:0]
+	for _, shard := range b.shards {
+		if shard.size() == 0 {
+			shard.cancel()
+			continue
+		}
+		kept = append(kept, shard)
+		if b.watching {
+			b.startShard(shard)
+		}
+	}
+	b.shards = kept
+
+	if len(added) > 0 || removedCount > 0 {
+		log.Printf("更新行情订阅: 新增 %d 个，取消 %d 个，共 %d 个 stream，%d 条连接",
+			len(added), removedCount, len(b.streamShard), len(b.shards))
+	}
+}
+
+// shardWithCapacity 返回还有空位的连接，都已满时新建一条连接。调用前需要持有 mutexShards
+func (b *BinanceController) shardWithCapacity(pending map[*feedShard][]string) *feedShard {
+	for _, shard := range b.shards {
+		if shard.size()+len(pending[shard]) < b.maxStreams {
+			return shard
+		}
+	}
+	b.nextShardID++
+	ctx, cancel := context.WithCancel(b.ctx)
+	shard := &feedShard{id: b.nextShardID, ctx: ctx, cancel: cancel, streams: make(map[string]bool)}
+	b.shards = append(b.shards, shard)
+	return shard
+}
+
+// startShard 启动连接协程，已启动时直接返回。调用前需要持有 mutexShards
+func (b *BinanceController) startShard(shard *feedShard) {
+	if shard.running {
+		return
+	}
+	shard.running = true
+	b.wgShards.Add(1)
+	go func() {
+		defer b.wgShards.Done()
+		b.runShard(shard)
+	}()
+}
+
+// Watch 启动价格监听，为每条连接启动协程并随监听列表和监控的变化更新订阅，直到调用 Stop
+func (b *BinanceController) Watch(changePairDataChan chan model.PairData) {
+	b.mutexShards.Lock()
+	if b.replaying {
+		b.mutexShards.Unlock()
+		log.Println("行情源已用于回放，不能再连接实时行情")
+		return
+	}
+	b.changePairDataChan = changePairDataChan
+	b.watching = true
+	for _, shard := range b.shards {
+		b.startShard(shard)
+	}
+	b.mutexShards.Unlock()
+
+	if b.redisController != nil {
+		go b.syncSubscriptions()
+	}
+
+	<-b.ctx.Done()
+	b.wgShards.Wait()
+}
+
+// syncSubscriptions 监听列表或监控变化时更新订阅，两次更新之间至少间隔 subscribeInterval
+func (b *BinanceController) syncSubscriptions() {
+	for {
+		b.SetSubscriptions(b.redisController.SubscribedPairs())
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-b.redisController.PairsChanged():
+		}
+		if !sleep(b.ctx, b.subscribeInterval) {
+			return
+		}
+	}
+}
+
+// runShard 维护单条连接：断开、行情停滞或达到最长使用时间后按指数退避重连，直到连接被关闭或调用 Stop
+func (b *BinanceController) runShard(shard *feedShard) {
+	backoff := b.minBackoff
+	stale := false
+
+	for {
+		if shard.ctx.Err() != nil {
+			return
+		}
+		conn, err := b.connect(shard)
+		if err != nil {
+			log.Printf("%v，%s 后重试", err, backoff)
+			if !sleep(shard.ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, b.maxBackoff)
+			continue
+		}
+		if stale {
+			b.sendMessage(fmt.Sprintf("✅ Binance 行情推送已重新连接（连接 #%d）", shard.id))
+			stale = false
+		}
+
+		start := time.Now()
+		err = b.serve(shard, conn)
+		switch {
+		case errors.Is(err, errStopped):
+			return
+		case errors.Is(err, errFeedStale):
+			stale = true
+			message := fmt.Sprintf("⚠️ Binance 行情 %s 没有推送，正在重连（连接 #%d，%d 个交易对）", b.staleTimeout, shard.id, shard.size())
+			log.Println(message)
+			b.sendMessage(message)
+		case errors.Is(err, errConnExpired):
+			log.Printf("Binance 连接 #%d 即将达到 24 小时上限，主动重连", shard.id)
+		default:
+			log.Printf("Binance 连接 #%d 断开: %v", shard.id, err)
+		}
+
+		// 连接稳定运行过一段时间后重置退避时长
+		if time.Since(start) > b.maxBackoff {
+			backoff = b.minBackoff
+		}
+		if errors.Is(err, errConnExpired) {
+			continue
+		}
+		if !sleep(shard.ctx, backoff) {
+			return
+		}
+		backoff = min(backoff*2, b.maxBackoff)
+	}
+}
+
+// connect 建立连接并订阅该连接负责的全部 stream
+func (b *BinanceController) connect(shard *feedShard) (*websocket.Conn, error) {
+	log.Printf("正在连接到Binance期货合约最优挂单推送流 #%d: %s", shard.id, b.wsURL)
+
+	conn, _, err := websocket.DefaultDialer.DialContext(shard.ctx, b.wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("连接Binance期货WebSocket推送流 #%d 失败: %v", shard.id, err)
+	}
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	shard.conn = conn
+	streams := make([]string, 0, len(shard.streams))
+	for stream := range shard.streams {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	if err := shard.send("SUBSCRIBE", streams); err != nil {
+		conn.Close()
+		shard.conn = nil
+		return nil, fmt.Errorf("订阅Binance推送流 #%d 失败: %v", shard.id, err)
+	}
+	log.Printf("成功连接到Binance期货合约最优挂单推送流 #%d，订阅 %d 个 stream", shard.id, len(streams))
+	return conn, nil
+}
+
+// serve 读取单个连接的推送直到连接结束，返回结束原因
+func (b *BinanceController) serve(shard *feedShard, conn *websocket.Conn) error {
+	defer func() {
+		shard.mutex.Lock()
+		if shard.conn == conn {
+			shard.conn = nil
+		}
+		shard.mutex.Unlock()
+		conn.Close()
+	}()
+
+	shard.lastTick.Store(time.Now().UnixNano())
+	conn.SetReadDeadline(time.Now().Add(b.readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(b.readTimeout))
+	})
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(b.readTimeout))
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
+	done := make(chan struct{})
+	reason := make(chan error, 1)
+	go b.supervise(shard, conn, done, reason)
+
+	var readErr error
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			readErr = err
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(b.readTimeout))
+
+		var ticker model.BookTickerData
+		if err := json.Unmarshal(message, &ticker); err != nil {
+			log.Printf("解析Binance推送数据失败: %v", err)
+			continue
+		}
+		if ticker.Symbol == "" {
+			// 没有交易对的消息是订阅控制消息的响应
+			handleStreamResponse(shard, message)
+			continue
+		}
+		now := time.Now()
+		shard.lastTick.Store(now.UnixNano())
+		b.lastTick.Store(now.UnixNano())
+		if b.recorder != nil {
+			b.recorder.Record(ticker, now)
+		}
+		b.processBookTicker(ticker)
+	}
+	close(done)
+
+	// 监督协程主动关闭连接时以它的原因为准
+	select {
+	case err := <-reason:
+		return err
+	default:
+		return readErr
+	}
+}
+
+// supervise 定时发送 ping，检查行情是否停滞以及连接是否达到最长使用时间，需要重连时关闭连接
+func (b *BinanceController) supervise(shard *feedShard, conn *websocket.Conn, done chan struct{}, reason chan error) {
+	pingTicker := time.NewTicker(b.pingInterval)
+	defer pingTicker.Stop()
+	checkTicker := time.NewTicker(min(b.staleTimeout/4, time.Second))
+	defer checkTicker.Stop()
+	expire := time.NewTimer(b.maxConnAge)
+	defer expire.Stop()
+
+	closeWith := func(err error) {
+		reason <- err
+		conn.Close()
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-shard.ctx.Done():
+			closeWith(errStopped)
+			return
+		case <-expire.C:
+			closeWith(errConnExpired)
+			return
+		case <-pingTicker.C:
+			if err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(time.Second)); err != nil {
+				log.Printf("发送ping失败: %v", err)
+			}
+		case <-checkTicker.C:
+			// 只订阅冷门交易对的连接可能长时间没有推送，整个行情源都没有推送时才视为停滞
+			if time.Since(time.Unix(0, shard.lastTick.Load())) > b.staleTimeout &&
+				time.Since(time.Unix(0, b.lastTick.Load())) > b.staleTimeout {
+				closeWith(errFeedStale)
+				return
+			}
+		}
+	}
+}
+
+// handleStreamResponse 记录订阅控制消息的失败响应
+func handleStreamResponse(shard *feedShard, message []byte) {
+	var response model.StreamResponse
+	if err := json.Unmarshal(message, &response); err != nil {
+		log.Printf("解析Binance控制消息响应失败: %v", err)
+		return
+	}
+	if response.Error != nil {
+		log.Printf("❌ Binance 连接 #%d 订阅请求 %d 失败: %d %s", shard.id, response.ID, response.Error.Code, response.Error.Msg)
+	}
+}
+
+// update 增加或取消连接负责的 stream，连接已建立时同时发送控制消息
+func (s *feedShard) update(method string, streams []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, stream := range streams {
+		if method == "SUBSCRIBE" {
+			s.streams[stream] = true
+		} else {
+			delete(s.streams, stream)
+		}
+	}
+	if s.conn == nil {
+		// 连接建立后会订阅全部 stream
+		return
+	}
+	if err := s.send(method, streams); err != nil {
+		// 发送失败时关闭连接，重连后按最新的 stream 重新订阅
+		log.Printf("Binance 连接 #%d 发送 %s 失败: %v", s.id, method, err)
+		s.conn.Close()
+	}
+}
+
+// send 发送订阅控制消息，调用前需要持有 mutex
+func (s *feedShard) send(method string, streams []string) error {
+	if len(streams) == 0 {
+		return nil
+	}
+	s.requestID++
+	s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return s.conn.WriteJSON(model.StreamRequest{Method: method, Params: streams, ID: s.requestID})
+}
+
+// size 连接负责的 stream 数量
+func (s *feedShard) size() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.streams)
+}
+
+// sleep 等待重连，期间 ctx 结束时返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// sendMessage 发送 Telegram 告警，通道已满时跳过
+func (b *BinanceController) sendMessage(message string) {
+	if b.messageChan == nil {
+		return
+	}
+	select {
+	case b.messageChan <- message:
+	default:
+		log.Printf("⚠️ 消息通道已满，跳过发送: %s", message)
+	}
+}
+
+// Stop 停止监听并关闭所有连接和录制文件
+func (b *BinanceController) Stop() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	if b.recorder != nil {
+		b.recorder.Close()
+	}
+	b.mutexShards.Lock()
+	defer b.mutexShards.Unlock()
+	for _, shard := range b.shards {
+		shard.mutex.Lock()
+		if shard.conn != nil {
+			shard.conn.Close()
+		}
+		shard.mutex.Unlock()
+	}
+}
@@ -0,0 +1,349 @@
+package binance
+
+import (
+	"monitor-trade/model"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestFeedServer 创建本地 WebSocket 行情服务，每次连接都交给 handle 处理，返回连接次数计数
+func newTestFeedServer(t *testing.T, handle func(conn *websocket.Conn)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var attempts atomic.Int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}))
+	t.Cleanup(server.Close)
+	return server, &attempts
+}
+
+// newTestFeedController 创建连接到本地行情服务的控制器，缩短各项超时便于测试
+func newTestFeedController(server *httptest.Server) *BinanceController {
+	controller := NewBinanceController()
+	controller.wsURL = "ws" + strings.TrimPrefix(server.URL, "http")
+	controller.pingInterval = 50 * time.Millisecond
+	controller.readTimeout = time.Second
+	controller.staleTimeout = 10 * time.Second
+	controller.maxConnAge = time.Hour
+	controller.minBackoff = 10 * time.Millisecond
+	controller.maxBackoff = 50 * time.Millisecond
+	controller.SetMessageChan(make(chan string, 10))
+	controller.SetSubscriptions([]string{"BTC/USDT:USDT"})
+	return controller
+}
+
+// startWatch 在后台启动监听，测试结束时停止并确认 Watch 已退出
+func startWatch(t *testing.T, controller *BinanceController) chan model.PairData {
+	t.Helper()
+	pairDataChan := make(chan model.PairData, 100)
+	stopped := make(chan struct{})
+	go func() {
+		controller.Watch(pairDataChan)
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		controller.Stop()
+		select {
+		case <-stopped:
+		case <-time.After(2 * time.Second):
+			t.Error("Stop 之后 Watch 应该退出")
+		}
+	})
+	return pairDataChan
+}
+
+// sendTicker 向客户端推送一条最优挂单数据
+func sendTicker(conn *websocket.Conn, bid string) error {
+	return conn.WriteJSON(model.BookTickerData{Symbol: "BTCUSDT", BidPrice: bid, AskPrice: "50001"})
+}
+
+// drain 持续读取客户端消息，使服务端能自动回复 ping，直到连接关闭
+func drain(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// waitFor 等待条件满足
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// TestWatchRetriesInitialConnect 测试首次连接失败后按退避重试，而不是直接退出
+func TestWatchRetriesInitialConnect(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		sendTicker(conn, "50000")
+		drain(conn)
+	}))
+	defer server.Close()
+
+	controller := newTestFeedController(server)
+	pairDataChan := startWatch(t, controller)
+
+	select {
+	case data := <-pairDataChan:
+		if data.Pair != "BTC/USDT:USDT" || data.BidPrice != 50000 {
+			t.Errorf("推送数据不正确: %+v", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("重试连接后应该收到推送")
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("期望连接 3 次，实际 %d 次", attempts.Load())
+	}
+}
+
+// TestWatchReconnectsWhenStale 测试行情停滞时重连并发送告警
+func TestWatchReconnectsWhenStale(t *testing.T) {
+	server, attempts := newTestFeedServer(t, func(conn *websocket.Conn) {
+		// 每次连接只推送一条数据，之后保持连接但不再推送
+		sendTicker(conn, "50000")
+		drain(conn)
+	})
+
+	controller := newTestFeedController(server)
+	controller.staleTimeout = 200 * time.Millisecond
+	startWatch(t, controller)
+
+	if !waitFor(t, 2*time.Second, func() bool { return attempts.Load() >= 2 }) {
+		t.Fatalf("行情停滞后应该重连，实际连接 %d 次", attempts.Load())
+	}
+
+	var messages []string
+	waitFor(t, time.Second, func() bool {
+		for {
+			select {
+			case msg := <-controller.messageChan:
+				messages = append(messages, msg)
+			default:
+				return len(messages) >= 2
+			}
+		}
+	})
+	if len(messages) < 2 || !strings.Contains(messages[0], "没有推送") || !strings.Contains(messages[1], "重新连接") {
+		t.Errorf("期望先收到停滞告警再收到恢复通知，实际: %v", messages)
+	}
+}
+
+// TestWatchReconnectsBeforeMaxAge 测试连接达到最长使用时间后主动重连，且不发送停滞告警
+func TestWatchReconnectsBeforeMaxAge(t *testing.T) {
+	server, attempts := newTestFeedServer(t, func(conn *websocket.Conn) {
+		go drain(conn)
+		for {
+			if err := sendTicker(conn, "50000"); err != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+
+	controller := newTestFeedController(server)
+	controller.maxConnAge = 200 * time.Millisecond
+	startWatch(t, controller)
+
+	if !waitFor(t, 2*time.Second, func() bool { return attempts.Load() >= 2 }) {
+		t.Fatalf("达到最长使用时间后应该重连，实际连接 %d 次", attempts.Load())
+	}
+	select {
+	case msg := <-controller.messageChan:
+		t.Errorf("主动重连不应发送告警: %s", msg)
+	default:
+	}
+}
+
+// TestWatchPongExtendsDeadline 测试没有推送时 pong 会顺延读超时，连接保持不断开
+func TestWatchPongExtendsDeadline(t *testing.T) {
+	server, attempts := newTestFeedServer(t, func(conn *websocket.Conn) {
+		drain(conn)
+	})
+
+	controller := newTestFeedController(server)
+	controller.readTimeout = 150 * time.Millisecond
+	startWatch(t, controller)
+
+	time.Sleep(600 * time.Millisecond)
+	if attempts.Load() != 1 {
+		t.Errorf("收到 pong 时不应重连，实际连接 %d 次", attempts.Load())
+	}
+}
+
+// recordedConn 本地行情服务收到的一条连接及其控制消息
+type recordedConn struct {
+	requests []model.StreamRequest
+	closed   bool
+}
+
+// feedRecorder 记录本地行情服务每条连接收到的订阅控制消息
+type feedRecorder struct {
+	mutex sync.Mutex
+	conns []*recordedConn
+}
+
+// handle 读取并记录控制消息，直到连接关闭
+func (r *feedRecorder) handle(conn *websocket.Conn) {
+	r.mutex.Lock()
+	record := &recordedConn{}
+	r.conns = append(r.conns, record)
+	r.mutex.Unlock()
+
+	for {
+		var request model.StreamRequest
+		if err := conn.ReadJSON(&request); err != nil {
+			r.mutex.Lock()
+			record.closed = true
+			r.mutex.Unlock()
+			return
+		}
+		r.mutex.Lock()
+		record.requests = append(record.requests, request)
+		r.mutex.Unlock()
+	}
+}
+
+// find 返回首个订阅请求包含 stream 的连接
+func (r *feedRecorder) find(stream string) (recordedConn, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, record := range r.conns {
+		if len(record.requests) > 0 && record.requests[0].Method == "SUBSCRIBE" {
+			for _, param := range record.requests[0].Params {
+				if param == stream {
+					return recordedConn{requests: append([]model.StreamRequest(nil), record.requests...), closed: record.closed}, true
+				}
+			}
+		}
+	}
+	return recordedConn{}, false
+}
+
+// TestSubscriptionsSplitAndUpdate 测试超过单连接上限时拆分连接，以及通过控制消息增减订阅
+func TestSubscriptionsSplitAndUpdate(t *testing.T) {
+	recorder := &feedRecorder{}
+	server, attempts := newTestFeedServer(t, recorder.handle)
+
+	controller := newTestFeedController(server)
+	controller.maxStreams = 2
+	controller.SetSubscriptions([]string{"BTC/USDT:USDT", "ETH/USDT:USDT", "SOL/USDT:USDT", "ETH/BTC"})
+	startWatch(t, controller)
+
+	var first, second recordedConn
+	if !waitFor(t, 2*time.Second, func() bool {
+		var ok1, ok2 bool
+		first, ok1 = recorder.find("btcusdt@bookTicker")
+		second, ok2 = recorder.find("solusdt@bookTicker")
+		return ok1 && ok2
+	}) {
+		t.Fatal("两条连接都应该发送订阅请求")
+	}
+	if got := first.requests[0].Params; !reflect.DeepEqual(got, []string{"btcusdt@bookTicker", "ethusdt@bookTicker"}) {
+		t.Errorf("第一条连接订阅不正确: %v", got)
+	}
+	if got := second.requests[0].Params; !reflect.DeepEqual(got, []string{"solusdt@bookTicker"}) {
+		t.Errorf("第二条连接订阅不正确: %v", got)
+	}
+
+	// 取消 ETH 和 SOL：第一条连接发送 UNSUBSCRIBE，第二条连接没有订阅后关闭
+	controller.SetSubscriptions([]string{"BTC/USDT:USDT"})
+	if !waitFor(t, 2*time.Second, func() bool {
+		first, _ = recorder.find("btcusdt@bookTicker")
+		second, _ = recorder.find("solusdt@bookTicker")
+		return len(first.requests) >= 2 && second.closed
+	}) {
+		t.Fatalf("取消订阅未生效: %+v %+v", first, second)
+	}
+	if got := first.requests[1]; got.Method != "UNSUBSCRIBE" || !reflect.DeepEqual(got.Params, []string{"ethusdt@bookTicker"}) {
+		t.Errorf("期望取消 ethusdt@bookTicker，实际 %+v", got)
+	}
+
+	// 新增的交易对放入已有连接，不新建连接
+	controller.SetSubscriptions([]string{"BTC/USDT:USDT", "XRP/USDT:USDT"})
+	if !waitFor(t, 2*time.Second, func() bool {
+		first, _ = recorder.find("btcusdt@bookTicker")
+		return len(first.requests) >= 3
+	}) {
+		t.Fatal("新增订阅应该通过已有连接发送")
+	}
+	if got := first.requests[2]; got.Method != "SUBSCRIBE" || !reflect.DeepEqual(got.Params, []string{"xrpusdt@bookTicker"}) {
+		t.Errorf("期望订阅 xrpusdt@bookTicker，实际 %+v", got)
+	}
+	if attempts.Load() != 2 {
+		t.Errorf("期望连接 2 次，实际 %d 次", attempts.Load())
+	}
+}
+
+// TestQuietShardNotStale 测试只订阅冷门交易对的连接没有推送时，其他连接仍在推送则不视为停滞
+func TestQuietShardNotStale(t *testing.T) {
+	server, attempts := newTestFeedServer(t, func(conn *websocket.Conn) {
+		var request model.StreamRequest
+		if err := conn.ReadJSON(&request); err != nil {
+			return
+		}
+		go drain(conn)
+		if len(request.Params) == 0 || request.Params[0] != "btcusdt@bookTicker" {
+			// 冷门交易对的连接一直没有推送
+			<-time.After(5 * time.Second)
+			return
+		}
+		for {
+			if err := sendTicker(conn, "50000"); err != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+
+	controller := newTestFeedController(server)
+	controller.staleTimeout = 200 * time.Millisecond
+	controller.maxStreams = 1
+	controller.SetSubscriptions([]string{"BTC/USDT:USDT", "ETH/USDT:USDT"})
+	startWatch(t, controller)
+
+	if !waitFor(t, 2*time.Second, func() bool { return attempts.Load() >= 2 }) {
+		t.Fatalf("应该建立两条连接，实际 %d 条", attempts.Load())
+	}
+	time.Sleep(800 * time.Millisecond)
+	if attempts.Load() != 2 {
+		t.Errorf("冷门交易对的连接不应重连，实际连接 %d 次", attempts.Load())
+	}
+	select {
+	case msg := <-controller.messageChan:
+		t.Errorf("其他连接仍在推送时不应发送停滞告警: %s", msg)
+	default:
+	}
+}
@@ -0,0 +1,238 @@
+package bybit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"monitor-trade/controller/feed"
+	"monitor-trade/controller/redis"
+	"monitor-trade/model"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// BybitController Bybit USDT/USDC 永续合约行情源，通过 tickers 频道获取最优挂单价格和资金费率
+type BybitController struct {
+	redisController    *redis.RedisController
+	changePairDataChan chan model.PairData
+	messageChan        chan string // Telegram 告警通道
+	ctx                context.Context
+	cancel             context.CancelFunc
+	httpClient         *http.Client
+
+	mutexConn sync.Mutex      // 保护 conn、topics 和 requestID，控制消息的写入也在锁内完成
+	conn      *websocket.Conn // 当前连接，断开期间为空
+	topics    map[string]bool // 需要订阅的 topic，如 tickers.BTCUSDT
+	requestID int64
+	lastTick  atomic.Int64 // 最近一次收到推送的时间（Unix纳秒）
+
+	mutexTickers sync.RWMutex
+	tickers      map[string]model.BybitTicker // 合约名称 -> 合并 delta 后的最新数据
+
+	mutexSymbols sync.RWMutex          // 保护 registry
+	registry     *model.SymbolRegistry // 交易对与合约名称的映射及交易规则，来自 instruments-info
+
+	baseURL           string        // REST 接口地址
+	wsURL             string        // 行情推送地址
+	maxArgs           int           // 单个订阅请求最多包含的 topic 数
+	subscribeInterval time.Duration // 两次更新订阅之间的最短间隔
+	pingInterval      time.Duration // 发送 ping 的间隔，Bybit 要求 20 秒内至少一次
+	readTimeout       time.Duration // 读超时，收到任何消息后顺延
+	staleTimeout      time.Duration // 超过该时长没有收到行情推送视为停滞
+	minBackoff        time.Duration // 重连等待的初始时长
+	maxBackoff        time.Duration // 重连等待的最长时长
+}
+
+var _ feed.PriceFeed = (*BybitController)(nil)
+
+func NewBybitController() *BybitController {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BybitController{
+		changePairDataChan: make(chan model.PairData, 1000),
+		ctx:                ctx,
+		cancel:             cancel,
+		httpClient:         &http.Client{Timeout: 10 * time.Second},
+		topics:             make(map[string]bool),
+		tickers:            make(map[string]model.BybitTicker),
+		registry:           model.NewSymbolRegistry(nil),
+		baseURL:            "https://api.bybit.com",
+		wsURL:              "wss://stream.bybit.com/v5/public/linear",
+		maxArgs:            10,
+		subscribeInterval:  time.Second,
+		pingInterval:       20 * time.Second,
+		readTimeout:        60 * time.Second,
+		staleTimeout:       30 * time.Second,
+		minBackoff:         time.Second,
+		maxBackoff:         time.Minute,
+	}
+}
+
+// SetRedisController 设置 Redis 控制器
+func (b *BybitController) SetRedisController(redisController *redis.RedisController) {
+	b.redisController = redisController
+}
+
+// SetMessageChan 设置 Telegram 告警通道
+func (b *BybitController) SetMessageChan(messageChan chan string) {
+	b.messageChan = messageChan
+}
+
+// SetHTTPClient 设置 REST 请求使用的 HTTP 客户端
+func (b *BybitController) SetHTTPClient(client *http.Client) {
+	b.httpClient = client
+}
+
+// SetStaleTimeout 设置行情停滞的判定时长，不大于0时保持默认值
+func (b *BybitController) SetStaleTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		b.staleTimeout = timeout
+	}
+}
+
+// handleTicker 合并 tickers 推送，买一卖一价变化时保存价格并通知
+func (b *BybitController) handleTicker(message model.BybitMessage) {
+	var update model.BybitTicker
+	if err := json.Unmarshal(message.Data, &update); err != nil {
+		log.Printf("解析Bybit推送数据失败 %s: %v", message.Topic, err)
+		return
+	}
+	if update.Symbol == "" {
+		update.Symbol = strings.TrimPrefix(message.Topic, tickerTopicPrefix)
+	}
+
+	b.mutexTickers.Lock()
+	ticker := update
+	if message.Type != "snapshot" {
+		ticker = b.tickers[update.Symbol].Merge(update)
+	}
+	b.tickers[update.Symbol] = ticker
+	b.mutexTickers.Unlock()
+
+	// 只有资金费率或标记价格变化时不通知
+	if update.Bid1Price == "" && update.Ask1Price == "" {
+		return
+	}
+	pairData, err := b.convertTickerToPairData(ticker, message.Ts)
+	if err != nil {
+		log.Printf("转换价格数据失败 %s: %v", ticker.Symbol, err)
+		return
+	}
+
+	ts := time.Now()
+	if message.Ts > 0 {
+		ts = time.UnixMilli(message.Ts)
+	}
+	if !feed.Store(b.redisController, pairData, ts) {
+		return
+	}
+	feed.Notify(b.changePairDataChan, *pairData)
+}
+
+// convertTickerToPairData 转换 tickers 数据为 PairData 格式
+func (b *BybitController) convertTickerToPairData(ticker model.BybitTicker, eventTime int64) (*model.PairData, error) {
+	bidPrice, err := strconv.ParseFloat(ticker.Bid1Price, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析买单价格: %s", ticker.Bid1Price)
+	}
+	askPrice, err := strconv.ParseFloat(ticker.Ask1Price, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析卖单价格: %s", ticker.Ask1Price)
+	}
+
+	now := time.Now()
+	pairData := &model.PairData{
+		Pair:        b.Registry().Pair(ticker.Symbol),
+		BidPrice:    bidPrice,
+		AskPrice:    askPrice,
+		Close:       (bidPrice + askPrice) / 2,
+		EventTime:   eventTime,
+		ReceiveTime: now.UnixMilli(),
+		Timestamp:   now.Format("2006-01-02 15:04:05"),
+	}
+	if eventTime > 0 {
+		pairData.Timestamp = time.UnixMilli(eventTime).Format("2006-01-02 15:04:05")
+	}
+	return pairData, nil
+}
+
+// cachedFundingRate 从 tickers 推送中获取资金费率
+func (b *BybitController) cachedFundingRate(symbol string) (float64, bool) {
+	b.mutexTickers.RLock()
+	defer b.mutexTickers.RUnlock()
+	ticker, exists := b.tickers[symbol]
+	if !exists || ticker.FundingRate == "" {
+		return 0, false
+	}
+	rate, err := parseFundingRate(ticker.FundingRate)
+	return rate, err == nil
+}
+
+// parseFundingRate 解析 Bybit 的资金费率，Bybit 返回小数形式，转换为百分比
+func parseFundingRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return rate * 100, nil
+}
+
+// SetSymbolFilters 用交易规则重建交易对映射
+func (b *BybitController) SetSymbolFilters(filters []model.SymbolFilter) {
+	registry := model.NewSymbolRegistry(filters)
+
+	b.mutexSymbols.Lock()
+	defer b.mutexSymbols.Unlock()
+	b.registry = registry
+}
+
+// Registry 当前的交易对映射，交易规则加载前为空映射，按合约名称的报价资产推断交易对
+func (b *BybitController) Registry() *model.SymbolRegistry {
+	b.mutexSymbols.RLock()
+	defer b.mutexSymbols.RUnlock()
+	return b.registry
+}
+
+// ResolvePair 将用户输入解析为交易对
+func (b *BybitController) ResolvePair(input string) string {
+	return b.Registry().Resolve(input)
+}
+
+// CheckSymbol 检查交易对是否存在且可以交易，合成交易对检查两条腿。
+// 交易规则还没有加载成功时不做检查
+func (b *BybitController) CheckSymbol(pair string) error {
+	registry := b.Registry()
+	if registry.Len() == 0 {
+		return nil
+	}
+
+	pairs := []string{pair}
+	if base, quote, ok := model.ParseSyntheticPair(pair); ok {
+		pairs = []string{base, quote}
+	}
+	for _, p := range pairs {
+		filter, exists := registry.Filter(p)
+		if !exists {
+			return fmt.Errorf("交易所没有 %s 合约", p)
+		}
+		if !filter.IsTrading() {
+			return fmt.Errorf("%s 当前状态为 %s，不能交易", p, filter.Status)
+		}
+	}
+	return nil
+}
+
+// RoundPrice 将价格按交易对的 tickSize 取整，没有交易规则（如合成交易对）时原样返回
+func (b *BybitController) RoundPrice(pair string, price float64) float64 {
+	filter, exists := b.Registry().Filter(pair)
+	if !exists {
+		return price
+	}
+	return filter.RoundPrice(price)
+}
@@ -0,0 +1,229 @@
+package bybit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"monitor-trade/model"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// klineIntervals K线周期对应的 Bybit interval 参数
+var klineIntervals = map[string]string{
+	"1m":  "1",
+	"5m":  "5",
+	"15m": "15",
+	"1h":  "60",
+}
+
+// get 请求 REST 接口，retCode 不为0时返回错误，成功时将 result 解析到 result
+func (b *BybitController) get(path string, query url.Values, result interface{}) error {
+	query.Set("category", "linear")
+	resp, err := b.httpClient.Get(b.baseURL + path + "?" + query.Encode())
+	if err != nil {
+		return fmt.Errorf("请求Bybit接口失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Bybit接口错误 %s，状态码: %d", path, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取Bybit响应失败: %v", err)
+	}
+
+	var response model.BybitResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("解析Bybit响应失败: %v", err)
+	}
+	if response.RetCode != 0 {
+		return fmt.Errorf("Bybit接口错误 %s: %d %s", path, response.RetCode, response.RetMsg)
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("解析Bybit响应失败: %v", err)
+	}
+	return nil
+}
+
+// GetTicker 通过 REST 接口获取合约的 tickers 数据
+func (b *BybitController) GetTicker(pair string) (model.BybitTicker, error) {
+	symbol := b.Registry().Symbol(pair)
+	var result model.BybitTickerList
+	if err := b.get("/v5/market/tickers", url.Values{"symbol": {symbol}}, &result); err != nil {
+		return model.BybitTicker{}, err
+	}
+	if len(result.List) == 0 {
+		return model.BybitTicker{}, fmt.Errorf("没有找到交易对 %s 的行情", pair)
+	}
+	return result.List[0], nil
+}
+
+// GetFundingRate 获取交易对当前的资金费率(%)，优先使用 tickers 推送中的数据
+func (b *BybitController) GetFundingRate(pair string) (float64, error) {
+	if rate, ok := b.cachedFundingRate(b.Registry().Symbol(pair)); ok {
+		return rate, nil
+	}
+	ticker, err := b.GetTicker(pair)
+	if err != nil {
+		return 0, err
+	}
+	rate, err := parseFundingRate(ticker.FundingRate)
+	if err != nil {
+		return 0, fmt.Errorf("解析资金费率数值失败: %v", err)
+	}
+	return rate, nil
+}
+
+// PairPrice 获取交易对的最新价格，本地没有订阅数据时通过 REST 接口获取，合成交易对会补齐两条腿的价格
+func (b *BybitController) PairPrice(pair string) model.PairData {
+	if data := b.redisController.GetPairPrice(pair); data.BidPrice > 0 && data.AskPrice > 0 {
+		return data
+	}
+
+	pairs := []string{pair}
+	if base, quote, ok := model.ParseSyntheticPair(pair); ok {
+		pairs = []string{base, quote}
+	}
+	for _, p := range pairs {
+		if data := b.redisController.GetPairPrice(p); data.BidPrice > 0 {
+			continue
+		}
+		ticker, err := b.GetTicker(p)
+		if err != nil {
+			log.Printf("获取 %s 最优挂单失败: %v", p, err)
+			return model.PairData{}
+		}
+		data, err := b.convertTickerToPairData(ticker, time.Now().UnixMilli())
+		if err != nil {
+			log.Printf("转换 %s 价格数据失败: %v", p, err)
+			return model.PairData{}
+		}
+		b.redisController.UpdatePairPrice(p, data)
+	}
+	return b.redisController.GetPairPrice(pair)
+}
+
+// GetKlines 通过 REST 接口获取最近的K线，按时间正序返回
+func (b *BybitController) GetKlines(pair, timeframe string, limit int) ([]model.Candle, error) {
+	interval, ok := klineIntervals[timeframe]
+	if !ok {
+		return nil, fmt.Errorf("不支持的K线周期: %s", timeframe)
+	}
+	duration, _ := model.TimeframeDuration(timeframe)
+
+	var result model.BybitKlineList
+	query := url.Values{
+		"symbol":   {b.Registry().Symbol(pair)},
+		"interval": {interval},
+		"limit":    {strconv.Itoa(limit)},
+	}
+	if err := b.get("/v5/market/kline", query, &result); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	candles := make([]model.Candle, 0, len(result.List))
+	for i := len(result.List) - 1; i >= 0; i-- {
+		row := result.List[i]
+		if len(row) < 5 {
+			return nil, fmt.Errorf("K线数据格式错误: %v", row)
+		}
+		var values [5]float64
+		for j := range values {
+			value, err := strconv.ParseFloat(row[j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("解析K线数据失败: %v", row)
+			}
+			values[j] = value
+		}
+		openTime := int64(values[0])
+		candles = append(candles, model.Candle{
+			OpenTime: openTime,
+			Open:     values[1],
+			High:     values[2],
+			Low:      values[3],
+			Close:    values[4],
+			Closed:   openTime+duration.Milliseconds() <= now,
+		})
+	}
+	return candles, nil
+}
+
+// LoadCandles 从 Bybit 拉取历史K线补齐本地K线，用于刚启动时计算 ATR
+func (b *BybitController) LoadCandles(pair, timeframe string) error {
+	if b.redisController == nil {
+		return fmt.Errorf("未设置 RedisController")
+	}
+	candles, err := b.GetKlines(pair, timeframe, model.ATRPeriod*3)
+	if err != nil {
+		return err
+	}
+	b.redisController.SeedCandles(pair, timeframe, candles)
+	log.Printf("已加载 %s %s 历史K线 %d 根", pair, timeframe, len(candles))
+	return nil
+}
+
+// GetInstruments 获取所有永续合约的交易规则
+func (b *BybitController) GetInstruments() ([]model.SymbolFilter, error) {
+	var filters []model.SymbolFilter
+	cursor := ""
+	for {
+		query := url.Values{"limit": {"1000"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		var result model.BybitInstrumentList
+		if err := b.get("/v5/market/instruments-info", query, &result); err != nil {
+			return nil, err
+		}
+		for _, instrument := range result.List {
+			if filter, ok := model.NewBybitSymbolFilter(instrument); ok {
+				filters = append(filters, filter)
+			}
+		}
+		if result.NextPageCursor == "" || len(result.List) == 0 {
+			break
+		}
+		cursor = result.NextPageCursor
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("交易规则中没有永续合约")
+	}
+	return filters, nil
+}
+
+// LoadInstruments 加载交易规则并更新交易对映射
+func (b *BybitController) LoadInstruments() error {
+	filters, err := b.GetInstruments()
+	if err != nil {
+		return err
+	}
+	b.SetSymbolFilters(filters)
+	log.Printf("已加载 %d 个Bybit合约的交易规则", len(filters))
+	return nil
+}
+
+// RefreshInstruments 启动时加载交易规则，之后每隔 interval 刷新一次，直到调用 Stop
+func (b *BybitController) RefreshInstruments(interval time.Duration) {
+	if err := b.LoadInstruments(); err != nil {
+		log.Printf("加载交易规则失败: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.LoadInstruments(); err != nil {
+				log.Printf("刷新交易规则失败，继续使用上次的数据: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package bybit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"monitor-trade/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// tickerTopicPrefix tickers 频道的 topic 前缀
+const tickerTopicPrefix = "tickers."
+
+// 连接结束的原因
+var (
+	errFeedStale = errors.New("行情推送停滞")
+	errStopped   = errors.New("已停止")
+)
+
+// tickerTopic 交易对对应的 tickers topic，合成交易对等没有合约的交易对返回空
+func (b *BybitController) tickerTopic(pair string) string {
+	if !strings.Contains(pair, ":") {
+		return ""
+	}
+	return tickerTopicPrefix + b.Registry().Symbol(pair)
+}
+
+// SetSubscriptions 设置需要订阅的交易对，已连接时只对变化的部分发送 subscribe/unsubscribe
+func (b *BybitController) SetSubscriptions(pairs []string) {
+	wanted := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		if topic := b.tickerTopic(pair); topic != "" {
+			wanted[topic] = true
+		}
+	}
+
+	b.mutexConn.Lock()
+	defer b.mutexConn.Unlock()
+
+	var added, removed []string
+	for topic := range b.topics {
+		if !wanted[topic] {
+			removed = append(removed, topic)
+		}
+	}
+	for topic := range wanted {
+		if !b.topics[topic] {
+			added = append(added, topic)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	b.topics = wanted
+
+	if b.conn == nil {
+		// 未连接时在建立连接后统一订阅
+		return
+	}
+	if err := b.send("unsubscribe", removed); err != nil {
+		log.Printf("Bybit 取消订阅失败: %v", err)
+	}
+	if err := b.send("subscribe", added); err != nil {
+		log.Printf("Bybit 订阅失败: %v", err)
+	}
+	log.Printf("Bybit 订阅更新：新增 %d 个，取消 %d 个，共 %d 个", len(added), len(removed), len(wanted))
+}
+
+// Watch 启动价格监听，断开或行情停滞后按指数退避重连，并随监听列表和监控的变化更新订阅，直到调用 Stop
+func (b *BybitController) Watch(changePairDataChan chan model.PairData) {
+	b.changePairDataChan = changePairDataChan
+	if b.redisController != nil {
+		go b.syncSubscriptions()
+	}
+
+	backoff := b.minBackoff
+	stale := false
+	for {
+		if b.ctx.Err() != nil {
+			return
+		}
+		conn, err := b.connect()
+		if err != nil {
+			log.Printf("%v，%s 后重试", err, backoff)
+			if !b.sleep(backoff) {
+				return
+			}
+			backoff = min(backoff*2, b.maxBackoff)
+			continue
+		}
+		if stale {
+			b.sendMessage("✅ Bybit 行情推送已重新连接")
+			stale = false
+		}
+
+		start := time.Now()
+		err = b.serve(conn)
+		switch {
+		case errors.Is(err, errStopped):
+			return
+		case errors.Is(err, errFeedStale):
+			stale = true
+			message := fmt.Sprintf("⚠️ Bybit 行情 %s 没有推送，正在重连", b.staleTimeout)
+			log.Println(message)
+			b.sendMessage(message)
+		default:
+			log.Printf("Bybit 连接断开: %v", err)
+		}
+
+		// 连接稳定运行过一段时间后重置退避时长
+		if time.Since(start) > b.maxBackoff {
+			backoff = b.minBackoff
+		}
+		if !b.sleep(backoff) {
+			return
+		}
+		backoff = min(backoff*2, b.maxBackoff)
+	}
+}
+
+// syncSubscriptions 监听列表或监控变化时更新订阅，两次更新之间至少间隔 subscribeInterval
+func (b *BybitController) syncSubscriptions() {
+	for {
+		b.SetSubscriptions(b.redisController.SubscribedPairs())
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-b.redisController.PairsChanged():
+		}
+		if !b.sleep(b.subscribeInterval) {
+			return
+		}
+	}
+}
+
+// connect 建立连接并订阅全部 topic
+func (b *BybitController) connect() (*websocket.Conn, error) {
+	log.Printf("正在连接到Bybit永续合约行情推送: %s", b.wsURL)
+	conn, _, err := websocket.DefaultDialer.DialContext(b.ctx, b.wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("连接Bybit WebSocket失败: %v", err)
+	}
+
+	b.mutexConn.Lock()
+	defer b.mutexConn.Unlock()
+	b.conn = conn
+	topics := make([]string, 0, len(b.topics))
+	for topic := range b.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	if err := b.send("subscribe", topics); err != nil {
+		conn.Close()
+		b.conn = nil
+		return nil, fmt.Errorf("订阅Bybit行情失败: %v", err)
+	}
+	log.Printf("成功连接到Bybit永续合约行情推送，订阅 %d 个 topic", len(topics))
+	return conn, nil
+}
+
+// serve 读取推送直到连接结束，返回结束原因
+func (b *BybitController) serve(conn *websocket.Conn) error {
+	defer func() {
+		b.mutexConn.Lock()
+		if b.conn == conn {
+			b.conn = nil
+		}
+		b.mutexConn.Unlock()
+		conn.Close()
+	}()
+
+	b.lastTick.Store(time.Now().UnixNano())
+	conn.SetReadDeadline(time.Now().Add(b.readTimeout))
+
+	done := make(chan struct{})
+	reason := make(chan error, 1)
+	go b.supervise(conn, done, reason)
+
+	var readErr error
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			readErr = err
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(b.readTimeout))
+
+		var message model.BybitMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			log.Printf("解析Bybit推送数据失败: %v", err)
+			continue
+		}
+		if strings.HasPrefix(message.Topic, tickerTopicPrefix) {
+			b.lastTick.Store(time.Now().UnixNano())
+			b.handleTicker(message)
+			continue
+		}
+		if message.Success != nil && !*message.Success {
+			log.Printf("❌ Bybit %s 请求失败: %s", message.Op, message.RetMsg)
+		}
+	}
+	close(done)
+
+	// 监督协程主动关闭连接时以它的原因为准
+	select {
+	case err := <-reason:
+		return err
+	default:
+		return readErr
+	}
+}
+
+// supervise 定时发送 ping，检查行情是否停滞，需要重连时关闭连接
+func (b *BybitController) supervise(conn *websocket.Conn, done chan struct{}, reason chan error) {
+	pingTicker := time.NewTicker(b.pingInterval)
+	defer pingTicker.Stop()
+	checkTicker := time.NewTicker(min(b.staleTimeout/4, time.Second))
+	defer checkTicker.Stop()
+
+	closeWith := func(err error) {
+		reason <- err
+		conn.Close()
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-b.ctx.Done():
+			closeWith(errStopped)
+			return
+		case <-pingTicker.C:
+			b.mutexConn.Lock()
+			err := conn.WriteJSON(model.BybitRequest{Op: "ping"})
+			b.mutexConn.Unlock()
+			if err != nil {
+				log.Printf("发送ping失败: %v", err)
+			}
+		case <-checkTicker.C:
+			// 没有订阅时不会有推送，不视为停滞
+			if b.topicCount() > 0 && time.Since(time.Unix(0, b.lastTick.Load())) > b.staleTimeout {
+				closeWith(errFeedStale)
+				return
+			}
+		}
+	}
+}
+
+// send 按 maxArgs 分批发送控制消息，调用方需持有 mutexConn
+func (b *BybitController) send(op string, topics []string) error {
+	for start := 0; start < len(topics); start += b.maxArgs {
+		end := min(start+b.maxArgs, len(topics))
+		b.requestID++
+		request := model.BybitRequest{ReqID: strconv.FormatInt(b.requestID, 10), Op: op, Args: topics[start:end]}
+		if err := b.conn.WriteJSON(request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BybitController) topicCount() int {
+	b.mutexConn.Lock()
+	defer b.mutexConn.Unlock()
+	return len(b.topics)
+}
+
+// sleep 等待重连，期间调用 Stop 时返回 false
+func (b *BybitController) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-b.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// sendMessage 发送 Telegram 告警，通道已满时跳过
+func (b *BybitController) sendMessage(message string) {
+	if b.messageChan == nil {
+		return
+	}
+	select {
+	case b.messageChan <- message:
+	default:
+		log.Printf("⚠️ 消息通道已满，跳过发送: %s", message)
+	}
+}
+
+// Stop 停止监听并关闭连接
+func (b *BybitController) Stop() {
+	b.cancel()
+	b.mutexConn.Lock()
+	defer b.mutexConn.Unlock()
+	if b.conn != nil {
+		b.conn.Close()
+	}
+}
@@ -0,0 +1,321 @@
+package bybit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"monitor-trade/model"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// standIn 本地模拟的 Bybit 公共行情服务：记录客户端的控制消息，回复 ping 和订阅请求
+type standIn struct {
+	t        *testing.T
+	server   *httptest.Server
+	mutex    sync.Mutex
+	conns    []*websocket.Conn
+	requests [][]model.BybitRequest // 每条连接收到的订阅类请求
+	writes   []*sync.Mutex
+}
+
+func newStandIn(t *testing.T) *standIn {
+	t.Helper()
+	s := &standIn{t: t}
+	upgrader := websocket.Upgrader{}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		s.mutex.Lock()
+		index := len(s.conns)
+		s.conns = append(s.conns, conn)
+		s.requests = append(s.requests, nil)
+		s.writes = append(s.writes, &sync.Mutex{})
+		s.mutex.Unlock()
+
+		for {
+			var request model.BybitRequest
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			if request.Op != "ping" {
+				s.mutex.Lock()
+				s.requests[index] = append(s.requests[index], request)
+				s.mutex.Unlock()
+			}
+			s.send(index, map[string]interface{}{"success": true, "ret_msg": "", "op": request.Op, "req_id": request.ReqID})
+		}
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// send 向第 index 条连接推送消息
+func (s *standIn) send(index int, message interface{}) error {
+	s.mutex.Lock()
+	conn, write := s.conns[index], s.writes[index]
+	s.mutex.Unlock()
+	write.Lock()
+	defer write.Unlock()
+	return conn.WriteJSON(message)
+}
+
+// sendTicker 推送 tickers 数据
+func (s *standIn) sendTicker(index int, kind string, ticker model.BybitTicker, ts int64) error {
+	data, _ := json.Marshal(ticker)
+	return s.send(index, model.BybitMessage{Topic: tickerTopicPrefix + ticker.Symbol, Type: kind, Ts: ts, Data: data})
+}
+
+// closeConn 服务端主动断开第 index 条连接
+func (s *standIn) closeConn(index int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.conns[index].Close()
+}
+
+func (s *standIn) connCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.conns)
+}
+
+// args 第 index 条连接收到的 op 请求中的全部 topic
+func (s *standIn) args(index int, op string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var args []string
+	if index >= len(s.requests) {
+		return nil
+	}
+	for _, request := range s.requests[index] {
+		if request.Op == op {
+			args = append(args, request.Args...)
+		}
+	}
+	return args
+}
+
+// newTestController 创建连接到本地模拟服务的控制器，缩短各项超时便于测试
+func newTestController(s *standIn) *BybitController {
+	controller := NewBybitController()
+	controller.wsURL = "ws" + strings.TrimPrefix(s.server.URL, "http")
+	controller.pingInterval = 50 * time.Millisecond
+	controller.readTimeout = time.Second
+	controller.staleTimeout = 10 * time.Second
+	controller.minBackoff = 10 * time.Millisecond
+	controller.maxBackoff = 50 * time.Millisecond
+	controller.SetMessageChan(make(chan string, 10))
+	return controller
+}
+
+// startWatch 在后台启动监听，测试结束时停止并确认 Watch 已退出
+func startWatch(t *testing.T, controller *BybitController) chan model.PairData {
+	t.Helper()
+	pairDataChan := make(chan model.PairData, 100)
+	stopped := make(chan struct{})
+	go func() {
+		controller.Watch(pairDataChan)
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		controller.Stop()
+		select {
+		case <-stopped:
+		case <-time.After(2 * time.Second):
+			t.Error("Stop 之后 Watch 应该退出")
+		}
+	})
+	return pairDataChan
+}
+
+// waitFor 等待条件满足
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// receive 读取一条价格推送
+func receive(t *testing.T, pairDataChan chan model.PairData) model.PairData {
+	t.Helper()
+	select {
+	case pairData := <-pairDataChan:
+		return pairData
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待价格推送超时")
+		return model.PairData{}
+	}
+}
+
+// TestWatchMergesTickerDelta 测试 snapshot 之后的 delta 只更新变化的字段，资金费率从推送中获取
+func TestWatchMergesTickerDelta(t *testing.T) {
+	s := newStandIn(t)
+	controller := newTestController(s)
+	controller.SetSubscriptions([]string{"BTC/USDT:USDT"})
+	pairDataChan := startWatch(t, controller)
+
+	if !waitFor(t, 2*time.Second, func() bool { return len(s.args(0, "subscribe")) == 1 }) {
+		t.Fatal("连接后应订阅 tickers.BTCUSDT")
+	}
+	if args := s.args(0, "subscribe"); args[0] != "tickers.BTCUSDT" {
+		t.Errorf("订阅的 topic 错误: %v", args)
+	}
+
+	s.sendTicker(0, "snapshot", model.BybitTicker{Symbol: "BTCUSDT", Bid1Price: "65000", Ask1Price: "65000.5", FundingRate: "0.0001"}, 1700000000000)
+	first := receive(t, pairDataChan)
+	if first.Pair != "BTC/USDT:USDT" || first.BidPrice != 65000 || first.AskPrice != 65000.5 || first.EventTime != 1700000000000 {
+		t.Errorf("snapshot 转换错误: %+v", first)
+	}
+
+	// 只有买一价变化
+	s.sendTicker(0, "delta", model.BybitTicker{Symbol: "BTCUSDT", Bid1Price: "64990"}, 1700000000100)
+	second := receive(t, pairDataChan)
+	if second.BidPrice != 64990 || second.AskPrice != 65000.5 {
+		t.Errorf("delta 应保留未变化的卖一价: %+v", second)
+	}
+
+	// 只有资金费率变化时不推送价格
+	s.sendTicker(0, "delta", model.BybitTicker{Symbol: "BTCUSDT", FundingRate: "-0.0002"}, 1700000000200)
+	if !waitFor(t, 2*time.Second, func() bool {
+		rate, err := controller.GetFundingRate("BTC/USDT:USDT")
+		return err == nil && math.Abs(rate+0.02) < 1e-9
+	}) {
+		t.Error("资金费率应更新为推送中的值，并转换为百分比")
+	}
+	select {
+	case pairData := <-pairDataChan:
+		t.Errorf("只有资金费率变化时不应推送价格: %+v", pairData)
+	default:
+	}
+}
+
+// TestSubscriptionsUpdateAndResubscribe 测试连接中增删交易对只发送变化部分，重连后重新订阅全部 topic
+func TestSubscriptionsUpdateAndResubscribe(t *testing.T) {
+	s := newStandIn(t)
+	controller := newTestController(s)
+	controller.maxArgs = 2
+	controller.SetSubscriptions([]string{"BTC/USDT:USDT", "ETH/USDT:USDT", "SOL/USDT:USDT"})
+	startWatch(t, controller)
+
+	if !waitFor(t, 2*time.Second, func() bool { return len(s.args(0, "subscribe")) == 3 }) {
+		t.Fatalf("连接后应分批订阅 3 个 topic，实际 %v", s.args(0, "subscribe"))
+	}
+	s.mutex.Lock()
+	batches := len(s.requests[0])
+	s.mutex.Unlock()
+	if batches != 2 {
+		t.Errorf("每批最多 2 个 topic，期望 2 个请求，实际 %d", batches)
+	}
+
+	// 合成交易对不订阅，只订阅两条腿
+	controller.SetSubscriptions([]string{"ETH/USDT:USDT", "SOL/USDT:USDT", "DOGE/USDT:USDT", "ETH/BTC"})
+	if !waitFor(t, 2*time.Second, func() bool { return len(s.args(0, "unsubscribe")) == 1 }) {
+		t.Fatal("应取消订阅 BTC")
+	}
+	if args := s.args(0, "unsubscribe"); !reflect.DeepEqual(args, []string{"tickers.BTCUSDT"}) {
+		t.Errorf("取消订阅的 topic 错误: %v", args)
+	}
+	if args := s.args(0, "subscribe"); args[len(args)-1] != "tickers.DOGEUSDT" {
+		t.Errorf("应只新增订阅 DOGE: %v", args)
+	}
+
+	// 服务端断开后重连，重新订阅当前全部 topic
+	s.closeConn(0)
+	if !waitFor(t, 2*time.Second, func() bool { return s.connCount() == 2 && len(s.args(1, "subscribe")) == 3 }) {
+		t.Fatalf("重连后应重新订阅，实际 %v", s.args(1, "subscribe"))
+	}
+	expected := []string{"tickers.DOGEUSDT", "tickers.ETHUSDT", "tickers.SOLUSDT"}
+	if args := s.args(1, "subscribe"); !reflect.DeepEqual(args, expected) {
+		t.Errorf("重连后订阅的 topic 错误: %v", args)
+	}
+}
+
+// TestWatchReconnectsWhenStale 测试连接正常但没有行情推送时告警并重连
+func TestWatchReconnectsWhenStale(t *testing.T) {
+	s := newStandIn(t)
+	controller := newTestController(s)
+	controller.staleTimeout = 200 * time.Millisecond
+	controller.SetSubscriptions([]string{"BTC/USDT:USDT"})
+	startWatch(t, controller)
+
+	if !waitFor(t, 3*time.Second, func() bool { return s.connCount() >= 2 }) {
+		t.Fatal("行情停滞后应重新连接")
+	}
+	select {
+	case message := <-controller.messageChan:
+		if !strings.Contains(message, "Bybit") {
+			t.Errorf("告警内容错误: %s", message)
+		}
+	case <-time.After(time.Second):
+		t.Error("行情停滞时应发送告警")
+	}
+}
+
+// TestRESTFallbacks 测试没有推送数据时通过 REST 获取资金费率，并按交易规则取整和校验交易对
+func TestRESTFallbacks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("category") != "linear" {
+			t.Errorf("请求应指定 category=linear: %s", r.URL)
+		}
+		switch r.URL.Path {
+		case "/v5/market/tickers":
+			fmt.Fprintf(w, `{"retCode":0,"retMsg":"OK","result":{"list":[{"symbol":"%s","bid1Price":"3000","ask1Price":"3001","fundingRate":"0.00015"}]}}`, r.URL.Query().Get("symbol"))
+		case "/v5/market/instruments-info":
+			fmt.Fprint(w, `{"retCode":0,"retMsg":"OK","result":{"nextPageCursor":"","list":[
+				{"symbol":"ETHUSDT","contractType":"LinearPerpetual","status":"Trading","baseCoin":"ETH","quoteCoin":"USDT","settleCoin":"USDT","priceFilter":{"tickSize":"0.01"},"lotSizeFilter":{"qtyStep":"0.01","minNotionalValue":"5"}},
+				{"symbol":"LUNAUSDT","contractType":"LinearPerpetual","status":"Closed","baseCoin":"LUNA","quoteCoin":"USDT","settleCoin":"USDT","priceFilter":{"tickSize":"0.0001"},"lotSizeFilter":{"qtyStep":"1"}},
+				{"symbol":"BTC-27DEC24","contractType":"LinearFutures","status":"Trading","baseCoin":"BTC","quoteCoin":"USDC","settleCoin":"USDC","priceFilter":{"tickSize":"0.5"},"lotSizeFilter":{"qtyStep":"0.001"}}
+			]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	controller := NewBybitController()
+	controller.baseURL = server.URL
+
+	rate, err := controller.GetFundingRate("ETH/USDT:USDT")
+	if err != nil || math.Abs(rate-0.015) > 1e-9 {
+		t.Errorf("期望资金费率 0.015，实际 %v %v", rate, err)
+	}
+
+	if err := controller.LoadInstruments(); err != nil {
+		t.Fatalf("加载交易规则失败: %v", err)
+	}
+	if controller.Registry().Len() != 2 {
+		t.Errorf("只应加载永续合约，实际 %d 个", controller.Registry().Len())
+	}
+	if price := controller.RoundPrice("ETH/USDT:USDT", 3000.1234); price != 3000.12 {
+		t.Errorf("期望取整为 3000.12，实际 %v", price)
+	}
+	if err := controller.CheckSymbol("ETH/USDT:USDT"); err != nil {
+		t.Errorf("ETH 应可以交易: %v", err)
+	}
+	if err := controller.CheckSymbol("LUNA/USDT:USDT"); err == nil {
+		t.Error("非 Trading 状态的合约应返回错误")
+	}
+	if err := controller.CheckSymbol("PEPE/USDT:USDT"); err == nil {
+		t.Error("不存在的合约应返回错误")
+	}
+	if pair := controller.ResolvePair("eth"); pair != "ETH/USDT:USDT" {
+		t.Errorf("期望解析为 ETH/USDT:USDT，实际 %s", pair)
+	}
+}
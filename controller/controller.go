@@ -4,99 +4,309 @@ import (
 	"fmt"
 	"log"
 	"monitor-trade/config"
-	"monitor-trade/controller/binance"
+	"monitor-trade/controller/feed"
+	"monitor-trade/controller/freqtrade"
 	"monitor-trade/controller/redis"
 	"monitor-trade/controller/tg"
 	"monitor-trade/model"
+	"sync/atomic"
+	"time"
 )
 
 type MainController struct {
-	TgController      *tg.TgController
-	RedisController   *redis.RedisController
-	Conf              *config.Config
-	BinanceController *binance.BinanceController
-	WatchKey          chan model.PairData
-	TradeChan         chan model.ForceBuyPayload
+	TgController    *tg.TgController
+	RedisController *redis.RedisController
+	Conf            *config.Config
+	Feed            feed.PriceFeed // 行情数据源
+	Freqtrade       *freqtrade.FreqtradeController
+	WatchKey        chan model.PairData
+	TradeChan       chan model.ForceBuyPayload
+	workers         map[string]chan model.PairData // 每个交易对一个处理协程，保证同一交易对的推送按顺序处理
+
+	clock   func() time.Time                   // 评估触发条件使用的当前时间，为空时使用 RedisController 的时钟；回测时为历史数据的时间
+	funding func(pair string) (float64, error) // 资金费率数据源，为空时从行情源获取
+
+	recorded    bool                  // 回放录制文件：按到达顺序同步处理推送，当前时间为推送录制时的接收时间
+	recordedNow atomic.Int64          // 最近处理的推送录制时的接收时间（Unix毫秒）
+	ticks       map[string]*tickState // 回放时各交易对的处理状态，只在 Start 协程中使用
+}
+
+// tickState 单个交易对连续处理推送时保留的状态
+type tickState struct {
+	lastPrice   float64 // 上一条推送的中间价
+	staleLogged bool    // 是否已记录价格过期
 }
 
 // NewMainController 创建MainController
 func NewMainController(tgController *tg.TgController, redisController *redis.RedisController,
-	conf *config.Config, binanceController *binance.BinanceController, tradeChan chan model.ForceBuyPayload) *MainController {
+	conf *config.Config, priceFeed feed.PriceFeed, freqtradeController *freqtrade.FreqtradeController,
+	tradeChan chan model.ForceBuyPayload) *MainController {
 	return &MainController{
-		TgController:      tgController,
-		RedisController:   redisController,
-		WatchKey:          make(chan model.PairData, 200),
-		Conf:              conf,
-		BinanceController: binanceController,
-		TradeChan:         tradeChan,
+		TgController:    tgController,
+		RedisController: redisController,
+		WatchKey:        make(chan model.PairData, 200),
+		Conf:            conf,
+		Feed:            priceFeed,
+		Freqtrade:       freqtradeController,
+		TradeChan:       tradeChan,
+		workers:         make(map[string]chan model.PairData, 1000),
+	}
+}
+
+// UseRecordedTime 回放录制文件时调用：推送按到达顺序同步处理，触发条件、时效、确认窗口以及
+// 监控状态时间、冷却和风控都以推送录制时的接收时间为当前时间，与回放倍速无关
+func (c *MainController) UseRecordedTime() {
+	c.recorded = true
+	c.ticks = make(map[string]*tickState)
+	c.clock = func() time.Time {
+		return time.UnixMilli(c.recordedNow.Load())
 	}
+	c.RedisController.SetClock(c.clock)
+}
+
+// SetFundingRate 使用固定的资金费率(%)评估资金费率条件，回放时不请求交易所
+func (c *MainController) SetFundingRate(rate float64) {
+	c.funding = func(string) (float64, error) { return rate, nil }
 }
 
 func (c *MainController) Start() {
 	for pairData := range c.WatchKey {
+		c.dispatch(pairData)
+	}
+}
+
+// dispatch 将价格推送交给交易对对应的处理协程
+func (c *MainController) dispatch(pairData model.PairData) {
+	if c.recorded {
+		// 回放时在当前协程处理，时钟停在这条推送的录制时间
+		if pairData.ReceiveTime > c.recordedNow.Load() {
+			c.recordedNow.Store(pairData.ReceiveTime)
+		}
+		state, exists := c.ticks[pairData.Pair]
+		if !exists {
+			state = &tickState{}
+			c.ticks[pairData.Pair] = state
+		}
+		c.processTick(pairData, state)
+	} else {
+		worker, exists := c.workers[pairData.Pair]
+		if !exists {
+			worker = make(chan model.PairData, 100)
+			c.workers[pairData.Pair] = worker
+			go c.runWorker(worker)
+		}
+
+		select {
+		case worker <- pairData:
+		default:
+			log.Printf("交易对 %s 处理队列已满，跳过这次推送", pairData.Pair)
+		}
+	}
+
+	// 腿的价格变化同时驱动依赖它的合成交易对
+	if !model.IsSyntheticPair(pairData.Pair) {
+		c.dispatchSynthetic(pairData)
+	}
+}
+
+// runWorker 顺序处理单个交易对的价格推送
+func (c *MainController) runWorker(worker chan model.PairData) {
+	state := &tickState{}
+	for pairData := range worker {
+		c.processTick(pairData, state)
+	}
+}
+
+// processTick 处理一条价格推送
+func (c *MainController) processTick(pairData model.PairData, state *tickState) {
+	// 价格数据过旧（处理队列积压或合成交易对的某条腿停滞）时不触发任何交易，只处理提醒
+	now := c.now()
+	stale := pairData.IsStale(now, c.priceMaxAge())
+	if stale != state.staleLogged {
+		if stale {
+			log.Printf("⚠️ 交易对 %s 价格数据已过期 %s，暂停触发交易", pairData.Pair, pairData.Age(now))
+		} else {
+			log.Printf("交易对 %s 价格数据已恢复", pairData.Pair)
+		}
+		state.staleLogged = stale
+	}
+
+	lastPrice := state.lastPrice
+	// 暂停期间不评估入场和加仓监控，不向交易通道提交请求；离场和提醒照常处理
+	if !stale && !c.RedisController.IsPaused() {
 		// 处理短线
-		go c.HandleShort(&pairData)
+		c.HandleShort(&pairData, lastPrice)
 		// 处理长线
-		go c.HandleLong(&pairData)
+		c.HandleLong(&pairData, lastPrice)
+		// 处理加仓
+		c.HandleAdd(&pairData, lastPrice)
+	}
+	if !stale {
+		// 处理止盈/止损离场
+		c.HandleExit(&pairData, lastPrice, tg.TakeProfitDirect)
+		c.HandleExit(&pairData, lastPrice, tg.StopLossDirect)
+	}
+	// 处理价格提醒
+	c.HandleAlert(&pairData, lastPrice, tg.AlertUpDirect)
+	c.HandleAlert(&pairData, lastPrice, tg.AlertDownDirect)
+	state.lastPrice = midPrice(&pairData)
+}
+
+// priceMaxAge 触发交易时允许的最长价格数据时长
+func (c *MainController) priceMaxAge() time.Duration {
+	return time.Duration(c.Conf.PriceMaxAgeMs) * time.Millisecond
+}
+
+// now 评估触发条件使用的当前时间
+func (c *MainController) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return c.RedisController.Now()
+}
+
+// fundingRate 获取交易对当前的资金费率
+func (c *MainController) fundingRate(pair string) (float64, error) {
+	if c.funding != nil {
+		return c.funding(pair)
+	}
+	return c.Feed.GetFundingRate(pair)
+}
+
+// checkCondition 评估监控的触发条件，追踪入场的极值变化会同步保存到Redis，确认进度保存在本地
+func (c *MainController) checkCondition(monitorData model.PairMonitorData, pairData *model.PairData, lastPrice float64) (bool, error) {
+	tc := &TriggerContext{
+		PairData:  pairData,
+		LastPrice: lastPrice,
+		FundingRate: func() (float64, error) {
+			return c.fundingRate(pairData.Pair)
+		},
+		Now: c.now(),
 	}
+
+	if monitorData.CandleClose != "" {
+		if candle, ok := c.RedisController.GetLastClosedCandle(pairData.Pair, monitorData.CandleClose); ok {
+			tc.Candle = &candle
+		}
+	}
+
+	c.refreshATRLevel(&monitorData)
+
+	oldExtreme := monitorData.TrailExtreme
+	progress := c.RedisController.GetConfirmProgress(monitorData.Pair, monitorData.Direct)
+	triggered, err := EvaluateMonitor(&monitorData, &progress, tc, c.Conf.FundingRate)
+	if monitorData.TrailExtreme != oldExtreme {
+		c.RedisController.UpdateMonitorTrailExtreme(monitorData.Pair, monitorData.Direct, monitorData.TrailExtreme)
+	}
+	if triggered {
+		progress = model.ConfirmProgress{}
+	}
+	c.RedisController.SetConfirmProgress(monitorData.Pair, monitorData.Direct, progress)
+	return triggered, err
 }
 
-func (c *MainController) HandleShort(pairData *model.PairData) {
+func (c *MainController) HandleShort(pairData *model.PairData, lastPrice float64) {
 	shortData, exists := c.RedisController.GetMonitorPair(pairData.Pair, tg.ShortDirect)
 	if !exists {
 		return
 	}
-	if shortData.Price <= 0 {
+	if shortData.Price <= 0 || !shortData.IsArmed() {
+		return
+	}
+	if len(shortData.Ladder) > 0 {
+		c.handleLadder(shortData, pairData, lastPrice)
 		return
 	}
 
-	if pairData.AskPrice > shortData.Price {
-		// 检查资金费率条件
-		fundingRate, err := c.BinanceController.GetFundingRate(pairData.Pair)
-		if err != nil {
-			log.Printf("获取交易对 %s 的资金费率失败: %v", pairData.Pair, err)
-			resultMsg := fmt.Sprintf("❌ %s 做空操作失败: 获取资金费率失败 - %v", pairData.Pair, err)
-			c.TgController.SendMessage(resultMsg)
-			return
-		}
-		if fundingRate <= c.Conf.FundingRate {
-			log.Printf("%s 的资金费率 %.6f 小于等于阈值 %.6f，跳过做空处理",
-				pairData.Pair, fundingRate, c.Conf.FundingRate)
-			return
-		}
+	triggered, err := c.checkCondition(shortData, pairData, lastPrice)
+	if err != nil {
+		log.Printf("交易对 %s 做空条件评估失败: %v", pairData.Pair, err)
+		resultMsg := fmt.Sprintf("❌ %s 做空操作失败: %v", pairData.Pair, err)
+		c.TgController.SendMessage(resultMsg)
+		return
+	}
+	// OCO 的另一方已触发时等待其交易结果，避免两个方向都提交
+	if !triggered || c.RedisController.OcoPeerPending(shortData) {
+		return
+	}
+	// 只有从 armed 切换到 triggered 成功的协程才能发出交易请求
+	if !c.RedisController.TransitionMonitorState(pairData.Pair, tg.ShortDirect, model.MonitorStateTriggered, model.MonitorStateArmed) {
+		return
+	}
+	if model.IsSyntheticPair(shortData.Pair) {
+		c.submitSyntheticLegs(shortData, pairData)
+		return
+	}
 
-		log.Printf("时间戳 %s 交易对 %s 的当前卖单价 %.6f 高于做空价格 %.6f，资金费率 %.6f，执行做空操作",
-			pairData.Timestamp, pairData.Pair, pairData.AskPrice, shortData.Price, fundingRate)
+	log.Printf("时间戳 %s 交易对 %s 的当前卖单价 %.6f 满足做空条件，限价 %.6f，执行做空操作",
+		pairData.Timestamp, pairData.Pair, pairData.AskPrice, shortData.Price)
 
-		c.TradeChan <- model.ForceBuyPayload{
-			Pair:      pairData.Pair,
-			Price:     pairData.AskPrice,
-			Side:      "short",
-			EntryTag:  "force_entry",
-			OrderType: "limit",
-		}
-	}
+	c.submitTrade(model.ForceBuyPayload{
+		Pair:      pairData.Pair,
+		Price:     pairData.AskPrice,
+		Side:      "short",
+		EntryTag:  "force_entry",
+		OrderType: "limit",
+	})
 }
 
-func (c *MainController) HandleLong(pairData *model.PairData) {
+func (c *MainController) HandleLong(pairData *model.PairData, lastPrice float64) {
 	longData, exists := c.RedisController.GetMonitorPair(pairData.Pair, tg.LongDirect)
 	if !exists {
 		return
 	}
-	if longData.Price <= 0 {
+	if longData.Price <= 0 || !longData.IsArmed() {
 		return
 	}
-	if pairData.BidPrice < longData.Price {
-		// 当前买单价(最低价) < 做多价格
-		log.Printf("时间戳 %s 交易对 %s 的当前买单价 %.6f 低于做多价格 %.6f，执行做多操作",
-			pairData.Timestamp, pairData.Pair, pairData.BidPrice, longData.Price)
-
-		c.TradeChan <- model.ForceBuyPayload{
-			Pair:      pairData.Pair,
-			Price:     pairData.BidPrice,
-			Side:      "long",
-			EntryTag:  "force_entry",
-			OrderType: "limit",
-		}
+	if len(longData.Ladder) > 0 {
+		c.handleLadder(longData, pairData, lastPrice)
+		return
+	}
+
+	triggered, err := c.checkCondition(longData, pairData, lastPrice)
+	if err != nil {
+		log.Printf("交易对 %s 做多条件评估失败: %v", pairData.Pair, err)
+		resultMsg := fmt.Sprintf("❌ %s 做多操作失败: %v", pairData.Pair, err)
+		c.TgController.SendMessage(resultMsg)
+		return
+	}
+	// OCO 的另一方已触发时等待其交易结果，避免两个方向都提交
+	if !triggered || c.RedisController.OcoPeerPending(longData) {
+		return
+	}
+	// 只有从 armed 切换到 triggered 成功的协程才能发出交易请求
+	if !c.RedisController.TransitionMonitorState(pairData.Pair, tg.LongDirect, model.MonitorStateTriggered, model.MonitorStateArmed) {
+		return
+	}
+	if model.IsSyntheticPair(longData.Pair) {
+		c.submitSyntheticLegs(longData, pairData)
+		return
+	}
+
+	log.Printf("时间戳 %s 交易对 %s 的当前买单价 %.6f 满足做多条件，限价 %.6f，执行做多操作",
+		pairData.Timestamp, pairData.Pair, pairData.BidPrice, longData.Price)
+
+	c.submitTrade(model.ForceBuyPayload{
+		Pair:      pairData.Pair,
+		Price:     pairData.BidPrice,
+		Side:      "long",
+		EntryTag:  "force_entry",
+		OrderType: "limit",
+	})
+}
+
+// submitTrade 将交易请求的价格按 tickSize 取整后提交到交易通道，避免 Freqtrade 收到精度不合法的价格
+func (c *MainController) submitTrade(payload model.ForceBuyPayload) {
+	payload.Price = c.Feed.RoundPrice(payload.Pair, payload.Price)
+	c.TradeChan <- payload
+}
+
+// cancelOcoPeer 离场监控的平仓请求被接受后取消 OCO 关联的另一方向监控，入场和加仓在交易通道提交成功后取消
+func (c *MainController) cancelOcoPeer(data model.PairMonitorData) {
+	peer, ok := c.RedisController.CancelOcoPeer(data.Pair, data.Direct)
+	if !ok {
+		return
 	}
+	c.TgController.SendMessage(fmt.Sprintf("🔗 %s %s监控的交易已提交，OCO 关联的 %s 监控已自动取消，限价: %.6f",
+		data.Pair, data.Direct, peer.Direct, peer.Price))
 }
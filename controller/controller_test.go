@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"monitor-trade/config"
+	"monitor-trade/controller/binance"
+	"monitor-trade/controller/redis"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+	"testing"
+	"time"
+)
+
+// TestPairDataIsStale 测试价格数据的时效判断，合成交易对以较旧的腿为准
+func TestPairDataIsStale(t *testing.T) {
+	now := time.UnixMilli(1700000010000)
+	maxAge := 5 * time.Second
+
+	fresh := model.PairData{BidPrice: 3000, AskPrice: 3001, EventTime: 1700000008900, ReceiveTime: 1700000009000}
+	if age := fresh.Age(now); age != time.Second {
+		t.Errorf("期望时长 1s，实际 %s", age)
+	}
+	if fresh.IsStale(now, maxAge) {
+		t.Error("1 秒前的价格不应过期")
+	}
+
+	old := model.PairData{BidPrice: 60000, AskPrice: 60010, EventTime: 1700000001900, ReceiveTime: 1700000002000}
+	if !old.IsStale(now, maxAge) {
+		t.Error("8 秒前的价格应该过期")
+	}
+	if old.IsStale(now, 0) {
+		t.Error("未设置上限时不应检查过期")
+	}
+
+	// 没有接收时间时使用事件时间，两者都没有时视为过期
+	if (model.PairData{EventTime: 1700000009000}).IsStale(now, maxAge) {
+		t.Error("只有事件时间时应按事件时间判断")
+	}
+	if !(model.PairData{}).IsStale(now, maxAge) {
+		t.Error("没有时间信息的价格应该过期")
+	}
+
+	synthetic := model.SyntheticPairData("ETH/BTC", fresh, old)
+	if synthetic.ReceiveTime != old.ReceiveTime || synthetic.EventTime != old.EventTime {
+		t.Errorf("合成交易对应使用较旧腿的时间: %+v", synthetic)
+	}
+	if !synthetic.IsStale(now, maxAge) {
+		t.Error("任意一条腿过期时合成交易对应该过期")
+	}
+}
+
+// freshTick 生成接收时间为当前时间的价格推送
+func freshTick(pair string, bid, ask float64) model.PairData {
+	now := time.Now().UnixMilli()
+	return model.PairData{Pair: pair, BidPrice: bid, AskPrice: ask, EventTime: now, ReceiveTime: now}
+}
+
+// TestPauseBlocksEntries 测试暂停期间价格越过限价也不提交入场交易，恢复后重新触发
+func TestPauseBlocksEntries(t *testing.T) {
+	conf := &config.Config{PriceMaxAgeMs: 5000}
+	redisController := redis.NewLocalRedisController(conf)
+	pair := "BTC/USDT:USDT"
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: pair, Direct: tg.ShortDirect, Price: 65000,
+		Condition: &model.Condition{Type: model.ConditionLevel}})
+
+	tradeChan := make(chan model.ForceBuyPayload, 10)
+	mainController := NewMainController(nil, redisController, conf, binance.NewBinanceController(), nil, tradeChan)
+	go mainController.Start()
+	defer close(mainController.WatchKey)
+
+	if err := redisController.Pause("test", time.Now().Unix()); err != nil {
+		t.Fatalf("暂停失败: %v", err)
+	}
+	mainController.WatchKey <- freshTick(pair, 65090, 65100)
+	select {
+	case payload := <-tradeChan:
+		t.Fatalf("暂停期间不应提交交易: %+v", payload)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if data, _ := redisController.GetMonitorPair(pair, tg.ShortDirect); !data.IsArmed() {
+		t.Errorf("暂停期间监控应保持 armed，实际 %q", data.State)
+	}
+
+	if err := redisController.Resume(); err != nil {
+		t.Fatalf("恢复失败: %v", err)
+	}
+	mainController.WatchKey <- freshTick(pair, 65090, 65100)
+	select {
+	case payload := <-tradeChan:
+		if payload.Pair != pair || payload.Side != "short" {
+			t.Errorf("交易请求错误: %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("恢复后价格越过限价应该触发做空")
+	}
+}
+
+// TestOcoPeerWaitsForTrade 测试 OCO 一方触发后另一方保留，在触发方的交易处理完之前不会触发；
+// 触发方的交易被拒绝恢复为 armed 后，另一方可以正常触发
+func TestOcoPeerWaitsForTrade(t *testing.T) {
+	conf := &config.Config{PriceMaxAgeMs: 5000}
+	redisController := redis.NewLocalRedisController(conf)
+	pair := "BTC/USDT:USDT"
+	level := &model.Condition{Type: model.ConditionLevel}
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: pair, Direct: tg.LongDirect, Price: 64000, OCO: tg.ShortDirect, Condition: level})
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: pair, Direct: tg.ShortDirect, Price: 66000, OCO: tg.LongDirect, Condition: level})
+
+	tradeChan := make(chan model.ForceBuyPayload, 10)
+	mainController := NewMainController(nil, redisController, conf, binance.NewBinanceController(), nil, tradeChan)
+	go mainController.Start()
+	defer close(mainController.WatchKey)
+
+	mainController.WatchKey <- freshTick(pair, 66090, 66100)
+	select {
+	case payload := <-tradeChan:
+		if payload.Side != "short" {
+			t.Errorf("应触发做空，实际: %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("卖价突破限价后应该触发做空")
+	}
+	if data, exists := redisController.GetMonitorPair(pair, tg.LongDirect); !exists || !data.IsArmed() {
+		t.Errorf("做空的交易还没有被接受，做多监控应保留，实际 %+v", data)
+	}
+
+	// 做空的交易还在处理中，价格跌破做多限价也不提交
+	mainController.WatchKey <- freshTick(pair, 63900, 63910)
+	select {
+	case payload := <-tradeChan:
+		t.Errorf("OCO 另一方的交易处理完之前不应触发: %+v", payload)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// 做空的交易被拒绝，恢复为 armed，做多监控可以触发
+	if !redisController.TransitionMonitorState(pair, tg.ShortDirect, model.MonitorStateArmed, model.MonitorStateTriggered) {
+		t.Fatal("做空监控应能恢复为 armed")
+	}
+	mainController.WatchKey <- freshTick(pair, 63900, 63910)
+	select {
+	case payload := <-tradeChan:
+		if payload.Side != "long" {
+			t.Errorf("应触发做多，实际: %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("做空的交易被拒绝后做多监控应该能触发")
+	}
+	if _, exists := redisController.GetMonitorPair(pair, tg.ShortDirect); !exists {
+		t.Error("做多只是触发，交易还没有被接受，不应取消做空监控")
+	}
+}
+
+// TestCancelOcoPeer 测试交易被接受后只取消仍指向本方向且在等待的另一方，另一方已重新设置或已触发时不被取消
+func TestCancelOcoPeer(t *testing.T) {
+	redisController := redis.NewLocalRedisController(&config.Config{})
+	pair := "ETH/USDT:USDT"
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: pair, Direct: tg.ShortDirect, Price: 3100, OCO: tg.LongDirect, State: model.MonitorStateSubmitted})
+
+	tests := []struct {
+		name string
+		peer model.PairMonitorData
+	}{
+		{"重新设置的普通监控", model.PairMonitorData{Pair: pair, Direct: tg.LongDirect, Price: 2900}},
+		{"已触发的关联监控", model.PairMonitorData{Pair: pair, Direct: tg.LongDirect, Price: 2900, OCO: tg.ShortDirect, State: model.MonitorStateTriggered}},
+	}
+	for _, tt := range tests {
+		redisController.SetLocalMonitorPair(tt.peer)
+		if _, ok := redisController.CancelOcoPeer(pair, tg.ShortDirect); ok {
+			t.Errorf("%s不应被取消", tt.name)
+		}
+		if _, exists := redisController.GetMonitorPair(pair, tg.LongDirect); !exists {
+			t.Errorf("%s不应被删除", tt.name)
+		}
+	}
+
+	// 没有 OCO 关联的监控不取消任何监控
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: pair, Direct: tg.LongDirect, Price: 2900})
+	if _, ok := redisController.CancelOcoPeer(pair, tg.LongDirect); ok {
+		t.Error("没有 OCO 关联的监控不应取消另一方")
+	}
+	if _, exists := redisController.GetMonitorPair(pair, tg.ShortDirect); !exists {
+		t.Error("没有 OCO 关联的监控不应删除另一方")
+	}
+
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: pair, Direct: tg.LongDirect, Price: 2900, OCO: tg.ShortDirect})
+	if peer, ok := redisController.CancelOcoPeer(pair, tg.ShortDirect); !ok || peer.Direct != tg.LongDirect {
+		t.Errorf("仍在等待的关联监控应被取消，实际 %+v, %v", peer, ok)
+	}
+	if _, exists := redisController.GetMonitorPair(pair, tg.LongDirect); exists {
+		t.Error("被取消的关联监控应被删除")
+	}
+}
+
+// TestMonitorUpdatesRequireArmed 测试追踪极值和 ATR 限价只写入仍在 armed 的监控，不会覆盖已触发的状态，也不会重建已删除的监控
+func TestMonitorUpdatesRequireArmed(t *testing.T) {
+	redisController := redis.NewLocalRedisController(&config.Config{})
+	pair := "BTC/USDT:USDT"
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: pair, Direct: tg.ShortDirect, Price: 65000, TrailPercent: 0.5, TrailExtreme: 65100})
+
+	if !redisController.UpdateMonitorTrailExtreme(pair, tg.ShortDirect, 65200) || !redisController.UpdateMonitorPrice(pair, tg.ShortDirect, 65050) {
+		t.Fatal("armed 的监控应该更新极值和限价")
+	}
+	if redisController.UpdateMonitorTrailExtreme(pair, tg.ShortDirect, 65150) {
+		t.Error("做空时更低的极值不应写入")
+	}
+
+	if !redisController.TransitionMonitorState(pair, tg.ShortDirect, model.MonitorStateTriggered, model.MonitorStateArmed) {
+		t.Fatal("监控应该切换到 triggered")
+	}
+	if redisController.UpdateMonitorTrailExtreme(pair, tg.ShortDirect, 65300) || redisController.UpdateMonitorPrice(pair, tg.ShortDirect, 65100) {
+		t.Error("已触发的监控不应再更新极值和限价")
+	}
+	data, _ := redisController.GetMonitorPair(pair, tg.ShortDirect)
+	if data.State != model.MonitorStateTriggered || data.TrailExtreme != 65200 || data.Price != 65050 {
+		t.Errorf("已触发的监控不应被修改: %+v", data)
+	}
+
+	redisController.DeleteMonitorPair(pair, tg.ShortDirect)
+	if redisController.UpdateMonitorTrailExtreme(pair, tg.ShortDirect, 65400) || redisController.HasMonitorPair(pair, tg.ShortDirect) {
+		t.Error("已删除的监控不应被重建")
+	}
+}
+
+// TestAlertFiresOnlyOnCross 测试提醒只在价格从记录的一侧越过提醒价时触发，价格已在另一侧时只记录位置；
+// 重复提醒重新布防后需要价格先回到起始一侧
+func TestAlertFiresOnlyOnCross(t *testing.T) {
+	conf := &config.Config{}
+	redisController := redis.NewLocalRedisController(conf)
+	mainController := NewMainController(nil, redisController, conf, binance.NewBinanceController(), nil, nil)
+	now := time.Unix(1700000000, 0)
+	mainController.clock = func() time.Time { return now }
+	redisController.SetClock(mainController.clock)
+
+	pair := "BTC/USDT:USDT"
+	// 没有记录位置的旧提醒，价格已在提醒价上方
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: pair, Direct: tg.AlertUpDirect, Price: 70000, RepeatSeconds: 60})
+	alert := func(mid float64) model.PairMonitorData {
+		pairData := model.PairData{Pair: pair, BidPrice: mid - 5, AskPrice: mid + 5}
+		mainController.HandleAlert(&pairData, 0, tg.AlertUpDirect)
+		data, _ := redisController.GetMonitorPair(pair, tg.AlertUpDirect)
+		return data
+	}
+
+	if data := alert(70100); !data.IsArmed() || data.PriceSide != model.PriceSideAbove {
+		t.Fatalf("价格已在提醒价上方时不应提醒，只记录位置，实际 %+v", data)
+	}
+	if data := alert(69900); !data.IsArmed() || data.PriceSide != model.PriceSideBelow {
+		t.Fatalf("价格回到下方应记录位置，实际 %+v", data)
+	}
+	if data := alert(70050); data.State != model.MonitorStateTriggered || data.PriceSide != model.PriceSideAbove {
+		t.Fatalf("价格从下方上穿提醒价应提醒，实际 %+v", data)
+	}
+
+	// 重复间隔后重新布防，价格仍在上方不再提醒
+	now = now.Add(61 * time.Second)
+	if data := alert(70100); !data.IsArmed() {
+		t.Fatalf("价格停留在提醒价上方时不应重复提醒，实际 %+v", data)
+	}
+	alert(69900)
+	if data := alert(70010); data.State != model.MonitorStateTriggered {
+		t.Errorf("价格回到下方后再次上穿应提醒，实际 %+v", data)
+	}
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+	"strconv"
+)
+
+// HandleExit 处理关联已有交易的止盈/止损离场监控，满足条件时通过 forcesell 平仓
+func (c *MainController) HandleExit(pairData *model.PairData, lastPrice float64, direct string) {
+	exitData, exists := c.RedisController.GetMonitorPair(pairData.Pair, direct)
+	if !exists {
+		return
+	}
+	if exitData.Price <= 0 || !exitData.HasTrade() || !exitData.IsArmed() {
+		return
+	}
+
+	triggered, err := c.checkCondition(exitData, pairData, lastPrice)
+	if err != nil {
+		log.Printf("交易对 %s %s条件评估失败: %v", pairData.Pair, exitName(direct), err)
+		c.TgController.SendMessage(fmt.Sprintf("❌ %s %s操作失败: %v", pairData.Pair, exitName(direct), err))
+		return
+	}
+	if !triggered || c.RedisController.OcoPeerPending(exitData) {
+		return
+	}
+	// 只有从 armed 切换到 triggered 成功的协程才能发出平仓请求
+	if !c.RedisController.TransitionMonitorState(pairData.Pair, direct, model.MonitorStateTriggered, model.MonitorStateArmed) {
+		return
+	}
+
+	log.Printf("时间戳 %s 交易对 %s 买价 %.6f 卖价 %.6f 满足%s条件，限价 %.6f，执行平仓操作",
+		pairData.Timestamp, pairData.Pair, pairData.BidPrice, pairData.AskPrice, exitName(direct), exitData.Price)
+
+	// forcesell 是同步的 HTTP 请求，放到单独的协程里避免阻塞该交易对的推送处理
+	go c.executeExit(exitData)
+}
+
+// executeExit 对离场监控关联的交易执行 forcesell，并推进监控状态
+func (c *MainController) executeExit(exitData model.PairMonitorData) {
+	name := exitName(exitData.Direct)
+	trade, exists := c.Freqtrade.GetOpenTrade(exitData.TradeId)
+	if !exists {
+		c.RedisController.TransitionMonitorState(exitData.Pair, exitData.Direct, model.MonitorStateFailed, model.MonitorStateTriggered)
+		c.TgController.SendMessage(fmt.Sprintf("❌ %s %s操作失败: 交易 %d 已不存在，监控已标记为失败", exitData.Pair, name, exitData.TradeId))
+		return
+	}
+
+	// 止盈使用限价单，止损使用市价单保证成交
+	orderType := "market"
+	if exitData.Direct == tg.TakeProfitDirect {
+		orderType = "limit"
+	}
+	amount := ""
+	if exitData.ExitPercent > 0 && exitData.ExitPercent < 100 {
+		amount = fmt.Sprintf("%.6f", trade.Amount*exitData.ExitPercent/100)
+	}
+
+	err := c.Freqtrade.ForceSell(strconv.Itoa(trade.TradeId), orderType, amount)
+	if err != nil {
+		log.Printf("❌ %s %s操作失败: %v", exitData.Pair, name, err)
+		c.RedisController.TransitionMonitorState(exitData.Pair, exitData.Direct, model.MonitorStateFailed, model.MonitorStateTriggered)
+		c.TgController.SendMessage(fmt.Sprintf("❌ %s %s操作失败: %v，监控已标记为失败", exitData.Pair, name, err))
+		return
+	}
+
+	if c.RedisController.TransitionMonitorState(exitData.Pair, exitData.Direct, model.MonitorStateSubmitted, model.MonitorStateTriggered) {
+		c.cancelOcoPeer(exitData)
+	}
+	c.TgController.SendMessage(fmt.Sprintf("✅ %s %s操作提交成功，限价: %.6f，平仓比例: %s",
+		exitData.Pair, name, exitData.Price, tg.FormatExitPercent(exitData.ExitPercent)))
+}
+
+// exitName 离场监控的名称
+func exitName(direct string) string {
+	if direct == tg.TakeProfitDirect {
+		return "止盈"
+	}
+	return "止损"
+}
@@ -0,0 +1,53 @@
+package feed
+
+import (
+	"log"
+	"monitor-trade/controller/redis"
+	"monitor-trade/model"
+	"time"
+)
+
+// PriceFeed 交易所行情数据源：推送最优挂单价格，提供资金费率、历史K线和合约交易规则
+type PriceFeed interface {
+	// Watch 将价格推送发送到 changePairDataChan，直到调用 Stop
+	Watch(changePairDataChan chan model.PairData)
+	// Stop 停止推送并关闭连接
+	Stop()
+	// GetFundingRate 交易对当前的资金费率，百分比形式，如 0.01 表示 0.01%，与 FUNDING_RATE 和 funding 条件的单位相同
+	GetFundingRate(pair string) (float64, error)
+	// PairPrice 交易对的最新价格，没有订阅时通过 REST 接口获取，合成交易对会补齐两条腿的价格
+	PairPrice(pair string) model.PairData
+	// LoadCandles 拉取历史K线补齐本地K线
+	LoadCandles(pair, timeframe string) error
+	// ResolvePair 将用户输入解析为 Freqtrade 交易对
+	ResolvePair(input string) string
+	// CheckSymbol 检查交易对在交易所是否存在且可以交易
+	CheckSymbol(pair string) error
+	// RoundPrice 按交易对的价格精度取整
+	RoundPrice(pair string, price float64) float64
+}
+
+// Store 保存最新价格，需要处理的交易对同时聚合K线，返回是否需要通知价格变化。
+// 各交易所的行情源收到推送后都通过这里写入本地数据
+func Store(redisController *redis.RedisController, pairData *model.PairData, ts time.Time) bool {
+	if redisController == nil {
+		return true
+	}
+	// 所有推送的价格都保存，供 Telegram 命令和合成交易对使用
+	redisController.UpdatePairPrice(pairData.Pair, pairData)
+	// 如果设置了监听列表，只通知列表中的交易对、设置了监控的交易对和合成交易对的腿
+	if !redisController.IsSubscribedPair(pairData.Pair) {
+		return false
+	}
+	redisController.UpdateCandles(pairData.Pair, pairData, ts)
+	return true
+}
+
+// Notify 通知价格更新，通道满时跳过这次通知
+func Notify(changePairDataChan chan model.PairData, pairData model.PairData) {
+	select {
+	case changePairDataChan <- pairData:
+	default:
+		log.Printf("价格更新通知channel满了，跳过这次通知: %s", pairData.Pair)
+	}
+}
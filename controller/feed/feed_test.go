@@ -0,0 +1,87 @@
+package feed_test
+
+import (
+	"fmt"
+	"math"
+	"monitor-trade/config"
+	"monitor-trade/controller/binance"
+	"monitor-trade/controller/bybit"
+	"monitor-trade/controller/feed"
+	"monitor-trade/controller/redis"
+	"monitor-trade/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fixtureTransport 把所有 REST 请求转发到本地模拟服务
+type fixtureTransport struct {
+	handler http.Handler
+}
+
+func (f fixtureTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	f.handler.ServeHTTP(recorder, r)
+	return recorder.Result(), nil
+}
+
+// TestFundingRateUnitsAgree 测试两个交易所返回相同资金费率时，行情源给出的百分比数值一致
+func TestFundingRateUnitsAgree(t *testing.T) {
+	const raw = "0.0001" // 两个交易所接口中的原始值，均为小数形式
+	client := &http.Client{Transport: fixtureTransport{http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/premiumIndex":
+			fmt.Fprintf(w, `{"symbol":"%s","lastFundingRate":"%s"}`, r.URL.Query().Get("symbol"), raw)
+		case "/v5/market/tickers":
+			fmt.Fprintf(w, `{"retCode":0,"retMsg":"OK","result":{"list":[{"symbol":"%s","fundingRate":"%s"}]}}`, r.URL.Query().Get("symbol"), raw)
+		default:
+			http.NotFound(w, r)
+		}
+	})}}
+
+	binanceFeed := binance.NewBinanceController()
+	binanceFeed.SetHTTPClient(client)
+	bybitFeed := bybit.NewBybitController()
+	bybitFeed.SetHTTPClient(client)
+
+	feeds := map[string]feed.PriceFeed{"binance": binanceFeed, "bybit": bybitFeed}
+	for name, priceFeed := range feeds {
+		rate, err := priceFeed.GetFundingRate("BTC/USDT:USDT")
+		if err != nil {
+			t.Fatalf("%s 获取资金费率失败: %v", name, err)
+		}
+		// 0.0001 即 0.01%
+		if math.Abs(rate-0.01) > 1e-9 {
+			t.Errorf("%s 资金费率应为百分比 0.01，实际 %v", name, rate)
+		}
+	}
+}
+
+// TestStoreNotifiesMonitoredPairs 测试设置了监听列表时，监控中的交易对和合成交易对的腿也会通知价格变化，其他交易对只保存价格
+func TestStoreNotifiesMonitoredPairs(t *testing.T) {
+	redisController := redis.NewLocalRedisController(&config.Config{})
+	redisController.SetWatchedPairs([]string{"BTC/USDT:USDT"})
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: "SOL/USDT:USDT", Direct: "alert_up", Price: 200})
+	redisController.SetLocalMonitorPair(model.PairMonitorData{Pair: "ETH/BTC", Direct: "long", Price: 0.05})
+
+	tests := []struct {
+		pair     string
+		expected bool
+	}{
+		{"BTC/USDT:USDT", true},   // 监听列表
+		{"SOL/USDT:USDT", true},   // 提醒监控
+		{"ETH/USDT:USDT", true},   // 合成交易对的分子
+		{"DOGE/USDT:USDT", false}, // 只保存价格
+	}
+	now := time.Now()
+	for _, tt := range tests {
+		pairData := &model.PairData{Pair: tt.pair, BidPrice: 1, AskPrice: 1.1}
+		if notify := feed.Store(redisController, pairData, now); notify != tt.expected {
+			t.Errorf("%s 是否通知应为 %v，实际 %v", tt.pair, tt.expected, notify)
+		}
+		if price := redisController.GetPairPrice(tt.pair); price.AskPrice != 1.1 {
+			t.Errorf("%s 的价格应该保存，实际 %+v", tt.pair, price)
+		}
+	}
+}
@@ -6,12 +6,17 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"monitor-trade/config"
 	"monitor-trade/controller/redis"
 	"monitor-trade/model"
 	"net/http"
+	"sync"
 	"time"
 )
 
+// SubmittedTimeout 监控提交后等待成交的最长时间
+const SubmittedTimeout = 15 * time.Minute
+
 type FreqtradeController struct {
 	BaseUrl         string
 	Username        string
@@ -20,11 +25,18 @@ type FreqtradeController struct {
 	RefreshToken    string
 	stopChan        chan struct{}
 	stopChanPair    chan struct{}
+	stopChanPaper   chan struct{}
 	httpClient      *http.Client
 	PositionStatus  model.PositionStatus
 	TradeStatus     []model.TradePosition
 	redisController *redis.RedisController
 	messageChan     chan string
+	paper           *PaperExecutor // 不为空时交易请求发送到模拟执行器，不再调用 Freqtrade
+	risk            config.RiskConfig
+	entryTimes      []time.Time // 最近成功提交的新开仓时间
+	configStake     float64     // Freqtrade 配置的每笔开仓金额，unlimited 时为0
+	stakeLoaded     bool        // configStake 是否已从 Freqtrade 获取
+	mutexRisk       sync.Mutex
 }
 
 func NewFreqtradeController(baseUrl, username, password string, redisController *redis.RedisController) *FreqtradeController {
@@ -37,6 +49,24 @@ func NewFreqtradeController(baseUrl, username, password string, redisController
 	}
 }
 
+// SetPaperExecutor 开启模拟交易，开仓、加仓、平仓和持仓查询都由模拟执行器处理
+func (fc *FreqtradeController) SetPaperExecutor(paper *PaperExecutor) {
+	fc.paper = paper
+}
+
+// IsPaper 是否为模拟交易模式
+func (fc *FreqtradeController) IsPaper() bool {
+	return fc.paper != nil
+}
+
+// PaperRealizedProfit 模拟交易的已实现盈亏
+func (fc *FreqtradeController) PaperRealizedProfit() float64 {
+	if fc.paper == nil {
+		return 0
+	}
+	return fc.paper.RealizedProfit()
+}
+
 // Stop 优雅停止所有定时器
 func (fc *FreqtradeController) Stop() {
 	log.Println("正在停止Freqtrade控制器...")
@@ -51,6 +81,11 @@ func (fc *FreqtradeController) Stop() {
 		fc.stopChanPair = nil
 	}
 
+	if fc.stopChanPaper != nil {
+		close(fc.stopChanPaper)
+		fc.stopChanPaper = nil
+	}
+
 	log.Println("Freqtrade控制器已停止")
 }
 
@@ -92,6 +127,7 @@ func (fc *FreqtradeController) pairRefresher() {
 			select {
 			case <-ticker.C:
 				go fc.setPairWhiteList()
+				go fc.CheckRedisPairStatus()
 			case <-fc.stopChanPair:
 				log.Println("交易对刷新器已停止")
 				return
@@ -126,6 +162,17 @@ func (fc *FreqtradeController) doRequest(method, url string, body io.Reader, use
 
 func (fc *FreqtradeController) Init(messageChan chan string) {
 	fc.messageChan = messageChan
+	if fc.paper != nil {
+		// 模拟交易不需要登录 Freqtrade，白名单取自 PAPER_WHITELIST
+		log.Println("模拟交易模式已开启，交易请求不会发送到 Freqtrade")
+		fc.stopChanPaper = make(chan struct{})
+		go fc.paper.Start(fc.stopChanPaper)
+		go fc.CheckRedisPairStatus()
+		go fc.setPairWhiteList()
+		go fc.pairRefresher()
+		return
+	}
+
 	url := fmt.Sprintf("%v/api/v1/token/login", fc.BaseUrl)
 	req, err := http.NewRequest("POST", url, nil)
 	if err != nil {
@@ -195,6 +242,9 @@ func (fc *FreqtradeController) refreshToken() {
 }
 
 func (fc *FreqtradeController) ForceBuy(payload model.ForceBuyPayload) error {
+	if fc.paper != nil {
+		return fc.paper.ForceBuy(payload)
+	}
 	url := fmt.Sprintf("%s/api/v1/forcebuy", fc.BaseUrl)
 
 	body, err := json.Marshal(payload)
@@ -212,6 +262,9 @@ func (fc *FreqtradeController) ForceBuy(payload model.ForceBuyPayload) error {
 }
 
 func (fc *FreqtradeController) ForceAdjustBuy(pair string, price float64, side string, stakeAmount float64, entryTag string) error {
+	if fc.paper != nil {
+		return fc.paper.ForceAdjustBuy(pair, price, side, stakeAmount, entryTag)
+	}
 	url := fmt.Sprintf("%s/api/v1/forcebuy", fc.BaseUrl)
 	payload := model.ForceAdjustBuyPayload{
 		Pair:        pair,
@@ -237,6 +290,9 @@ func (fc *FreqtradeController) ForceAdjustBuy(pair string, price float64, side s
 }
 
 func (fc *FreqtradeController) ForceSell(tradeId string, orderType string, amount string) error {
+	if fc.paper != nil {
+		return fc.paper.ForceSell(tradeId, orderType, amount)
+	}
 	url := fmt.Sprintf("%s/api/v1/forcesell", fc.BaseUrl)
 	payload := model.ForceSellPayload{
 		TradeId:   tradeId,
@@ -259,6 +315,10 @@ func (fc *FreqtradeController) ForceSell(tradeId string, orderType string, amoun
 }
 
 func (fc *FreqtradeController) getCount() error {
+	if fc.paper != nil {
+		fc.PositionStatus = fc.paper.Count()
+		return nil
+	}
 	url := fmt.Sprintf("%v/api/v1/count", fc.BaseUrl)
 	body, err := fc.doRequest("GET", url, nil, true)
 	if err != nil {
@@ -274,6 +334,10 @@ func (fc *FreqtradeController) getCount() error {
 }
 
 func (fc *FreqtradeController) getStatus() error {
+	if fc.paper != nil {
+		fc.TradeStatus = fc.paper.Status()
+		return nil
+	}
 	url := fmt.Sprintf("%s/api/v1/status", fc.BaseUrl)
 	body, err := fc.doRequest("GET", url, nil, true)
 	if err != nil {
@@ -317,34 +381,218 @@ func (fc *FreqtradeController) CheckRedisPairStatus() {
 		trade := tradeStatus[i]
 		if len(trade.Orders) >= 1 {
 			if !trade.Orders[0].IsOpen {
+				direct := "long"
+				directName := "做多"
 				if trade.IsShort {
-					// short 交易对
-					_, exits := fc.redisController.GetMonitorPair(trade.Pair, "short")
-					if exits {
-						log.Printf("交易对 %s 的做空仓位已经成交，删除 Redis 中的监控数据", trade.Pair)
-						fc.redisController.DeleteMonitorPair(trade.Pair, "short")
-						go func() {
-							// 发送做空成功消息
-							fc.messageChan <- fmt.Sprintf("✅ %s 做空仓位已成交，删除 Redis 中的监控数据", trade.Pair)
-						}()
-					}
-				} else {
-					// long 交易对
-					_, exits := fc.redisController.GetMonitorPair(trade.Pair, "long")
-					if exits {
-						log.Printf("交易对 %s 的做多仓位已经成交，删除 Redis 中的监控数据", trade.Pair)
-						fc.redisController.DeleteMonitorPair(trade.Pair, "long")
-						go func() {
-							// 发送做多成功消息
-							fc.messageChan <- fmt.Sprintf("✅ %s 做多仓位已成交，删除 Redis 中的监控数据", trade.Pair)
-						}()
+					direct = "short"
+					directName = "做空"
+				}
+				monitorData, exits := fc.redisController.GetMonitorPair(trade.Pair, direct)
+				if exits && len(monitorData.Ladder) > 0 {
+					fc.updateLadderStatus(trade, monitorData)
+				} else if exits && fc.completeMonitor(trade.Pair, direct) {
+					// 入场已成交，filled 为终态，删除监控数据
+					log.Printf("交易对 %s 的%s仓位已经成交(%s)，删除 Redis 中的监控数据", trade.Pair, directName, model.MonitorStateFilled)
+					if monitorData.Rearm {
+						fc.scheduleRearm(trade, monitorData)
 					}
+					go func() {
+						fc.messageChan <- fmt.Sprintf("✅ %s %s仓位已成交，删除 Redis 中的监控数据", trade.Pair, directName)
+					}()
+				}
+			}
+		}
+	}
+
+	fc.updateSyntheticMonitors(tradeStatus)
+	fc.expireSubmittedMonitors(tradeStatus)
+	fc.cleanTradeMonitors(tradeStatus)
+	if err == nil {
+		// 交易数据获取失败时无法判断关联交易是否已平仓
+		fc.processRearmTimers(tradeStatus, fc.redisController.Now())
+	}
+}
+
+// completeMonitor 将监控切换为 filled 后删除，返回是否由本次调用完成。
+// 多个状态检查同时运行时只有一个能切换成功，避免重复删除和重复通知
+func (fc *FreqtradeController) completeMonitor(pair, direct string) bool {
+	if !fc.redisController.TransitionMonitorState(pair, direct, model.MonitorStateFilled,
+		model.MonitorStateArmed, model.MonitorStateTriggered, model.MonitorStateSubmitted,
+		model.MonitorStateFailed, model.MonitorStateExpired) {
+		return false
+	}
+	fc.cancelOcoPeer(pair, direct)
+	fc.redisController.DeleteMonitorPair(pair, direct)
+	return true
+}
+
+// updateSyntheticMonitors 合成交易对监控的所有腿都已成交后删除监控数据
+func (fc *FreqtradeController) updateSyntheticMonitors(tradeStatus []model.TradePosition) {
+	for _, data := range fc.redisController.ListMonitorPairs() {
+		if data.State != model.MonitorStateSubmitted || !model.IsSyntheticPair(data.Pair) {
+			continue
+		}
+		filled := true
+		for _, leg := range data.SyntheticLegs() {
+			if !legFilled(tradeStatus, leg) {
+				filled = false
+				break
+			}
+		}
+		if !filled || !fc.completeMonitor(data.Pair, data.Direct) {
+			continue
+		}
+		log.Printf("合成交易对 %s 的%s腿已全部成交(%s)，删除 Redis 中的监控数据", data.Pair, data.Direct, model.MonitorStateFilled)
+		fc.sendMessage(fmt.Sprintf("✅ %s %s腿已全部成交，删除 Redis 中的监控数据", data.Pair, data.Direct))
+	}
+}
+
+// legFilled 合成交易对的腿是否已有入场成交的持仓
+func legFilled(tradeStatus []model.TradePosition, leg model.SyntheticLeg) bool {
+	for i := range tradeStatus {
+		trade := tradeStatus[i]
+		if trade.Pair != leg.Pair || trade.IsShort != (leg.Side == "short") {
+			continue
+		}
+		if len(trade.Orders) >= 1 && !trade.Orders[0].IsOpen {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanTradeMonitors 删除已经结束的关联交易监控（止盈/止损/加仓）：关联交易已平仓，或提交的订单已成交
+func (fc *FreqtradeController) cleanTradeMonitors(tradeStatus []model.TradePosition) {
+	openTrades := make(map[int]model.TradePosition, len(tradeStatus))
+	for i := range tradeStatus {
+		if tradeStatus[i].IsOpen {
+			openTrades[tradeStatus[i].TradeId] = tradeStatus[i]
+		}
+	}
+
+	for _, data := range fc.redisController.ListMonitorPairs() {
+		if !data.HasTrade() {
+			continue
+		}
+		trade, exists := openTrades[data.TradeId]
+		if !exists {
+			if fc.completeMonitor(data.Pair, data.Direct) {
+				log.Printf("交易 %d (%s) 已平仓，删除 %s 监控", data.TradeId, data.Pair, data.Direct)
+				fc.sendMessage(fmt.Sprintf("✅ %s 交易 %d 已平仓，删除 %s 监控", data.Pair, data.TradeId, data.Direct))
+			}
+			continue
+		}
+		if data.State == model.MonitorStateSubmitted && !trade.HasOpenOrders && fc.completeMonitor(data.Pair, data.Direct) {
+			log.Printf("交易 %d (%s) 的 %s 订单已成交(%s)，删除监控", data.TradeId, data.Pair, data.Direct, model.MonitorStateFilled)
+			fc.sendMessage(fmt.Sprintf("✅ %s %s 订单已成交，删除监控", data.Pair, data.Direct))
+		}
+	}
+}
+
+// updateLadderStatus 更新分批入场档位的成交状态，所有档位结束后删除监控数据
+func (fc *FreqtradeController) updateLadderStatus(trade model.TradePosition, data model.PairMonitorData) {
+	if !trade.HasOpenOrders {
+		for i := range data.Ladder {
+			if fc.redisController.TransitionRungState(trade.Pair, data.Direct, i, model.MonitorStateFilled, model.MonitorStateSubmitted) {
+				fc.sendMessage(fmt.Sprintf("✅ %s %s第%d档已成交，价格: %.6f，金额: %.2f",
+					trade.Pair, data.Direct, i+1, data.Ladder[i].Price, data.Ladder[i].StakeAmount))
+			}
+		}
+	}
+
+	data, exists := fc.redisController.GetMonitorPair(trade.Pair, data.Direct)
+	if !exists {
+		return
+	}
+	for i := range data.Ladder {
+		switch data.Ladder[i].State {
+		case "", model.MonitorStateArmed, model.MonitorStateTriggered, model.MonitorStateSubmitted:
+			return
+		}
+	}
+	if !fc.completeMonitor(trade.Pair, data.Direct) {
+		return
+	}
+	log.Printf("交易对 %s 的%s分批入场已全部结束，删除 Redis 中的监控数据", trade.Pair, data.Direct)
+	fc.sendMessage(fmt.Sprintf("✅ %s %s分批入场已全部结束，删除 Redis 中的监控数据", trade.Pair, data.Direct))
+}
+
+// expireSubmittedMonitors 已提交但超时仍没有对应持仓的监控标记为过期
+func (fc *FreqtradeController) expireSubmittedMonitors(tradeStatus []model.TradePosition) {
+	openPairs := make(map[string]bool, len(tradeStatus))
+	for i := range tradeStatus {
+		openPairs[tradeStatus[i].Pair] = true
+	}
+
+	deadline := fc.redisController.Now().Add(-SubmittedTimeout).Unix()
+	for _, data := range fc.redisController.ListMonitorPairs() {
+		if data.State != model.MonitorStateSubmitted || data.StateTime > deadline {
+			continue
+		}
+		open := openPairs[data.Pair]
+		for _, leg := range data.SyntheticLegs() {
+			open = open || openPairs[leg.Pair]
+		}
+		if open {
+			continue
+		}
+		if fc.redisController.TransitionMonitorState(data.Pair, data.Direct, model.MonitorStateExpired, model.MonitorStateSubmitted) {
+			fc.sendMessage(fmt.Sprintf("⌛ %s %s 监控提交后超时未成交，已标记为过期", data.Pair, data.Direct))
+		}
+	}
+}
+
+// HandleWebhookMessage 根据 Freqtrade webhook 推进监控状态
+func (fc *FreqtradeController) HandleWebhookMessage(msg model.WebhookMessage) {
+	if msg.Pair == nil {
+		return
+	}
+
+	switch msg.Type {
+	case "exit_fill":
+		// 平仓（或部分平仓）成交后由 webhook 处理器统一调用 CheckRedisPairStatus 清理已经结束的关联交易监控
+	case "exit_cancel":
+		if msg.TradeId == nil {
+			return
+		}
+		for _, data := range fc.redisController.ListMonitorPairs() {
+			if data.TradeId != *msg.TradeId {
+				continue
+			}
+			if fc.redisController.TransitionMonitorState(data.Pair, data.Direct, model.MonitorStateExpired, model.MonitorStateSubmitted) {
+				fc.sendMessage(fmt.Sprintf("⌛ %s %s 平仓订单已取消，监控已标记为过期", data.Pair, data.Direct))
+			}
+		}
+	case "entry_cancel":
+		direct := "long"
+		if msg.Direction != nil && *msg.Direction == "short" {
+			direct = "short"
+		}
+		if data, exists := fc.redisController.GetMonitorPair(*msg.Pair, direct); exists && len(data.Ladder) > 0 {
+			// 分批入场只将已提交的档位标记为过期，其余档位继续监听
+			for i := range data.Ladder {
+				if fc.redisController.TransitionRungState(*msg.Pair, direct, i, model.MonitorStateExpired, model.MonitorStateSubmitted) {
+					fc.sendMessage(fmt.Sprintf("⌛ %s %s第%d档入场订单已取消，档位已标记为过期", *msg.Pair, direct, i+1))
 				}
 			}
+			return
+		}
+		if fc.redisController.TransitionMonitorState(*msg.Pair, direct, model.MonitorStateExpired,
+			model.MonitorStateTriggered, model.MonitorStateSubmitted) {
+			fc.sendMessage(fmt.Sprintf("⌛ %s %s 入场订单已取消，监控已标记为过期", *msg.Pair, direct))
 		}
 	}
 }
 
+// sendMessage 非阻塞地发送通知消息
+func (fc *FreqtradeController) sendMessage(message string) {
+	select {
+	case fc.messageChan <- message:
+	default:
+		log.Printf("⚠️ 消息通道已满，跳过发送: %s", message)
+	}
+}
+
 // 检查是否可以强制买入
 func (fc *FreqtradeController) CheckForceBuy(pair string) bool {
 	err := fc.fetchTradeData()
@@ -364,8 +612,50 @@ func (fc *FreqtradeController) CheckForceBuy(pair string) bool {
 	return len(tradeStatus) < fc.PositionStatus.Max
 }
 
+// CheckForceAdjustBuy 刷新交易数据并查找交易对当前未平仓的交易，用于加仓前校验持仓
+func (fc *FreqtradeController) CheckForceAdjustBuy(pair string) (model.TradePosition, bool, error) {
+	if err := fc.fetchTradeData(); err != nil {
+		return model.TradePosition{}, false, err
+	}
+	trade, exists := fc.FindOpenTrade(pair)
+	return trade, exists, nil
+}
+
+// GetTradeStatus 返回当前交易状态，模拟交易模式下直接读取模拟执行器的最新数据
+func (fc *FreqtradeController) GetTradeStatus() []model.TradePosition {
+	if fc.paper != nil {
+		return fc.paper.Status()
+	}
+	return fc.TradeStatus
+}
+
+// GetOpenTrade 根据交易ID查找未平仓的交易
+func (fc *FreqtradeController) GetOpenTrade(tradeId int) (model.TradePosition, bool) {
+	tradeStatus := fc.GetTradeStatus()
+	for i := range tradeStatus {
+		if tradeStatus[i].TradeId == tradeId && tradeStatus[i].IsOpen {
+			return tradeStatus[i], true
+		}
+	}
+	return model.TradePosition{}, false
+}
+
+// FindOpenTrade 根据交易对查找未平仓的交易
+func (fc *FreqtradeController) FindOpenTrade(pair string) (model.TradePosition, bool) {
+	tradeStatus := fc.GetTradeStatus()
+	for i := range tradeStatus {
+		if tradeStatus[i].Pair == pair && tradeStatus[i].IsOpen {
+			return tradeStatus[i], true
+		}
+	}
+	return model.TradePosition{}, false
+}
+
 // GetWhitelist 获取交易对白名单
 func (fc *FreqtradeController) getWhitelist() ([]string, error) {
+	if fc.paper != nil {
+		return fc.paper.Whitelist(), nil
+	}
 	url := fmt.Sprintf("%s/api/v1/whitelist", fc.BaseUrl)
 	body, err := fc.doRequest("GET", url, nil, true)
 	if err != nil {
@@ -0,0 +1,512 @@
+package freqtrade
+
+import (
+	"fmt"
+	"log"
+	"monitor-trade/config"
+	"monitor-trade/controller/redis"
+	"monitor-trade/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// paperClosedLimit 保留的已平仓模拟交易数量
+const paperClosedLimit = 500
+
+// PaperExecutor 模拟交易执行器，用实时买卖价撮合订单，持仓和盈亏保存在Redis中。
+// 交易数据使用与 Freqtrade /status 相同的结构，其余逻辑无需区分实盘和模拟
+type PaperExecutor struct {
+	redisController *redis.RedisController
+	conf            config.PaperConfig
+	mutex           sync.Mutex
+	state           model.PaperState
+}
+
+func NewPaperExecutor(redisController *redis.RedisController, conf config.PaperConfig) *PaperExecutor {
+	state, err := redisController.LoadPaperState()
+	if err != nil {
+		log.Printf("加载模拟交易数据失败: %v", err)
+	}
+	if state.NextTradeId <= 0 {
+		state.NextTradeId = 1
+	}
+	return &PaperExecutor{
+		redisController: redisController,
+		conf:            conf,
+		state:           state,
+	}
+}
+
+// Whitelist 配置的监听交易对，为空时不限制
+func (p *PaperExecutor) Whitelist() []string {
+	var pairs []string
+	for _, pair := range strings.Split(p.conf.Whitelist, ",") {
+		if pair = strings.TrimSpace(pair); pair != "" {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// Start 每秒用最新价格撮合挂单
+func (p *PaperExecutor) Start(stop chan struct{}) {
+	log.Println("模拟交易撮合已启动")
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.Match(p.redisController.Now())
+		case <-stop:
+			log.Println("模拟交易撮合已停止")
+			return
+		}
+	}
+}
+
+// ForceBuy 模拟开仓
+func (p *PaperExecutor) ForceBuy(payload model.ForceBuyPayload) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, exists := p.openTrade(payload.Pair); exists {
+		return fmt.Errorf("%s 已有模拟持仓", payload.Pair)
+	}
+	if len(p.openTrades()) >= p.conf.MaxOpenTrades {
+		return fmt.Errorf("模拟持仓数量已达上限 %d", p.conf.MaxOpenTrades)
+	}
+
+	stakeAmount := payload.StakeAmount
+	if stakeAmount <= 0 {
+		stakeAmount = p.conf.StakeAmount
+	}
+	now := p.redisController.Now()
+	trade := model.TradePosition{
+		TradeId:       p.state.NextTradeId,
+		Pair:          payload.Pair,
+		IsOpen:        true,
+		IsShort:       payload.Side == "short",
+		Exchange:      "paper",
+		EnterTag:      payload.EntryTag,
+		Leverage:      1,
+		FeeOpen:       p.conf.Fee,
+		FeeClose:      p.conf.Fee,
+		OpenDate:      now.Format("2006-01-02 15:04:05"),
+		OpenTimestamp: now.UnixMilli(),
+		TradingMode:   "futures",
+	}
+	p.state.NextTradeId++
+
+	order, err := p.newEntryOrder(trade, payload.OrderType, payload.Price, stakeAmount, payload.EntryTag, now)
+	if err != nil {
+		return err
+	}
+	trade.Orders = append(trade.Orders, order)
+	trade.HasOpenOrders = true
+	p.state.Trades = append(p.state.Trades, trade)
+	log.Printf("模拟开仓 %s %s，交易ID: %d，价格: %.6f，金额: %.2f", payload.Pair, payload.Side, trade.TradeId, payload.Price, stakeAmount)
+
+	p.matchLocked(now)
+	p.save()
+	return nil
+}
+
+// ForceAdjustBuy 模拟加仓
+func (p *PaperExecutor) ForceAdjustBuy(pair string, price float64, side string, stakeAmount float64, entryTag string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	index, exists := p.openTrade(pair)
+	if !exists {
+		return fmt.Errorf("%s 没有模拟持仓", pair)
+	}
+	trade := &p.state.Trades[index]
+	if trade.IsShort != (side == "short") {
+		return fmt.Errorf("%s 模拟持仓方向与加仓方向不一致", pair)
+	}
+	if stakeAmount <= 0 {
+		stakeAmount = p.conf.StakeAmount
+	}
+
+	now := p.redisController.Now()
+	order, err := p.newEntryOrder(*trade, "limit", price, stakeAmount, entryTag, now)
+	if err != nil {
+		return err
+	}
+	trade.Orders = append(trade.Orders, order)
+	trade.HasOpenOrders = true
+	log.Printf("模拟加仓 %s %s，交易ID: %d，价格: %.6f，金额: %.2f", pair, side, trade.TradeId, price, stakeAmount)
+
+	p.matchLocked(now)
+	p.save()
+	return nil
+}
+
+// ForceSell 模拟平仓，amount 为空时全部平仓。平仓按当前买卖价立即成交
+func (p *PaperExecutor) ForceSell(tradeId string, orderType string, amount string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	id, err := strconv.Atoi(tradeId)
+	if err != nil {
+		return fmt.Errorf("无效的交易ID: %s", tradeId)
+	}
+	index := -1
+	for i := range p.state.Trades {
+		if p.state.Trades[i].TradeId == id && p.state.Trades[i].IsOpen {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("模拟交易 %s 不存在或已平仓", tradeId)
+	}
+	trade := &p.state.Trades[index]
+	now := p.redisController.Now()
+
+	// 入场订单还未成交时取消挂单
+	cancelOpenOrders(trade)
+	if trade.Amount <= 0 {
+		p.removeTrade(index)
+		p.save()
+		log.Printf("模拟交易 %d 入场订单未成交，已取消", id)
+		return nil
+	}
+
+	sellAmount := trade.Amount
+	if amount != "" {
+		sellAmount, err = strconv.ParseFloat(amount, 64)
+		if err != nil || sellAmount <= 0 {
+			return fmt.Errorf("无效的平仓数量: %s", amount)
+		}
+		sellAmount = min(sellAmount, trade.Amount)
+	}
+
+	pairData := p.redisController.GetPairPrice(trade.Pair)
+	price := pairData.BidPrice
+	if trade.IsShort {
+		price = pairData.AskPrice
+	}
+	if price <= 0 {
+		return fmt.Errorf("无法获取 %s 的最新价格", trade.Pair)
+	}
+
+	order := model.TradeOrder{
+		Pair:           trade.Pair,
+		OrderId:        fmt.Sprintf("paper-%d-%d", trade.TradeId, len(trade.Orders)+1),
+		Status:         "open",
+		Remaining:      sellAmount,
+		Amount:         sellAmount,
+		SafePrice:      price,
+		FtOrderSide:    exitSide(*trade),
+		OrderType:      orderType,
+		IsOpen:         true,
+		OrderTimestamp: now.UnixMilli(),
+		FtOrderTag:     "force_exit",
+	}
+	trade.Orders = append(trade.Orders, order)
+	p.fill(trade, len(trade.Orders)-1, price, now)
+	log.Printf("模拟平仓 %s，交易ID: %d，价格: %.6f，数量: %.6f", trade.Pair, trade.TradeId, price, sellAmount)
+
+	p.save()
+	return nil
+}
+
+// Status 返回未平仓的模拟交易，并按最新价格计算浮动盈亏
+func (p *PaperExecutor) Status() []model.TradePosition {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	trades := p.openTrades()
+	for i := range trades {
+		trade := &trades[i]
+		pairData := p.redisController.GetPairPrice(trade.Pair)
+		trade.CurrentRate = pairData.BidPrice
+		if trade.IsShort {
+			trade.CurrentRate = pairData.AskPrice
+		}
+		if trade.Amount <= 0 || trade.CurrentRate <= 0 {
+			continue
+		}
+		trade.ProfitAbs = tradeProfit(*trade, trade.CurrentRate, trade.Amount)
+		trade.ProfitRatio = trade.ProfitAbs / trade.StakeAmount
+		trade.ProfitPct = trade.ProfitRatio * 100
+		trade.TotalProfitAbs = trade.ProfitAbs + trade.RealizedProfit
+	}
+	return trades
+}
+
+// Count 返回模拟持仓数量，与 Freqtrade /count 一致
+func (p *PaperExecutor) Count() model.PositionStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	status := model.PositionStatus{Max: p.conf.MaxOpenTrades}
+	for _, trade := range p.openTrades() {
+		status.Current++
+		status.TotalStake += trade.StakeAmount
+	}
+	return status
+}
+
+// RealizedProfit 返回所有模拟交易的已实现盈亏（已扣除手续费）
+func (p *PaperExecutor) RealizedProfit() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	total := 0.0
+	for i := range p.state.Trades {
+		total += p.state.Trades[i].RealizedProfit
+	}
+	return total
+}
+
+// DailyProfit 返回 now 当日（UTC）平仓的模拟交易的已实现盈亏
+func (p *PaperExecutor) DailyProfit(now time.Time) float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	today := now.UTC().Format("2006-01-02")
+	total := 0.0
+	for i := range p.state.Trades {
+		trade := p.state.Trades[i]
+		if trade.IsOpen || trade.CloseTimestamp == nil {
+			continue
+		}
+		if time.UnixMilli(*trade.CloseTimestamp).UTC().Format("2006-01-02") == today {
+			total += trade.RealizedProfit
+		}
+	}
+	return total
+}
+
+// Match 用最新买卖价撮合所有挂单，超时未成交的入场挂单会被取消
+func (p *PaperExecutor) Match(now time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.matchLocked(now) {
+		p.save()
+	}
+}
+
+// matchLocked 撮合挂单，返回是否有变化，调用方需持有锁
+func (p *PaperExecutor) matchLocked(now time.Time) bool {
+	changed := false
+	for i := len(p.state.Trades) - 1; i >= 0; i-- {
+		trade := &p.state.Trades[i]
+		if !trade.IsOpen || !trade.HasOpenOrders {
+			continue
+		}
+
+		pairData := p.redisController.GetPairPrice(trade.Pair)
+		for j := range trade.Orders {
+			order := trade.Orders[j]
+			if !order.IsOpen {
+				continue
+			}
+			if now.UnixMilli()-order.OrderTimestamp > SubmittedTimeout.Milliseconds() {
+				trade.Orders[j].IsOpen = false
+				trade.Orders[j].Status = "canceled"
+				changed = true
+				log.Printf("模拟挂单超时取消: %s %s", trade.Pair, order.OrderId)
+				continue
+			}
+			if price, ok := entryFillPrice(*trade, order, pairData); ok {
+				p.fill(trade, j, price, now)
+				changed = true
+			}
+		}
+		trade.HasOpenOrders = hasOpenOrders(*trade)
+
+		// 入场订单全部取消且没有成交的交易直接删除，与 Freqtrade 的处理一致
+		if trade.Amount <= 0 && !trade.HasOpenOrders {
+			p.removeTrade(i)
+		}
+	}
+	return changed
+}
+
+// newEntryOrder 创建入场挂单
+func (p *PaperExecutor) newEntryOrder(trade model.TradePosition, orderType string, price, stakeAmount float64, tag string, now time.Time) (model.TradeOrder, error) {
+	if orderType == "market" {
+		pairData := p.redisController.GetPairPrice(trade.Pair)
+		price = pairData.AskPrice
+		if trade.IsShort {
+			price = pairData.BidPrice
+		}
+	}
+	if price <= 0 {
+		return model.TradeOrder{}, fmt.Errorf("无法获取 %s 的下单价格", trade.Pair)
+	}
+	amount := stakeAmount / price
+	return model.TradeOrder{
+		Pair:           trade.Pair,
+		OrderId:        fmt.Sprintf("paper-%d-%d", trade.TradeId, len(trade.Orders)+1),
+		Status:         "open",
+		Remaining:      amount,
+		Amount:         amount,
+		SafePrice:      price,
+		FtOrderSide:    entrySide(trade),
+		OrderType:      orderType,
+		IsOpen:         true,
+		OrderTimestamp: now.UnixMilli(),
+		FtOrderTag:     tag,
+	}, nil
+}
+
+// fill 以 price 成交第 index 个订单并更新仓位和盈亏
+func (p *PaperExecutor) fill(trade *model.TradePosition, index int, price float64, now time.Time) {
+	order := &trade.Orders[index]
+	amount := order.Amount
+	cost := amount * price
+	fee := cost * p.conf.Fee
+
+	order.IsOpen = false
+	order.Status = "closed"
+	order.Filled = amount
+	order.Remaining = 0
+	order.SafePrice = price
+	order.Cost = cost
+	order.OrderFilledTimestamp = now.UnixMilli()
+
+	if order.FtOrderSide == entrySide(*trade) {
+		if trade.Amount <= 0 {
+			trade.OpenFillDate = now.Format("2006-01-02 15:04:05")
+			trade.OpenFillTimestamp = now.UnixMilli()
+		}
+		trade.OpenRate = (trade.OpenRate*trade.Amount + price*amount) / (trade.Amount + amount)
+		trade.Amount += amount
+		trade.StakeAmount += cost
+		trade.FeeOpenCost += fee
+		trade.RealizedProfit -= fee
+	} else {
+		amount = min(amount, trade.Amount)
+		trade.RealizedProfit += tradeProfit(*trade, price, amount) - fee
+		trade.StakeAmount -= trade.OpenRate * amount
+		trade.Amount -= amount
+		if trade.Amount < 1e-12 {
+			closeRate := price
+			closeTimestamp := now.UnixMilli()
+			closeDate := now.Format("2006-01-02 15:04:05")
+			closeProfit := trade.RealizedProfit
+			exitReason := "force_exit"
+			trade.IsOpen = false
+			trade.Amount = 0
+			trade.StakeAmount = 0
+			trade.CloseRate = &closeRate
+			trade.CloseTimestamp = &closeTimestamp
+			trade.CloseDate = &closeDate
+			trade.CloseProfitAbs = &closeProfit
+			trade.ExitReason = &exitReason
+		}
+	}
+	trade.HasOpenOrders = hasOpenOrders(*trade)
+	log.Printf("模拟成交 %s %s，价格: %.6f，数量: %.6f，手续费: %.4f", trade.Pair, order.FtOrderSide, price, amount, fee)
+}
+
+// openTrade 查找交易对未平仓的模拟交易
+func (p *PaperExecutor) openTrade(pair string) (int, bool) {
+	for i := range p.state.Trades {
+		if p.state.Trades[i].Pair == pair && p.state.Trades[i].IsOpen {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// openTrades 返回未平仓模拟交易的副本
+func (p *PaperExecutor) openTrades() []model.TradePosition {
+	trades := []model.TradePosition{}
+	for i := range p.state.Trades {
+		if p.state.Trades[i].IsOpen {
+			trade := p.state.Trades[i]
+			trade.Orders = append([]model.TradeOrder(nil), trade.Orders...)
+			trades = append(trades, trade)
+		}
+	}
+	return trades
+}
+
+// removeTrade 删除第 index 个模拟交易
+func (p *PaperExecutor) removeTrade(index int) {
+	p.state.Trades = append(p.state.Trades[:index], p.state.Trades[index+1:]...)
+}
+
+// save 保存模拟交易数据，只保留最近的已平仓交易
+func (p *PaperExecutor) save() {
+	closed := 0
+	for i := len(p.state.Trades) - 1; i >= 0; i-- {
+		if p.state.Trades[i].IsOpen {
+			continue
+		}
+		closed++
+		if closed > paperClosedLimit {
+			p.removeTrade(i)
+		}
+	}
+	if err := p.redisController.SavePaperState(p.state); err != nil {
+		log.Printf("保存模拟交易数据失败: %v", err)
+	}
+}
+
+// entryFillPrice 判断入场挂单能否按当前买卖价成交：做多卖一价不高于限价，做空买一价不低于限价
+func entryFillPrice(trade model.TradePosition, order model.TradeOrder, pairData model.PairData) (float64, bool) {
+	if order.FtOrderSide != entrySide(trade) {
+		return 0, false
+	}
+	if order.OrderType == "market" {
+		if trade.IsShort {
+			return pairData.BidPrice, pairData.BidPrice > 0
+		}
+		return pairData.AskPrice, pairData.AskPrice > 0
+	}
+	if trade.IsShort {
+		return order.SafePrice, pairData.BidPrice > 0 && pairData.BidPrice >= order.SafePrice
+	}
+	return order.SafePrice, pairData.AskPrice > 0 && pairData.AskPrice <= order.SafePrice
+}
+
+// tradeProfit 按价格计算 amount 数量的盈亏（未扣手续费）
+func tradeProfit(trade model.TradePosition, price, amount float64) float64 {
+	if trade.IsShort {
+		return (trade.OpenRate - price) * amount
+	}
+	return (price - trade.OpenRate) * amount
+}
+
+// cancelOpenOrders 取消所有挂单
+func cancelOpenOrders(trade *model.TradePosition) {
+	for i := range trade.Orders {
+		if trade.Orders[i].IsOpen {
+			trade.Orders[i].IsOpen = false
+			trade.Orders[i].Status = "canceled"
+		}
+	}
+	trade.HasOpenOrders = false
+}
+
+func hasOpenOrders(trade model.TradePosition) bool {
+	for i := range trade.Orders {
+		if trade.Orders[i].IsOpen {
+			return true
+		}
+	}
+	return false
+}
+
+func entrySide(trade model.TradePosition) string {
+	if trade.IsShort {
+		return "sell"
+	}
+	return "buy"
+}
+
+func exitSide(trade model.TradePosition) string {
+	if trade.IsShort {
+		return "buy"
+	}
+	return "sell"
+}
@@ -0,0 +1,129 @@
+package freqtrade
+
+import (
+	"math"
+	"monitor-trade/config"
+	"monitor-trade/controller/redis"
+	"monitor-trade/model"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestPaperExecutor 创建测试用的模拟执行器，Redis 不可用时只记录日志
+func newTestPaperExecutor() (*PaperExecutor, *redis.RedisController) {
+	conf := &config.Config{
+		Redis: config.RedisConfig{
+			Addr:      "localhost:6379",
+			Password:  "",
+			DB:        0,
+			KeyExpire: 300,
+		},
+	}
+	redisController := redis.NewRedisController(conf)
+	paper := &PaperExecutor{
+		redisController: redisController,
+		conf:            config.PaperConfig{Enabled: true, Fee: 0.001, StakeAmount: 100, MaxOpenTrades: 1},
+		state:           model.PaperState{NextTradeId: 1},
+	}
+	return paper, redisController
+}
+
+// TestPaperExecutorLimitFill 测试限价单按实时买卖价撮合
+func TestPaperExecutorLimitFill(t *testing.T) {
+	paper, redisController := newTestPaperExecutor()
+	pair := "BTC/USDT:USDT"
+	redisController.UpdatePairPrice(pair, &model.PairData{Pair: pair, BidPrice: 60010, AskPrice: 60020})
+
+	err := paper.ForceBuy(model.ForceBuyPayload{Pair: pair, Price: 60000, Side: "long", OrderType: "limit", StakeAmount: 600})
+	if err != nil {
+		t.Fatalf("模拟开仓失败: %v", err)
+	}
+	trades := paper.Status()
+	if len(trades) != 1 || !trades[0].HasOpenOrders || trades[0].Amount != 0 {
+		t.Fatalf("卖价高于限价时不应成交: %+v", trades)
+	}
+
+	// 同一交易对不能重复开仓，且持仓数量已达上限
+	if err := paper.ForceBuy(model.ForceBuyPayload{Pair: "ETH/USDT:USDT", Price: 3000, Side: "long"}); err == nil {
+		t.Error("持仓数量达到上限时应该返回错误")
+	}
+
+	redisController.UpdatePairPrice(pair, &model.PairData{Pair: pair, BidPrice: 59990, AskPrice: 60000})
+	paper.Match(time.Now())
+	trades = paper.Status()
+	if trades[0].HasOpenOrders || trades[0].Orders[0].IsOpen {
+		t.Fatal("卖价到达限价后应该成交")
+	}
+	if math.Abs(trades[0].Amount-0.01) > 1e-9 || trades[0].OpenRate != 60000 {
+		t.Errorf("期望持仓 0.01 开仓价 60000，实际 %.6f %.2f", trades[0].Amount, trades[0].OpenRate)
+	}
+	if math.Abs(trades[0].RealizedProfit+0.6) > 1e-9 {
+		t.Errorf("期望开仓手续费 0.6，实际已实现盈亏 %.4f", trades[0].RealizedProfit)
+	}
+}
+
+// TestPaperExecutorForceSell 测试部分平仓和全部平仓的盈亏
+func TestPaperExecutorForceSell(t *testing.T) {
+	paper, redisController := newTestPaperExecutor()
+	pair := "ETH/USDT:USDT"
+	redisController.UpdatePairPrice(pair, &model.PairData{Pair: pair, BidPrice: 2999, AskPrice: 3000})
+
+	err := paper.ForceBuy(model.ForceBuyPayload{Pair: pair, Price: 2999, Side: "short", OrderType: "market", StakeAmount: 299.9})
+	if err != nil {
+		t.Fatalf("模拟开仓失败: %v", err)
+	}
+	trades := paper.Status()
+	if len(trades) != 1 || math.Abs(trades[0].Amount-0.1) > 1e-9 {
+		t.Fatalf("市价单应该立即成交: %+v", trades)
+	}
+	tradeId := trades[0].TradeId
+
+	redisController.UpdatePairPrice(pair, &model.PairData{Pair: pair, BidPrice: 2899, AskPrice: 2900})
+	if err := paper.ForceSell("1", "market", "0.05"); err != nil {
+		t.Fatalf("部分平仓失败: %v", err)
+	}
+	trades = paper.Status()
+	if len(trades) != 1 || math.Abs(trades[0].Amount-0.05) > 1e-9 {
+		t.Fatalf("部分平仓后应剩余 0.05: %+v", trades)
+	}
+
+	if err := paper.ForceSell("1", "market", ""); err != nil {
+		t.Fatalf("全部平仓失败: %v", err)
+	}
+	if len(paper.Status()) != 0 {
+		t.Error("全部平仓后不应有持仓")
+	}
+
+	// 做空从 2999 到 2900 盈利 9.9，扣除开仓 0.2999 和平仓 0.29 的手续费
+	expected := 9.9 - 0.2999 - 0.29
+	if math.Abs(paper.RealizedProfit()-expected) > 1e-6 {
+		t.Errorf("交易 %d 期望已实现盈亏 %.4f，实际 %.4f", tradeId, expected, paper.RealizedProfit())
+	}
+}
+
+// TestPaperInitWithoutLogin 测试模拟交易启动时不登录 Freqtrade，白名单取自配置
+func TestPaperInitWithoutLogin(t *testing.T) {
+	fake := &fakeFreqtrade{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	paper, redisController := newTestPaperExecutor()
+	paper.conf.Whitelist = "BTC/USDT:USDT, ETH/USDT:USDT,"
+	fc := NewFreqtradeController(server.URL, "testuser", "testpass", redisController)
+	fc.SetPaperExecutor(paper)
+	fc.Init(make(chan string, 100))
+	defer close(fc.stopChanPaper)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(redisController.GetWatchedPairs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	pairs := redisController.GetWatchedPairs()
+	if len(pairs) != 2 || pairs[0] != "BTC/USDT:USDT" || pairs[1] != "ETH/USDT:USDT" {
+		t.Errorf("白名单应取自配置，实际 %v", pairs)
+	}
+	if fake.logins != 0 {
+		t.Errorf("模拟交易不应登录 Freqtrade，实际登录 %d 次", fake.logins)
+	}
+}
@@ -0,0 +1,87 @@
+package freqtrade
+
+import (
+	"fmt"
+	"log"
+	"monitor-trade/model"
+	"time"
+)
+
+// scheduleRearm 入场成交后记录需要重新设置的监控，等待关联交易平仓
+func (fc *FreqtradeController) scheduleRearm(trade model.TradePosition, data model.PairMonitorData) {
+	timer := model.RearmTimer{Monitor: data, TradeId: trade.TradeId}
+	if err := fc.redisController.SetRearmTimer(timer); err != nil {
+		log.Printf("保存 %s %s 重新设置计时失败: %v", data.Pair, data.Direct, err)
+		return
+	}
+	log.Printf("交易 %d (%s) 平仓后将重新设置 %s 监控，冷却 %ds", trade.TradeId, data.Pair, data.Direct, data.RearmCooldown)
+}
+
+// processRearmTimers 推进所有等待重新设置的监控：关联交易平仓后开始冷却，冷却结束后重新布防
+func (fc *FreqtradeController) processRearmTimers(tradeStatus []model.TradePosition, now time.Time) {
+	timers, err := fc.redisController.ListRearmTimers()
+	if err != nil {
+		log.Printf("读取重新设置计时失败: %v", err)
+		return
+	}
+
+	openTrades := make(map[int]bool, len(tradeStatus))
+	for i := range tradeStatus {
+		if tradeStatus[i].IsOpen {
+			openTrades[tradeStatus[i].TradeId] = true
+		}
+	}
+
+	for _, timer := range timers {
+		data := timer.Monitor
+		next, changed, ready := advanceRearmTimer(timer, openTrades[timer.TradeId], now.Unix())
+		if ready {
+			fc.rearmMonitor(next)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if err := fc.redisController.SetRearmTimer(next); err != nil {
+			log.Printf("保存 %s %s 重新设置计时失败: %v", data.Pair, data.Direct, err)
+			continue
+		}
+		cooldown := time.Duration(data.RearmCooldown) * time.Second
+		fc.sendMessage(fmt.Sprintf("⏳ %s 交易 %d 已平仓，%s 后重新设置 %s 监控，限价: %.6f",
+			data.Pair, timer.TradeId, cooldown, data.Direct, data.Price))
+	}
+}
+
+// advanceRearmTimer 根据关联交易是否仍在持仓推进计时，返回更新后的计时、是否有变化以及是否可以重新设置
+func advanceRearmTimer(timer model.RearmTimer, tradeOpen bool, now int64) (model.RearmTimer, bool, bool) {
+	if timer.ExitTime == 0 {
+		if tradeOpen {
+			return timer, false, false
+		}
+		timer.ExitTime = now
+		return timer, true, timer.ReadyTime() <= now
+	}
+	return timer, false, timer.ReadyTime() <= now
+}
+
+// rearmMonitor 冷却结束后按原设置重新布防监控，期间已手动设置新监控时放弃
+func (fc *FreqtradeController) rearmMonitor(timer model.RearmTimer) {
+	data := timer.Monitor
+	if !fc.redisController.DeleteRearmTimer(data.Pair, data.Direct) {
+		// 其他实例已经处理
+		return
+	}
+	if _, exists := fc.redisController.GetMonitorPair(data.Pair, data.Direct); exists {
+		log.Printf("%s %s 已有新的监控，放弃重新设置", data.Pair, data.Direct)
+		return
+	}
+
+	data.TrailExtreme = 0
+	if err := fc.redisController.SetMonitorPair(data, data.Direct); err != nil {
+		log.Printf("重新设置 %s %s 监控失败: %v", data.Pair, data.Direct, err)
+		fc.sendMessage(fmt.Sprintf("❌ 重新设置 %s %s 监控失败: %v", data.Pair, data.Direct, err))
+		return
+	}
+	log.Printf("🔁 冷却结束，重新设置 %s %s 监控，限价 %.6f", data.Pair, data.Direct, data.Price)
+	fc.sendMessage(fmt.Sprintf("🔁 %s 冷却结束，已重新设置 %s 监控，限价: %.6f", data.Pair, data.Direct, data.Price))
+}
@@ -0,0 +1,71 @@
+package freqtrade
+
+import (
+	"monitor-trade/model"
+	"testing"
+	"time"
+)
+
+// TestAdvanceRearmTimer 测试平仓后的冷却计时
+func TestAdvanceRearmTimer(t *testing.T) {
+	timer := model.RearmTimer{
+		Monitor: model.PairMonitorData{Pair: "BTC/USDT:USDT", Direct: "long", Price: 60000, Rearm: true, RearmCooldown: 600},
+		TradeId: 7,
+	}
+
+	// 交易仍在持仓，不开始冷却
+	next, changed, ready := advanceRearmTimer(timer, true, 1000)
+	if changed || ready || next.ExitTime != 0 {
+		t.Fatalf("持仓期间不应开始冷却: %+v %v %v", next, changed, ready)
+	}
+
+	// 平仓后记录平仓时间并开始冷却
+	next, changed, ready = advanceRearmTimer(timer, false, 1000)
+	if !changed || ready || next.ExitTime != 1000 || next.ReadyTime() != 1600 {
+		t.Fatalf("平仓后应开始冷却: %+v %v %v", next, changed, ready)
+	}
+
+	// 冷却期间不重新设置
+	if _, changed, ready := advanceRearmTimer(next, false, 1599); changed || ready {
+		t.Error("冷却未结束时不应重新设置")
+	}
+	if _, _, ready := advanceRearmTimer(next, false, 1600); !ready {
+		t.Error("冷却结束后应重新设置")
+	}
+
+	// 没有冷却时间时平仓后立即重新设置
+	timer.Monitor.RearmCooldown = 0
+	if _, _, ready := advanceRearmTimer(timer, false, 1000); !ready {
+		t.Error("没有冷却时间时应立即重新设置")
+	}
+}
+
+// TestRearmLocalMode 测试不连接 Redis 时计时保存在本地，平仓冷却后重新设置监控
+func TestRearmLocalMode(t *testing.T) {
+	fc, redisController, _ := newTestLifecycle(t)
+	data := model.PairMonitorData{Pair: "BTC/USDT:USDT", Direct: "long", Price: 60000, Rearm: true}
+	fc.scheduleRearm(model.TradePosition{TradeId: 7}, data)
+	if _, exists, err := redisController.GetRearmTimer(data.Pair, data.Direct); err != nil || !exists {
+		t.Fatalf("本地模式应保存重新设置计时: %v %v", exists, err)
+	}
+
+	// 交易仍在持仓时不重新设置
+	fc.processRearmTimers([]model.TradePosition{{TradeId: 7, IsOpen: true}}, time.Unix(1000, 0))
+	if _, exists := redisController.GetMonitorPair(data.Pair, data.Direct); exists {
+		t.Error("持仓期间不应重新设置监控")
+	}
+
+	fc.processRearmTimers(nil, time.Unix(1000, 0))
+	if _, exists := redisController.GetMonitorPair(data.Pair, data.Direct); !exists {
+		t.Error("平仓且没有冷却时间时应重新设置监控")
+	}
+	if _, exists, _ := redisController.GetRearmTimer(data.Pair, data.Direct); exists {
+		t.Error("重新设置后应删除计时")
+	}
+	if redisController.DeleteRearmTimer(data.Pair, data.Direct) {
+		t.Error("计时已删除，不应再次删除成功")
+	}
+	if err := redisController.ResetCircuitBreaker(); err != nil {
+		t.Errorf("本地模式重置熔断不应失败: %v", err)
+	}
+}
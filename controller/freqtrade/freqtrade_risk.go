@@ -0,0 +1,197 @@
+package freqtrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"monitor-trade/config"
+	"monitor-trade/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetRiskConfig 设置开仓前的全局风控限制
+func (fc *FreqtradeController) SetRiskConfig(risk config.RiskConfig) {
+	fc.risk = risk
+}
+
+// RiskConfig 返回当前的风控限制
+func (fc *FreqtradeController) RiskConfig() config.RiskConfig {
+	return fc.risk
+}
+
+// CircuitBreaker 返回风控熔断状态
+func (fc *FreqtradeController) CircuitBreaker() (model.CircuitBreaker, bool, error) {
+	return fc.redisController.GetCircuitBreaker()
+}
+
+// ResetCircuitBreaker 手动重置风控熔断，同时清空开仓计数
+func (fc *FreqtradeController) ResetCircuitBreaker() error {
+	fc.mutexRisk.Lock()
+	fc.entryTimes = nil
+	fc.mutexRisk.Unlock()
+	return fc.redisController.ResetCircuitBreaker()
+}
+
+// circuitBreakerBlocked 检查是否处于风控熔断中，熔断中或读取状态失败时返回原因
+func (fc *FreqtradeController) circuitBreakerBlocked() (string, bool) {
+	breaker, tripped, err := fc.redisController.GetCircuitBreaker()
+	if err != nil {
+		return fmt.Sprintf("读取风控熔断状态失败: %v", err), true
+	}
+	if tripped {
+		return fmt.Sprintf("风控熔断中(%s)", breaker.Reason), true
+	}
+	return "", false
+}
+
+// checkRisk 在提交交易前检查这笔交易是否超出风控限制，任何一项超限都会触发熔断并停止所有自动开仓。
+// 调用前需要先刷新交易数据，熔断状态由 circuitBreakerBlocked 在获取交易锁前检查
+func (fc *FreqtradeController) checkRisk(trade model.ForceBuyPayload) error {
+	if reason := fc.riskViolation(trade, fc.redisController.Now()); reason != "" {
+		fc.tripCircuitBreaker(reason)
+		return fmt.Errorf("风控熔断: %s", reason)
+	}
+	return nil
+}
+
+// riskViolation 返回超出的风控限制，没有超限时返回空字符串
+func (fc *FreqtradeController) riskViolation(trade model.ForceBuyPayload, now time.Time) string {
+	risk := fc.risk
+
+	if risk.MaxTotalStake > 0 {
+		total := fc.PositionStatus.TotalStake + fc.entryStake(trade)
+		if total > risk.MaxTotalStake {
+			return fmt.Sprintf("总投入 %.2f 超过上限 %.2f", total, risk.MaxTotalStake)
+		}
+	}
+
+	// 以下限制只针对新开仓
+	if !trade.Adjust {
+		if risk.MaxEntriesPerHour > 0 {
+			if count := fc.countEntries(now.Add(-time.Hour)); count >= risk.MaxEntriesPerHour {
+				return fmt.Sprintf("最近一小时已开仓 %d 次，达到上限 %d", count, risk.MaxEntriesPerHour)
+			}
+		}
+		if risk.MaxSameDirection > 0 {
+			count := 0
+			for _, position := range fc.TradeStatus {
+				if position.IsOpen && position.IsShort == (trade.Side == "short") {
+					count++
+				}
+			}
+			if count >= risk.MaxSameDirection {
+				return fmt.Sprintf("%s 方向已有 %d 个持仓，达到上限 %d", trade.Side, count, risk.MaxSameDirection)
+			}
+		}
+	}
+
+	if risk.DailyLossLimit > 0 {
+		profit, err := fc.dailyProfit(now)
+		if err != nil {
+			log.Printf("获取当日盈亏失败: %v", err)
+		} else if profit <= -risk.DailyLossLimit {
+			return fmt.Sprintf("当日已实现亏损 %.2f 达到上限 %.2f", -profit, risk.DailyLossLimit)
+		}
+	}
+	return ""
+}
+
+// entryStake 交易请求计入总投入的金额。未指定金额时按 Freqtrade 实际使用的默认金额计算：
+// 模拟交易使用 PAPER_STAKE_AMOUNT，否则使用 Freqtrade 配置的 stake_amount，配置为 unlimited 时使用 RISK_DEFAULT_STAKE
+func (fc *FreqtradeController) entryStake(trade model.ForceBuyPayload) float64 {
+	if trade.StakeAmount > 0 {
+		return trade.StakeAmount
+	}
+	if fc.paper != nil {
+		return fc.paper.conf.StakeAmount
+	}
+	if stake := fc.loadConfigStake(); stake > 0 {
+		return stake
+	}
+	return fc.risk.DefaultStake
+}
+
+// loadConfigStake 获取 Freqtrade 配置的每笔开仓金额，成功后缓存，获取失败时下次重试
+func (fc *FreqtradeController) loadConfigStake() float64 {
+	fc.mutexRisk.Lock()
+	defer fc.mutexRisk.Unlock()
+	if fc.stakeLoaded {
+		return fc.configStake
+	}
+
+	url := fmt.Sprintf("%s/api/v1/show_config", fc.BaseUrl)
+	body, err := fc.doRequest("GET", url, nil, true)
+	if err != nil {
+		log.Printf("获取Freqtrade配置失败: %v", err)
+		return 0
+	}
+	var showConfig model.ShowConfigResponse
+	if err := json.Unmarshal(body, &showConfig); err != nil {
+		log.Printf("解析Freqtrade配置失败: %v", err)
+		return 0
+	}
+	// unlimited 时解析失败，金额为0
+	stake, _ := strconv.ParseFloat(strings.Trim(string(showConfig.StakeAmount), `"`), 64)
+	fc.configStake = stake
+	fc.stakeLoaded = true
+	return stake
+}
+
+// tripCircuitBreaker 触发风控熔断并发送 Telegram 告警
+func (fc *FreqtradeController) tripCircuitBreaker(reason string) {
+	breaker := model.CircuitBreaker{Reason: reason, Time: fc.redisController.Now().Unix()}
+	if err := fc.redisController.SetCircuitBreaker(breaker); err != nil {
+		log.Printf("保存风控熔断状态失败: %v", err)
+	}
+	log.Printf("🚨 风控熔断: %s", reason)
+	fc.sendMessage(fmt.Sprintf("🚨 风控熔断: %s\n已停止所有自动开仓，确认后使用 /risk reset 恢复", reason))
+}
+
+// recordEntry 记录一次成功提交的新开仓，用于每小时开仓次数限制
+func (fc *FreqtradeController) recordEntry(now time.Time) {
+	fc.mutexRisk.Lock()
+	defer fc.mutexRisk.Unlock()
+	fc.entryTimes = append(fc.entryTimes, now)
+}
+
+// countEntries 统计 since 之后的开仓次数，并清理更早的记录
+func (fc *FreqtradeController) countEntries(since time.Time) int {
+	fc.mutexRisk.Lock()
+	defer fc.mutexRisk.Unlock()
+
+	kept := fc.entryTimes[:0]
+	for _, t := range fc.entryTimes {
+		if t.After(since) {
+			kept = append(kept, t)
+		}
+	}
+	fc.entryTimes = kept
+	return len(kept)
+}
+
+// dailyProfit 获取当日（UTC）的已实现盈亏
+func (fc *FreqtradeController) dailyProfit(now time.Time) (float64, error) {
+	if fc.paper != nil {
+		return fc.paper.DailyProfit(now), nil
+	}
+
+	url := fmt.Sprintf("%s/api/v1/daily?timescale=1", fc.BaseUrl)
+	body, err := fc.doRequest("GET", url, nil, true)
+	if err != nil {
+		return 0, err
+	}
+
+	var daily model.DailyResponse
+	if err := json.Unmarshal(body, &daily); err != nil {
+		return 0, err
+	}
+	today := now.UTC().Format("2006-01-02")
+	for _, day := range daily.Data {
+		if day.Date == today {
+			return day.AbsProfit, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,90 @@
+package freqtrade
+
+import (
+	"monitor-trade/config"
+	"monitor-trade/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRiskViolation 测试各项风控限制
+func TestRiskViolation(t *testing.T) {
+	paper, _ := newTestPaperExecutor()
+	fc := &FreqtradeController{
+		paper: paper,
+		risk: config.RiskConfig{
+			MaxTotalStake:     1000,
+			MaxEntriesPerHour: 2,
+			MaxSameDirection:  1,
+			DailyLossLimit:    50,
+		},
+		PositionStatus: model.PositionStatus{TotalStake: 800},
+		TradeStatus:    []model.TradePosition{{Pair: "ETH/USDT:USDT", IsOpen: true, IsShort: true}},
+	}
+	now := time.Now()
+
+	if reason := fc.riskViolation(model.ForceBuyPayload{Pair: "BTC/USDT:USDT", Side: "long", StakeAmount: 100}, now); reason != "" {
+		t.Errorf("未超限时不应触发风控: %s", reason)
+	}
+	if reason := fc.riskViolation(model.ForceBuyPayload{Side: "long", StakeAmount: 300}, now); !strings.Contains(reason, "总投入") {
+		t.Errorf("期望总投入超限，实际: %s", reason)
+	}
+	if reason := fc.riskViolation(model.ForceBuyPayload{Side: "short"}, now); !strings.Contains(reason, "方向") {
+		t.Errorf("期望同方向持仓超限，实际: %s", reason)
+	}
+	// 加仓不受同方向持仓数量限制
+	if reason := fc.riskViolation(model.ForceBuyPayload{Side: "short", Adjust: true}, now); reason != "" {
+		t.Errorf("加仓不应受同方向持仓限制: %s", reason)
+	}
+
+	fc.recordEntry(now.Add(-2 * time.Hour))
+	fc.recordEntry(now.Add(-30 * time.Minute))
+	fc.recordEntry(now.Add(-10 * time.Minute))
+	if reason := fc.riskViolation(model.ForceBuyPayload{Side: "long"}, now); !strings.Contains(reason, "一小时") {
+		t.Errorf("期望每小时开仓次数超限，实际: %s", reason)
+	}
+	if count := fc.countEntries(now.Add(-time.Hour)); count != 2 {
+		t.Errorf("期望最近一小时开仓 2 次，实际 %d", count)
+	}
+
+	// 当日已实现亏损达到上限
+	closeTimestamp := now.UnixMilli()
+	paper.state.Trades = append(paper.state.Trades, model.TradePosition{RealizedProfit: -60, CloseTimestamp: &closeTimestamp})
+	if reason := fc.riskViolation(model.ForceBuyPayload{Side: "long", Adjust: true}, now); !strings.Contains(reason, "亏损") {
+		t.Errorf("期望当日亏损超限，实际: %s", reason)
+	}
+}
+
+// TestRiskTotalStakeDefaultStake 测试未指定金额的开仓按 Freqtrade 默认金额计入总投入
+func TestRiskTotalStakeDefaultStake(t *testing.T) {
+	fc, _, fake := newTestLifecycle(t)
+	fc.risk = config.RiskConfig{MaxTotalStake: 1000, DefaultStake: 50}
+	fc.PositionStatus = model.PositionStatus{TotalStake: 900}
+	now := time.Now()
+
+	// Freqtrade 配置了固定金额
+	fake.stake = `"200"`
+	if reason := fc.riskViolation(model.ForceBuyPayload{Side: "long"}, now); !strings.Contains(reason, "总投入 1100.00") {
+		t.Errorf("未指定金额时应按配置的 200 计入总投入，实际: %s", reason)
+	}
+	if reason := fc.riskViolation(model.ForceBuyPayload{Side: "long", StakeAmount: 80}, now); reason != "" {
+		t.Errorf("指定金额时按指定金额计算，不应超限: %s", reason)
+	}
+
+	// Freqtrade 配置为 unlimited 时使用 RISK_DEFAULT_STAKE
+	fc, _, fake = newTestLifecycle(t)
+	fc.risk = config.RiskConfig{MaxTotalStake: 1000, DefaultStake: 150}
+	fc.PositionStatus = model.PositionStatus{TotalStake: 900}
+	fake.stake = `"unlimited"`
+	if reason := fc.riskViolation(model.ForceBuyPayload{Side: "long"}, now); !strings.Contains(reason, "总投入 1050.00") {
+		t.Errorf("unlimited 时应按 RISK_DEFAULT_STAKE 计入总投入，实际: %s", reason)
+	}
+
+	// 模拟交易使用 PAPER_STAKE_AMOUNT
+	paper, _ := newTestPaperExecutor()
+	fc = &FreqtradeController{paper: paper, risk: config.RiskConfig{MaxTotalStake: 1000}, PositionStatus: model.PositionStatus{TotalStake: 950}}
+	if reason := fc.riskViolation(model.ForceBuyPayload{Side: "long"}, now); !strings.Contains(reason, "总投入") {
+		t.Errorf("模拟交易未指定金额时应按 PAPER_STAKE_AMOUNT 计入总投入，实际: %s", reason)
+	}
+}
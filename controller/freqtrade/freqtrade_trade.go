@@ -25,43 +25,166 @@ func (fc *FreqtradeController) HandleTradeChan(ctx context.Context, tradeChan ch
 
 // processTrade 处理单个交易请求
 func (fc *FreqtradeController) processTrade(trade model.ForceBuyPayload) {
-	log.Printf("收到%s交易请求: %s, 价格: %.6f", trade.Side, trade.Pair, trade.Price)
+	label := tradeLabel(trade)
+	log.Printf("收到%s交易请求: %s, 价格: %.6f", label, trade.Pair, trade.Price)
 
-	// 尝试获取Redis分布式锁
-	if !fc.redisController.AcquireTradeLock(trade.Pair) {
-		log.Printf("⏰ %s 交易锁获取失败，可能有其他交易正在进行，跳过执行", trade.Pair)
+	// 暂停前已进入通道的请求不再执行，恢复为 armed 等待恢复后重新触发
+	if fc.redisController.IsPaused() {
+		log.Printf("⏸ 自动交易已暂停，跳过 %s %s交易请求", trade.Pair, label)
+		fc.advanceMonitor(trade, model.MonitorStateArmed, model.MonitorStateTriggered)
 		return
 	}
 
-	log.Printf("🔒 获取 %s 交易锁成功，开始处理交易", trade.Pair)
+	// 熔断期间同样不执行，恢复为 armed 等待 /risk reset 后重新触发
+	if reason, blocked := fc.circuitBreakerBlocked(); blocked {
+		log.Printf("🚨 %s，跳过 %s %s交易请求", reason, trade.Pair, label)
+		fc.advanceMonitor(trade, model.MonitorStateArmed, model.MonitorStateTriggered)
+		return
+	}
+
+	// 尝试获取Redis分布式锁，分批入场的每个档位单独加锁
+	lockKey := trade.Pair
+	if trade.Rung > 0 {
+		lockKey = fmt.Sprintf("%s:%s:%d", trade.Pair, trade.Side, trade.Rung)
+	}
+	if !fc.redisController.AcquireTradeLock(lockKey) {
+		log.Printf("⏰ %s 交易锁获取失败，可能有其他交易正在进行，跳过执行", lockKey)
+		if trade.MonitorPair != "" {
+			// 合成交易对的其他腿可能已经提交，不能整体重新触发
+			fc.advanceMonitor(trade, model.MonitorStateFailed, model.MonitorStateTriggered)
+			fc.sendTradeResult(trade.Pair, trade.Price, label, fmt.Errorf("交易锁获取失败"))
+			return
+		}
+		// 恢复为 armed，等待下一次触发
+		fc.advanceMonitor(trade, model.MonitorStateArmed, model.MonitorStateTriggered)
+		return
+	}
+
+	log.Printf("🔒 获取 %s 交易锁成功，开始处理交易", lockKey)
 
 	// 校验仓位限制
-	if !fc.CheckForceBuy(trade.Pair) {
-		errMsg := fmt.Sprintf("交易对 %s 校验仓位不通过，跳过%s操作", trade.Pair, trade.Side)
+	if trade.Adjust {
+		position, exists, err := fc.CheckForceAdjustBuy(trade.Pair)
+		if err != nil {
+			// 获取交易数据失败，恢复为 armed 等待下一次触发
+			log.Printf("获取交易数据失败，暂缓%s: %v", label, err)
+			fc.advanceMonitor(trade, model.MonitorStateArmed, model.MonitorStateTriggered)
+			return
+		}
+		if !exists && trade.Rung > 0 {
+			// 开仓档位还未成交，恢复为 armed 等待下一次触发
+			log.Printf("交易对 %s 还没有持仓，暂缓%s", trade.Pair, label)
+			fc.advanceMonitor(trade, model.MonitorStateArmed, model.MonitorStateTriggered)
+			return
+		}
+		if !exists || (trade.TradeId > 0 && position.TradeId != trade.TradeId) {
+			log.Printf("❌ 交易对 %s 关联的持仓已不存在，跳过%s", trade.Pair, label)
+			fc.advanceMonitor(trade, model.MonitorStateFailed, model.MonitorStateTriggered)
+			fc.sendTradeResult(trade.Pair, trade.Price, label, fmt.Errorf("持仓已不存在"))
+			return
+		}
+		if position.IsShort != (trade.Side == "short") {
+			log.Printf("❌ 交易对 %s 持仓方向与%s不一致，跳过加仓", trade.Pair, label)
+			fc.advanceMonitor(trade, model.MonitorStateFailed, model.MonitorStateTriggered)
+			fc.sendTradeResult(trade.Pair, trade.Price, label, fmt.Errorf("持仓方向不一致"))
+			return
+		}
+		if position.HasOpenEntryOrder() {
+			// 之前的入场订单还未成交，恢复为 armed 等待成交后重新触发
+			log.Printf("交易对 %s 的入场订单还未成交，暂缓%s", trade.Pair, label)
+			fc.advanceMonitor(trade, model.MonitorStateArmed, model.MonitorStateTriggered)
+			return
+		}
+	} else if !fc.CheckForceBuy(trade.Pair) {
+		errMsg := fmt.Sprintf("交易对 %s 校验仓位不通过，跳过%s操作", trade.Pair, label)
 		log.Printf("❌ %s", errMsg)
-		fc.sendTradeResult(trade.Pair, trade.Price, trade.Side, fmt.Errorf("仓位校验失败"))
+		fc.advanceMonitor(trade, model.MonitorStateFailed, model.MonitorStateTriggered)
+		fc.sendTradeResult(trade.Pair, trade.Price, label, fmt.Errorf("仓位校验失败"))
+		return
+	}
+
+	// 全局风控检查，超限时触发熔断
+	if err := fc.checkRisk(trade); err != nil {
+		log.Printf("❌ %s %s未通过风控检查: %v", trade.Pair, label, err)
+		fc.advanceMonitor(trade, model.MonitorStateFailed, model.MonitorStateTriggered)
+		fc.sendTradeResult(trade.Pair, trade.Price, label, err)
 		return
 	}
 
 	// 执行交易
-	err := fc.ForceBuy(trade)
+	var err error
+	if trade.Adjust {
+		err = fc.ForceAdjustBuy(trade.Pair, trade.Price, trade.Side, trade.StakeAmount, trade.EntryTag)
+	} else {
+		err = fc.ForceBuy(trade)
+	}
 	if err != nil {
-		log.Printf("❌ %s %s操作失败: %v", trade.Pair, trade.Side, err)
+		log.Printf("❌ %s %s操作失败: %v", trade.Pair, label, err)
+		fc.advanceMonitor(trade, model.MonitorStateFailed, model.MonitorStateTriggered)
 	} else {
-		log.Printf("✅ %s %s操作提交成功，价格: %.6f", trade.Pair, trade.Side, trade.Price)
+		log.Printf("✅ %s %s操作提交成功，价格: %.6f", trade.Pair, label, trade.Price)
+		fc.advanceMonitor(trade, model.MonitorStateSubmitted, model.MonitorStateTriggered)
+		if !trade.Adjust {
+			fc.recordEntry(fc.redisController.Now())
+		}
 	}
 
 	// 异步发送结果通知
-	go fc.sendTradeResult(trade.Pair, trade.Price, trade.Side, err)
+	go fc.sendTradeResult(trade.Pair, trade.Price, label, err)
+}
+
+// advanceMonitor 推进触发该交易的监控（或分批入场档位）的状态，交易提交成功后取消 OCO 关联的另一方向监控
+func (fc *FreqtradeController) advanceMonitor(trade model.ForceBuyPayload, to string, from ...string) bool {
+	monitor := trade.Monitor
+	if monitor == "" {
+		monitor = trade.Side
+	}
+	pair := trade.Pair
+	if trade.MonitorPair != "" {
+		pair = trade.MonitorPair
+	}
+	var ok bool
+	if trade.Rung > 0 {
+		ok = fc.redisController.TransitionRungState(pair, monitor, trade.Rung-1, to, from...)
+	} else {
+		ok = fc.redisController.TransitionMonitorState(pair, monitor, to, from...)
+	}
+	if ok && to == model.MonitorStateSubmitted {
+		fc.cancelOcoPeer(pair, monitor)
+	}
+	return ok
+}
+
+// cancelOcoPeer 监控的交易被接受后取消 OCO 关联的另一方向监控并发送通知
+func (fc *FreqtradeController) cancelOcoPeer(pair, direct string) {
+	peer, ok := fc.redisController.CancelOcoPeer(pair, direct)
+	if !ok {
+		return
+	}
+	fc.sendMessage(fmt.Sprintf("🔗 %s %s监控的交易已提交，OCO 关联的 %s 监控已自动取消，限价: %.6f",
+		pair, direct, peer.Direct, peer.Price))
+}
+
+// tradeLabel 交易请求的描述，用于日志和通知
+func tradeLabel(trade model.ForceBuyPayload) string {
+	label := trade.Side
+	if trade.Rung > 0 {
+		label = fmt.Sprintf("%s 第%d档", label, trade.Rung)
+	}
+	if trade.Adjust {
+		label += " 加仓"
+	}
+	if trade.MonitorPair != "" {
+		label = fmt.Sprintf("%s (%s 腿)", label, trade.MonitorPair)
+	}
+	return label
 }
 
 // sendTradeResult 统一处理交易结果的消息发送
 func (fc *FreqtradeController) sendTradeResult(pair string, price float64, side string, err error) {
 	var resultMsg string
 	if err != nil {
-		resultMsg = fmt.Sprintf("❌ %s %s操作失败: %v", pair, side, err)
-		// 交易失败时删除Redis中的监控数据
-		fc.redisController.DeleteMonitorPair(pair, side)
+		resultMsg = fmt.Sprintf("❌ %s %s操作失败: %v，监控已标记为失败", pair, side, err)
 	} else {
 		resultMsg = fmt.Sprintf("✅ %s %s操作提交成功，价格: %.6f", pair, side, price)
 	}
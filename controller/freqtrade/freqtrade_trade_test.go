@@ -0,0 +1,357 @@
+package freqtrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"monitor-trade/config"
+	"monitor-trade/controller/redis"
+	"monitor-trade/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFreqtrade 模拟 Freqtrade 的登录、持仓查询、配置和 forcebuy 接口
+type fakeFreqtrade struct {
+	mutex      sync.Mutex
+	trades     []model.TradePosition
+	failBuy    bool   // forcebuy 返回错误
+	stake      string // show_config 返回的 stake_amount 原始 JSON，如 "100" 或 "unlimited"
+	buyPayload []model.ForceBuyPayload
+	logins     int // 登录接口的调用次数
+}
+
+func (f *fakeFreqtrade) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch r.URL.Path {
+	case "/api/v1/token/login":
+		f.logins++
+		w.Write([]byte(`{"access_token":"token","refresh_token":"refresh"}`))
+	case "/api/v1/status":
+		json.NewEncoder(w).Encode(f.trades)
+	case "/api/v1/count":
+		json.NewEncoder(w).Encode(model.PositionStatus{Current: len(f.trades), Max: 5})
+	case "/api/v1/show_config":
+		fmt.Fprintf(w, `{"stake_amount":%s}`, f.stake)
+	case "/api/v1/forcebuy":
+		if f.failBuy {
+			http.Error(w, `{"error":"insufficient funds"}`, http.StatusBadRequest)
+			return
+		}
+		var payload model.ForceBuyPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		f.buyPayload = append(f.buyPayload, payload)
+		w.Write([]byte(`{"status":"ok"}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeFreqtrade) setTrades(trades ...model.TradePosition) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.trades = trades
+}
+
+// newTestLifecycle 创建连接到模拟 Freqtrade 的控制器，监控只保存在本地
+func newTestLifecycle(t *testing.T) (*FreqtradeController, *redis.RedisController, *fakeFreqtrade) {
+	t.Helper()
+	fake := &fakeFreqtrade{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	redisController := redis.NewLocalRedisController(&config.Config{Redis: config.RedisConfig{KeyExpire: 300}})
+	fc := NewFreqtradeController(server.URL, "testuser", "testpass", redisController)
+	fc.messageChan = make(chan string, 100)
+	return fc, redisController, fake
+}
+
+// armAndTrigger 设置做空监控并模拟行情触发
+func armAndTrigger(t *testing.T, redisController *redis.RedisController, pair string) model.ForceBuyPayload {
+	t.Helper()
+	redisController.SetMonitorPair(model.PairMonitorData{Pair: pair, Price: 65000}, "short")
+	if !redisController.TransitionMonitorState(pair, "short", model.MonitorStateTriggered, model.MonitorStateArmed) {
+		t.Fatal("armed 状态的监控应能切换为 triggered")
+	}
+	if redisController.TransitionMonitorState(pair, "short", model.MonitorStateTriggered, model.MonitorStateArmed) {
+		t.Error("已触发的监控不应再次触发")
+	}
+	return model.ForceBuyPayload{Pair: pair, Price: 65000, OrderType: "limit", Side: "short"}
+}
+
+// monitorState 监控当前的状态，不存在时返回空
+func monitorState(redisController *redis.RedisController, pair, direct string) string {
+	data, exists := redisController.GetMonitorPair(pair, direct)
+	if !exists {
+		return ""
+	}
+	return data.State
+}
+
+// collectMessages 等待异步发送的通知后读取全部消息
+func collectMessages(fc *FreqtradeController) []string {
+	time.Sleep(50 * time.Millisecond)
+	var messages []string
+	for {
+		select {
+		case message := <-fc.messageChan:
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+}
+
+// countContaining 统计包含 substr 的消息数量
+func countContaining(messages []string, substr string) int {
+	count := 0
+	for _, message := range messages {
+		if strings.Contains(message, substr) {
+			count++
+		}
+	}
+	return count
+}
+
+// TestMonitorLifecycleFilled 测试 armed -> triggered -> submitted -> filled，成交后删除监控且只通知一次
+func TestMonitorLifecycleFilled(t *testing.T) {
+	fc, redisController, fake := newTestLifecycle(t)
+	pair := "BTC/USDT:USDT"
+	trade := armAndTrigger(t, redisController, pair)
+
+	fc.processTrade(trade)
+	if state := monitorState(redisController, pair, "short"); state != model.MonitorStateSubmitted {
+		t.Fatalf("提交成功后期望状态 submitted，实际 %s", state)
+	}
+	if len(fake.buyPayload) != 1 || fake.buyPayload[0].Side != "short" {
+		t.Errorf("应向 Freqtrade 提交一次做空请求: %+v", fake.buyPayload)
+	}
+
+	// 入场订单成交
+	fake.setTrades(model.TradePosition{TradeId: 1, Pair: pair, IsOpen: true, IsShort: true, Orders: []model.TradeOrder{{Pair: pair, IsOpen: false}}})
+	fc.CheckRedisPairStatus()
+	fc.CheckRedisPairStatus()
+	if _, exists := redisController.GetMonitorPair(pair, "short"); exists {
+		t.Error("成交后应删除监控")
+	}
+	if count := countContaining(collectMessages(fc), "仓位已成交"); count != 1 {
+		t.Errorf("成交通知应只发送一次，实际 %d 次", count)
+	}
+}
+
+// TestCompleteMonitorOnce 测试多个状态检查同时完成同一监控时，只有一个成功
+func TestCompleteMonitorOnce(t *testing.T) {
+	fc, redisController, _ := newTestLifecycle(t)
+	pair := "ETH/USDT:USDT"
+	redisController.SetMonitorPair(model.PairMonitorData{Pair: pair, Price: 3000}, "long")
+	redisController.TransitionMonitorState(pair, "long", model.MonitorStateSubmitted, model.MonitorStateArmed)
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	completed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if fc.completeMonitor(pair, "long") {
+				mutex.Lock()
+				completed++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if completed != 1 {
+		t.Errorf("同一监控只应完成一次，实际 %d 次", completed)
+	}
+	if _, exists := redisController.GetMonitorPair(pair, "long"); exists {
+		t.Error("完成后应删除监控")
+	}
+}
+
+// TestMonitorLifecycleFailed 测试提交失败时监控切换为 failed，不再触发
+func TestMonitorLifecycleFailed(t *testing.T) {
+	fc, redisController, fake := newTestLifecycle(t)
+	fake.failBuy = true
+	pair := "BTC/USDT:USDT"
+	trade := armAndTrigger(t, redisController, pair)
+
+	fc.processTrade(trade)
+	if state := monitorState(redisController, pair, "short"); state != model.MonitorStateFailed {
+		t.Fatalf("提交失败后期望状态 failed，实际 %s", state)
+	}
+	if redisController.TransitionMonitorState(pair, "short", model.MonitorStateTriggered, model.MonitorStateArmed) {
+		t.Error("failed 状态的监控不应再次触发")
+	}
+	if count := countContaining(collectMessages(fc), "监控已标记为失败"); count != 1 {
+		t.Errorf("提交失败应通知一次，实际 %d 次", count)
+	}
+}
+
+// TestMonitorLifecycleExpired 测试提交后入场订单被取消或超时未成交时切换为 expired
+func TestMonitorLifecycleExpired(t *testing.T) {
+	fc, redisController, _ := newTestLifecycle(t)
+	pair := "BTC/USDT:USDT"
+	fc.processTrade(armAndTrigger(t, redisController, pair))
+	if state := monitorState(redisController, pair, "short"); state != model.MonitorStateSubmitted {
+		t.Fatalf("提交成功后期望状态 submitted，实际 %s", state)
+	}
+
+	// 入场订单被取消
+	direction := "short"
+	fc.HandleWebhookMessage(model.WebhookMessage{Type: "entry_cancel", Pair: &pair, Direction: &direction})
+	if state := monitorState(redisController, pair, "short"); state != model.MonitorStateExpired {
+		t.Errorf("入场订单取消后期望状态 expired，实际 %s", state)
+	}
+
+	// 提交后超时仍没有持仓
+	now := time.Now().Unix()
+	stale := model.PairMonitorData{Pair: "ETH/USDT:USDT", Direct: "long", Price: 3000,
+		State: model.MonitorStateSubmitted, StateTime: now - int64(SubmittedTimeout.Seconds()) - 1}
+	fresh := model.PairMonitorData{Pair: "SOL/USDT:USDT", Direct: "long", Price: 150,
+		State: model.MonitorStateSubmitted, StateTime: now}
+	redisController.SetLocalMonitorPair(stale)
+	redisController.SetLocalMonitorPair(fresh)
+	fc.expireSubmittedMonitors(nil)
+	if state := monitorState(redisController, stale.Pair, "long"); state != model.MonitorStateExpired {
+		t.Errorf("超时未成交期望状态 expired，实际 %s", state)
+	}
+	if state := monitorState(redisController, fresh.Pair, "long"); state != model.MonitorStateSubmitted {
+		t.Errorf("未超时的监控应保持 submitted，实际 %s", state)
+	}
+}
+
+// TestProcessTradePaused 测试暂停前已进入通道的请求不提交，监控恢复为 armed，恢复后可以重新触发并提交
+func TestProcessTradePaused(t *testing.T) {
+	fc, redisController, fake := newTestLifecycle(t)
+	pair := "BTC/USDT:USDT"
+	trade := armAndTrigger(t, redisController, pair)
+
+	redisController.Pause("test", time.Now().Unix())
+	fc.processTrade(trade)
+	if len(fake.buyPayload) != 0 {
+		t.Errorf("暂停期间不应向 Freqtrade 提交: %+v", fake.buyPayload)
+	}
+	if state := monitorState(redisController, pair, "short"); state != model.MonitorStateArmed {
+		t.Fatalf("暂停期间的请求应恢复为 armed，实际 %s", state)
+	}
+
+	redisController.Resume()
+	if !redisController.TransitionMonitorState(pair, "short", model.MonitorStateTriggered, model.MonitorStateArmed) {
+		t.Fatal("恢复后监控应能重新触发")
+	}
+	fc.processTrade(trade)
+	if len(fake.buyPayload) != 1 {
+		t.Errorf("恢复后应向 Freqtrade 提交一次，实际 %d 次", len(fake.buyPayload))
+	}
+	if state := monitorState(redisController, pair, "short"); state != model.MonitorStateSubmitted {
+		t.Errorf("恢复后提交成功期望状态 submitted，实际 %s", state)
+	}
+}
+
+// TestProcessTradeCircuitBreaker 测试超出风控限制的交易标记为失败并触发熔断，熔断期间其他监控的请求恢复为 armed，重置后可以重新触发
+func TestProcessTradeCircuitBreaker(t *testing.T) {
+	fc, redisController, fake := newTestLifecycle(t)
+	fc.SetRiskConfig(config.RiskConfig{MaxEntriesPerHour: 1})
+
+	fc.processTrade(armAndTrigger(t, redisController, "BTC/USDT:USDT"))
+	if state := monitorState(redisController, "BTC/USDT:USDT", "short"); state != model.MonitorStateSubmitted {
+		t.Fatalf("第一笔开仓期望状态 submitted，实际 %s", state)
+	}
+
+	// 超出每小时开仓次数，这笔交易失败并触发熔断
+	fc.processTrade(armAndTrigger(t, redisController, "ETH/USDT:USDT"))
+	if state := monitorState(redisController, "ETH/USDT:USDT", "short"); state != model.MonitorStateFailed {
+		t.Errorf("超出风控限制的交易期望状态 failed，实际 %s", state)
+	}
+	if _, tripped, _ := fc.CircuitBreaker(); !tripped {
+		t.Fatal("超出风控限制后应触发熔断")
+	}
+
+	// 熔断期间的请求不是这笔交易超限，恢复为 armed
+	trade := armAndTrigger(t, redisController, "SOL/USDT:USDT")
+	fc.processTrade(trade)
+	if state := monitorState(redisController, "SOL/USDT:USDT", "short"); state != model.MonitorStateArmed {
+		t.Errorf("熔断期间的请求应恢复为 armed，实际 %s", state)
+	}
+	if len(fake.buyPayload) != 1 {
+		t.Errorf("熔断期间不应向 Freqtrade 提交，实际提交 %d 次", len(fake.buyPayload))
+	}
+
+	if err := fc.ResetCircuitBreaker(); err != nil {
+		t.Fatalf("重置熔断失败: %v", err)
+	}
+	if !redisController.TransitionMonitorState("SOL/USDT:USDT", "short", model.MonitorStateTriggered, model.MonitorStateArmed) {
+		t.Fatal("重置后监控应能重新触发")
+	}
+	fc.processTrade(trade)
+	if state := monitorState(redisController, "SOL/USDT:USDT", "short"); state != model.MonitorStateSubmitted {
+		t.Errorf("重置熔断后期望状态 submitted，实际 %s", state)
+	}
+}
+
+// TestProcessTradeCancelsOcoPeer 测试 OCO 一方的交易被拒绝时另一方保留，提交成功后才取消另一方
+func TestProcessTradeCancelsOcoPeer(t *testing.T) {
+	fc, redisController, fake := newTestLifecycle(t)
+	pair := "BTC/USDT:USDT"
+	redisController.SetMonitorPair(model.PairMonitorData{Pair: pair, Price: 66000, OCO: "long"}, "short")
+	redisController.SetMonitorPair(model.PairMonitorData{Pair: pair, Price: 64000, OCO: "short"}, "long")
+	trade := model.ForceBuyPayload{Pair: pair, Price: 66000, Side: "short", EntryTag: "force_entry", OrderType: "limit"}
+
+	// Freqtrade 拒绝这笔交易，做多监控保留
+	fake.failBuy = true
+	redisController.TransitionMonitorState(pair, "short", model.MonitorStateTriggered, model.MonitorStateArmed)
+	fc.processTrade(trade)
+	if state := monitorState(redisController, pair, "short"); state != model.MonitorStateFailed {
+		t.Fatalf("提交失败期望状态 failed，实际 %s", state)
+	}
+	if data, exists := redisController.GetMonitorPair(pair, "long"); !exists || !data.IsArmed() {
+		t.Fatalf("做空的交易被拒绝，OCO 关联的做多监控应保留，实际 %+v", data)
+	}
+
+	fake.failBuy = false
+	redisController.SetMonitorPair(model.PairMonitorData{Pair: pair, Price: 66000, OCO: "long"}, "short")
+	redisController.TransitionMonitorState(pair, "short", model.MonitorStateTriggered, model.MonitorStateArmed)
+	fc.processTrade(trade)
+	if state := monitorState(redisController, pair, "short"); state != model.MonitorStateSubmitted {
+		t.Fatalf("提交成功期望状态 submitted，实际 %s", state)
+	}
+	if _, exists := redisController.GetMonitorPair(pair, "long"); exists {
+		t.Error("做空的交易提交成功后应取消 OCO 关联的做多监控")
+	}
+}
+
+// TestProcessTradeAdjustWaitsForEntryFill 测试关联交易的入场订单未成交时加仓恢复为 armed，成交后才提交
+func TestProcessTradeAdjustWaitsForEntryFill(t *testing.T) {
+	fc, redisController, fake := newTestLifecycle(t)
+	pair := "BTC/USDT:USDT"
+	fake.setTrades(model.TradePosition{TradeId: 1, Pair: pair, IsOpen: true, IsShort: true,
+		Orders: []model.TradeOrder{{Pair: pair, FtOrderSide: "sell", IsOpen: true}}})
+	redisController.SetMonitorPair(model.PairMonitorData{Pair: pair, Price: 66000, TradeId: 1, Side: "short", StakeAmount: 50}, "adl")
+	trade := model.ForceBuyPayload{Pair: pair, Price: 66000, Side: "short", OrderType: "limit", StakeAmount: 50, Monitor: "adl", Adjust: true, TradeId: 1}
+
+	redisController.TransitionMonitorState(pair, "adl", model.MonitorStateTriggered, model.MonitorStateArmed)
+	fc.processTrade(trade)
+	if len(fake.buyPayload) != 0 {
+		t.Errorf("入场订单未成交时不应加仓: %+v", fake.buyPayload)
+	}
+	if state := monitorState(redisController, pair, "adl"); state != model.MonitorStateArmed {
+		t.Fatalf("入场订单未成交时加仓应恢复为 armed，实际 %s", state)
+	}
+
+	// 入场订单成交后重新触发
+	fake.setTrades(model.TradePosition{TradeId: 1, Pair: pair, IsOpen: true, IsShort: true,
+		Orders: []model.TradeOrder{{Pair: pair, FtOrderSide: "sell", IsOpen: false}}})
+	redisController.TransitionMonitorState(pair, "adl", model.MonitorStateTriggered, model.MonitorStateArmed)
+	fc.processTrade(trade)
+	if len(fake.buyPayload) != 1 {
+		t.Errorf("入场订单成交后应提交加仓，实际 %d 次", len(fake.buyPayload))
+	}
+	if state := monitorState(redisController, pair, "adl"); state != model.MonitorStateSubmitted {
+		t.Errorf("加仓提交后期望状态 submitted，实际 %s", state)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"monitor-trade/controller/redis"
 	"monitor-trade/model"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,11 @@ import (
 func ListenAndServe(hh *HttpHandler) {
 	r := gin.Default()
 	r.GET("/api/monitor", hh.ListMonitor)
+	r.GET("/api/candles", hh.ListCandles)
+	r.GET("/api/prices", hh.ListPrices)
+	r.GET("/api/pause", hh.GetPause)
+	r.POST("/api/pause", hh.Pause)
+	r.POST("/api/resume", hh.Resume)
 	r.POST("/api/webhook", hh.HandleWebhook) // 单一webhook端点
 
 	s := &http.Server{
@@ -77,10 +83,96 @@ func (h *HttpHandler) ListMonitor(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": pairMonitorDataList})
 }
 
+// ListCandles 获取本地聚合的K线
+func (h *HttpHandler) ListCandles(c *gin.Context) {
+	timeframe := c.DefaultQuery("tf", "1m")
+	if c.Query("pair") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pair is required"})
+		return
+	}
+	pair := h.MainController.Feed.ResolvePair(c.Query("pair"))
+	if _, ok := model.TimeframeDuration(timeframe); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported tf", "timeframes": model.CandleTimeframes})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.redisController.GetCandles(pair, timeframe)})
+}
+
+// ListPrices 获取各交易对价格数据的时效，按时长从旧到新排序。
+// watched=true 只返回监听列表中的交易对，stale=true 只返回已过期的交易对
+func (h *HttpHandler) ListPrices(c *gin.Context) {
+	watched := c.Query("watched") == "true"
+	staleOnly := c.Query("stale") == "true"
+	maxAge := time.Duration(h.MainController.Conf.PriceMaxAgeMs) * time.Millisecond
+	now := time.Now()
+
+	prices := []model.PairPriceAge{}
+	for _, data := range h.redisController.ListPairPrices() {
+		if watched && !h.redisController.IsWatchedPair(data.Pair) {
+			continue
+		}
+		stale := data.IsStale(now, maxAge)
+		if staleOnly && !stale {
+			continue
+		}
+		price := model.PairPriceAge{
+			Pair:        data.Pair,
+			BidPrice:    data.BidPrice,
+			AskPrice:    data.AskPrice,
+			EventTime:   data.EventTime,
+			ReceiveTime: data.ReceiveTime,
+			AgeMs:       data.Age(now).Milliseconds(),
+			Stale:       stale,
+		}
+		if data.EventTime > 0 && data.ReceiveTime > 0 {
+			price.LatencyMs = data.ReceiveTime - data.EventTime
+		}
+		prices = append(prices, price)
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].AgeMs > prices[j].AgeMs
+	})
+
+	c.JSON(http.StatusOK, gin.H{"data": prices, "max_age_ms": h.MainController.Conf.PriceMaxAgeMs})
+}
+
+// GetPause 获取自动交易暂停状态
+func (h *HttpHandler) GetPause(c *gin.Context) {
+	state, paused := h.redisController.PauseState()
+	c.JSON(http.StatusOK, gin.H{"paused": paused, "data": state})
+}
+
+// Pause 暂停自动交易，价格照常更新但不提交入场交易
+func (h *HttpHandler) Pause(c *gin.Context) {
+	if err := h.redisController.Pause("http", time.Now().Unix()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	log.Println("⏸ 自动交易已通过 HTTP 暂停")
+	c.JSON(http.StatusOK, gin.H{"paused": true})
+}
+
+// Resume 恢复自动交易
+func (h *HttpHandler) Resume(c *gin.Context) {
+	if err := h.redisController.Resume(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	log.Println("▶️ 自动交易已通过 HTTP 恢复")
+	c.JSON(http.StatusOK, gin.H{"paused": false})
+}
+
 // HandleWebhook 处理Freqtrade webhook消息
 func (h *HttpHandler) HandleWebhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
 	var rawData map[string]interface{}
-	if err := c.ShouldBindJSON(&rawData); err != nil {
+	if err := json.Unmarshal(body, &rawData); err != nil {
 		log.Printf("解析webhook数据失败: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
 		return
@@ -90,6 +182,14 @@ func (h *HttpHandler) HandleWebhook(c *gin.Context) {
 	for s := range rawData {
 		log.Println(rawData[s])
 	}
+
+	var message model.WebhookMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		log.Printf("解析webhook消息失败: %v", err)
+	} else {
+		message.RawData = rawData
+		h.fc.HandleWebhookMessage(message)
+	}
 	go h.fc.CheckRedisPairStatus()
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
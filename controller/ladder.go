@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+)
+
+// handleLadder 处理分批入场监控：第一档通过 forcebuy 开仓，后续档位对同一仓位加仓
+func (c *MainController) handleLadder(data model.PairMonitorData, pairData *model.PairData, lastPrice float64) {
+	tc := &TriggerContext{
+		PairData:  pairData,
+		LastPrice: lastPrice,
+		FundingRate: func() (float64, error) {
+			return c.fundingRate(pairData.Pair)
+		},
+	}
+
+	index, err := NextLadderRung(data, tc, c.Conf.FundingRate)
+	if err != nil {
+		log.Printf("交易对 %s %s分批条件评估失败: %v", pairData.Pair, data.Direct, err)
+		c.TgController.SendMessage(fmt.Sprintf("❌ %s %s分批操作失败: %v", pairData.Pair, data.Direct, err))
+		return
+	}
+	if index < 0 || c.RedisController.OcoPeerPending(data) {
+		return
+	}
+	// 之前档位的订单还未成交时档位保持 armed，成交后再加仓
+	if LadderEntryPending(data, index) {
+		return
+	}
+	// 同一档位只允许触发一次
+	if !c.RedisController.TransitionRungState(data.Pair, data.Direct, index, model.MonitorStateTriggered, model.MonitorStateArmed) {
+		return
+	}
+
+	rung := data.Ladder[index]
+	payload := model.ForceBuyPayload{
+		Pair:        pairData.Pair,
+		Price:       pairData.BidPrice,
+		Side:        data.Direct,
+		EntryTag:    "force_entry",
+		OrderType:   "limit",
+		StakeAmount: rung.StakeAmount,
+		Rung:        index + 1,
+		Adjust:      LadderRungIsAdjust(data, index),
+	}
+	if data.Direct == tg.ShortDirect {
+		payload.Price = pairData.AskPrice
+	}
+	if payload.Adjust {
+		payload.EntryTag = c.Conf.BotAdjustEntryTag
+	}
+
+	log.Printf("时间戳 %s 交易对 %s %s第%d档满足条件，档位价格 %.6f，金额 %.2f，加仓: %v",
+		pairData.Timestamp, pairData.Pair, data.Direct, index+1, rung.Price, rung.StakeAmount, payload.Adjust)
+
+	c.submitTrade(payload)
+}
@@ -0,0 +1,136 @@
+package redis
+
+import (
+	"monitor-trade/model"
+	"time"
+)
+
+// CandleHistoryLimit 每个交易对每个周期保留的K线数量
+const CandleHistoryLimit = 500
+
+// UpdateCandles 用一次价格推送更新交易对所有周期的K线
+func (r *RedisController) UpdateCandles(pair string, pairData *model.PairData, ts time.Time) {
+	mid := (pairData.BidPrice + pairData.AskPrice) / 2
+	if mid <= 0 {
+		return
+	}
+
+	r.mutexCandles.Lock()
+	defer r.mutexCandles.Unlock()
+
+	series, exists := r.Candles[pair]
+	if !exists {
+		series = make(map[string][]model.Candle, len(model.CandleTimeframes))
+		r.Candles[pair] = series
+	}
+
+	for _, timeframe := range model.CandleTimeframes {
+		duration, _ := model.TimeframeDuration(timeframe)
+		openTime := ts.Truncate(duration).UnixMilli()
+		series[timeframe] = appendTick(series[timeframe], openTime, mid, pairData)
+	}
+}
+
+// appendTick 将推送合并到最后一根K线，进入新周期时收盘并开启新K线
+func appendTick(candles []model.Candle, openTime int64, mid float64, pairData *model.PairData) []model.Candle {
+	if n := len(candles); n > 0 {
+		last := &candles[n-1]
+		if openTime < last.OpenTime {
+			// 乱序到达的旧推送直接忽略
+			return candles
+		}
+		if openTime == last.OpenTime {
+			last.High = max(last.High, mid)
+			last.Low = min(last.Low, mid)
+			last.Close = mid
+			last.BidLow = min(last.BidLow, pairData.BidPrice)
+			last.BidClose = pairData.BidPrice
+			last.AskHigh = max(last.AskHigh, pairData.AskPrice)
+			last.AskClose = pairData.AskPrice
+			last.Ticks++
+			return candles
+		}
+		last.Closed = true
+	}
+
+	candles = append(candles, model.Candle{
+		OpenTime: openTime,
+		Open:     mid,
+		High:     mid,
+		Low:      mid,
+		Close:    mid,
+		BidLow:   pairData.BidPrice,
+		BidClose: pairData.BidPrice,
+		AskHigh:  pairData.AskPrice,
+		AskClose: pairData.AskPrice,
+		Ticks:    1,
+	})
+	if len(candles) > CandleHistoryLimit {
+		candles = append(candles[:0:0], candles[len(candles)-CandleHistoryLimit:]...)
+	}
+	return candles
+}
+
+// GetCandles 获取交易对指定周期的K线副本，最后一根可能尚未收盘
+func (r *RedisController) GetCandles(pair, timeframe string) []model.Candle {
+	r.mutexCandles.RLock()
+	defer r.mutexCandles.RUnlock()
+
+	candles := r.Candles[pair][timeframe]
+	result := make([]model.Candle, len(candles))
+	copy(result, candles)
+	return result
+}
+
+// GetLastClosedCandle 获取交易对指定周期最近一根已收盘的K线
+func (r *RedisController) GetLastClosedCandle(pair, timeframe string) (model.Candle, bool) {
+	r.mutexCandles.RLock()
+	defer r.mutexCandles.RUnlock()
+
+	candles := r.Candles[pair][timeframe]
+	for i := len(candles) - 1; i >= 0; i-- {
+		if candles[i].Closed {
+			return candles[i], true
+		}
+	}
+	return model.Candle{}, false
+}
+
+// SeedCandles 用历史K线补齐本地K线，只补充早于本地第一根K线的已收盘K线
+func (r *RedisController) SeedCandles(pair, timeframe string, history []model.Candle) {
+	r.mutexCandles.Lock()
+	defer r.mutexCandles.Unlock()
+
+	series, exists := r.Candles[pair]
+	if !exists {
+		series = make(map[string][]model.Candle, len(model.CandleTimeframes))
+		r.Candles[pair] = series
+	}
+
+	local := series[timeframe]
+	var merged []model.Candle
+	for _, candle := range history {
+		if !candle.Closed || (len(local) > 0 && candle.OpenTime >= local[0].OpenTime) {
+			continue
+		}
+		merged = append(merged, candle)
+	}
+	merged = append(merged, local...)
+	if len(merged) > CandleHistoryLimit {
+		merged = merged[len(merged)-CandleHistoryLimit:]
+	}
+	series[timeframe] = merged
+}
+
+// GetATR 用本地K线计算交易对指定周期的 ATR，已收盘K线不足时返回 false
+func (r *RedisController) GetATR(pair, timeframe string) (float64, bool) {
+	r.mutexCandles.RLock()
+	defer r.mutexCandles.RUnlock()
+
+	candles := r.Candles[pair][timeframe]
+	// Wilder 平滑只需要最近的一段K线
+	if n := len(candles); n > model.ATRPeriod*3 {
+		candles = candles[n-model.ATRPeriod*3:]
+	}
+	return model.ATR(candles, model.ATRPeriod)
+}
@@ -18,10 +18,22 @@ type RedisController struct {
 	conf              *config.Config
 	MonitorPairs      map[string]model.PairMonitorData
 	PairPrices        map[string]*model.PairData
-	WatchedPairs      []string     // 需要监听的交易对
-	mutexPairPrices   sync.RWMutex // 保护 PairPrices 的读写锁
-	mutexWatchedPairs sync.RWMutex // 保护 WatchedPairs 的读写锁
-	mutexMonitorPairs sync.RWMutex // 保护 MonitorPairs 的读写锁
+	WatchedPairs      []string                             // 需要监听的交易对
+	ConfirmProgress   map[string]model.ConfirmProgress     // 监控确认窗口进度，仅保存在本地
+	Candles           map[string]map[string][]model.Candle // 本地聚合的K线: pair -> timeframe -> candles
+	pause             *model.PauseState                    // 自动交易暂停状态，nil 表示未暂停
+	pairsChanged      chan struct{}                        // 监听列表或监控的交易对变化时通知，用于更新行情订阅
+	rearmTimers       map[string]model.RearmTimer          // 本地模式等待重新设置的监控，连接Redis时不使用
+	breaker           *model.CircuitBreaker                // 本地模式的风控熔断状态，nil 表示未熔断，连接Redis时不使用
+	clock             func() time.Time                     // 监控状态时间等使用的当前时间，为空时使用系统时间；回放时为录制数据的时间
+	mutexPairPrices   sync.RWMutex                         // 保护 PairPrices 的读写锁
+	mutexWatchedPairs sync.RWMutex                         // 保护 WatchedPairs 的读写锁
+	mutexMonitorPairs sync.RWMutex                         // 保护 MonitorPairs 的读写锁
+	mutexProgress     sync.RWMutex                         // 保护 ConfirmProgress 的读写锁
+	mutexCandles      sync.RWMutex                         // 保护 Candles 的读写锁
+	mutexPause        sync.RWMutex                         // 保护 pause 的读写锁
+	mutexRearm        sync.Mutex                           // 保护 rearmTimers
+	mutexBreaker      sync.Mutex                           // 保护 breaker
 }
 
 func NewRedisController(conf *config.Config) *RedisController {
@@ -30,20 +42,51 @@ func NewRedisController(conf *config.Config) *RedisController {
 		Password: conf.Redis.Password,
 		DB:       conf.Redis.DB,
 	})
+	return newRedisController(conf, rdb)
+}
+
+// NewLocalRedisController 创建不连接Redis的控制器，数据只保存在本地，用于回测
+func NewLocalRedisController(conf *config.Config) *RedisController {
+	return newRedisController(conf, nil)
+}
+
+// SetClock 设置当前时间的来源，回放录制文件时按录制时间计算监控状态时间、冷却和超时
+func (r *RedisController) SetClock(clock func() time.Time) {
+	r.clock = clock
+}
 
+// Now 当前时间，设置了时钟时使用时钟的时间
+func (r *RedisController) Now() time.Time {
+	if r.clock != nil {
+		return r.clock()
+	}
+	return time.Now()
+}
+
+func newRedisController(conf *config.Config, rdb *redis.Client) *RedisController {
 	return &RedisController{
 		Client:            rdb,
 		conf:              conf,
 		MonitorPairs:      make(map[string]model.PairMonitorData, 1000),
 		PairPrices:        make(map[string]*model.PairData, 1000),
+		ConfirmProgress:   make(map[string]model.ConfirmProgress, 1000),
+		Candles:           make(map[string]map[string][]model.Candle, 1000),
+		pairsChanged:      make(chan struct{}, 1),
+		rearmTimers:       make(map[string]model.RearmTimer),
 		mutexWatchedPairs: sync.RWMutex{},
 		mutexPairPrices:   sync.RWMutex{},
 		mutexMonitorPairs: sync.RWMutex{},
+		mutexProgress:     sync.RWMutex{},
+		mutexCandles:      sync.RWMutex{},
 	}
 }
 
 // AcquireTradeLock 获取交易锁
 func (r *RedisController) AcquireTradeLock(pair string) bool {
+	if r.Client == nil {
+		// 本地模式只有一个进程，不需要分布式锁
+		return true
+	}
 	ctx := context.Background()
 	key := TradeKey + ":" + pair
 
@@ -58,6 +101,9 @@ func (r *RedisController) AcquireTradeLock(pair string) bool {
 
 // ReleaseTradeLock 释放交易锁
 func (r *RedisController) ReleaseTradeLock(pair string) {
+	if r.Client == nil {
+		return
+	}
 	ctx := context.Background()
 	key := TradeKey + ":" + pair
 	r.Client.Del(ctx, key)
@@ -3,11 +3,14 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"monitor-trade/model"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 // ===== 本地 MonitorPairs 操作（主要数据源） =====
@@ -22,15 +25,20 @@ func (r *RedisController) GetMonitorPair(pair, direct string) (model.PairMonitor
 	return data, exists
 }
 
-// SetMonitorPair 设置本地监控数据并同步到Redis
+// SetMonitorPair 设置本地监控数据并同步到Redis，监控状态重置为 armed
 func (r *RedisController) SetMonitorPair(data model.PairMonitorData, direct string) error {
 	// 先更新本地数据
 	r.mutexMonitorPairs.Lock()
 	localKey := fmt.Sprintf("%s:%s", data.Pair, direct)
 	data.Direct = direct
-	data.Timestamp = time.Now().Format("2006-01-02 15:04:05")
+	data.Timestamp = r.Now().Format("2006-01-02 15:04:05")
+	// 新设置的监控总是重新布防
+	data.State = model.MonitorStateArmed
+	data.StateTime = r.Now().Unix()
 	r.MonitorPairs[localKey] = data
 	r.mutexMonitorPairs.Unlock()
+	r.SetConfirmProgress(data.Pair, direct, model.ConfirmProgress{})
+	r.notifyPairsChanged()
 
 	// 同步到Redis
 	if err := r.SetPairDataToRedis(data, direct); err != nil {
@@ -43,6 +51,14 @@ func (r *RedisController) SetMonitorPair(data model.PairMonitorData, direct stri
 	return nil
 }
 
+// SetLocalMonitorPair 只写入本地监控数据，保留传入的状态和时间，不同步Redis，用于回测
+func (r *RedisController) SetLocalMonitorPair(data model.PairMonitorData) {
+	r.mutexMonitorPairs.Lock()
+	r.MonitorPairs[fmt.Sprintf("%s:%s", data.Pair, data.Direct)] = data
+	r.mutexMonitorPairs.Unlock()
+	r.SetConfirmProgress(data.Pair, data.Direct, model.ConfirmProgress{})
+}
+
 // DeleteMonitorPair 删除本地监控数据并同步到Redis
 func (r *RedisController) DeleteMonitorPair(pair string, direct string) {
 	// 先删除本地数据
@@ -53,11 +69,308 @@ func (r *RedisController) DeleteMonitorPair(pair string, direct string) {
 		log.Printf("删除监控数据: %s", localKey)
 	}
 	r.mutexMonitorPairs.Unlock()
+	r.SetConfirmProgress(pair, direct, model.ConfirmProgress{})
+	r.notifyPairsChanged()
 
 	// 同步删除Redis
 	r.deletePairDataRedis(pair, direct)
 }
 
+// CancelOcoPeer 监控的交易已被接受后取消 OCO 关联的另一方向监控，返回被取消的监控。
+// 只有对方仍指向本方向且还在等待触发时才删除，避免误删之后重新设置的监控
+func (r *RedisController) CancelOcoPeer(pair, direct string) (model.PairMonitorData, bool) {
+	data, exists := r.GetMonitorPair(pair, direct)
+	if !exists || data.OCO == "" {
+		return model.PairMonitorData{}, false
+	}
+	peer, exists := r.GetMonitorPair(pair, data.OCO)
+	if !exists || peer.OCO != direct || !peer.IsArmed() {
+		return model.PairMonitorData{}, false
+	}
+	r.DeleteMonitorPair(pair, data.OCO)
+	log.Printf("交易对 %s %s监控的交易已提交，取消 OCO 关联的 %s 监控", pair, direct, data.OCO)
+	return peer, true
+}
+
+// OcoPeerPending OCO 关联的另一方向监控已触发、交易还在处理中时返回 true，此时本方向暂不触发，
+// 等对方的交易被接受（本方向随之取消）或被拒绝后再评估
+func (r *RedisController) OcoPeerPending(data model.PairMonitorData) bool {
+	if data.OCO == "" {
+		return false
+	}
+	peer, exists := r.GetMonitorPair(data.Pair, data.OCO)
+	if !exists || peer.OCO != data.Direct {
+		return false
+	}
+	if peer.State == model.MonitorStateTriggered {
+		return true
+	}
+	// 分批入场的档位单独触发
+	for _, rung := range peer.Ladder {
+		if rung.State == model.MonitorStateTriggered {
+			return true
+		}
+	}
+	return false
+}
+
+// errStateConflict Redis 中的监控已被其他实例切换状态或删除
+var errStateConflict = errors.New("监控状态已变化")
+
+// TransitionMonitorState 仅当监控处于 from 中的某个状态时切换到 to，返回是否切换成功。
+// 状态在 Redis 中通过 WATCH/MULTI 比较并切换，多个实例共用同一个 Redis 时同一监控也只会被切换一次
+func (r *RedisController) TransitionMonitorState(pair, direct, to string, from ...string) bool {
+	current, ok := r.transitionMonitor(pair, direct, func(data *model.PairMonitorData) (string, bool) {
+		current := data.State
+		if current == "" {
+			current = model.MonitorStateArmed
+		}
+		if !stateIn(current, from) {
+			return current, false
+		}
+		data.State = to
+		data.StateTime = r.Now().Unix()
+		return current, true
+	})
+	if ok {
+		log.Printf("监控状态变更: %s:%s %s -> %s", pair, direct, current, to)
+	}
+	return ok
+}
+
+// TransitionRungState 切换分批入场中第 index 个档位（从0开始）的状态，规则与 TransitionMonitorState 相同
+func (r *RedisController) TransitionRungState(pair, direct string, index int, to string, from ...string) bool {
+	current, ok := r.transitionMonitor(pair, direct, func(data *model.PairMonitorData) (string, bool) {
+		if index < 0 || index >= len(data.Ladder) {
+			return "", false
+		}
+		current := data.Ladder[index].State
+		if current == "" {
+			current = model.MonitorStateArmed
+		}
+		if !stateIn(current, from) {
+			return current, false
+		}
+		// 复制档位切片，避免修改其他协程持有的快照
+		ladder := make([]model.LadderRung, len(data.Ladder))
+		copy(ladder, data.Ladder)
+		ladder[index].State = to
+		ladder[index].StateTime = r.Now().Unix()
+		data.Ladder = ladder
+		return current, true
+	})
+	if ok {
+		log.Printf("档位状态变更: %s:%s 第%d档 %s -> %s", pair, direct, index+1, current, to)
+	}
+	return ok
+}
+
+// transitionMonitor 用 apply 检查并修改监控，apply 返回切换前的状态以及是否允许切换。
+// 先按本地数据检查，再在 Redis 中比较并写入，成功后更新本地数据；
+// Redis 中的监控已不存在时视为已被其他实例处理。Redis 不可用时退回到本地锁内比较，只能保证单个实例内不重复切换
+func (r *RedisController) transitionMonitor(pair, direct string, apply func(data *model.PairMonitorData) (string, bool)) (string, bool) {
+	localKey := fmt.Sprintf("%s:%s", pair, direct)
+	data, exists := r.GetMonitorPair(pair, direct)
+	if !exists {
+		return "", false
+	}
+	if _, ok := apply(&data); !ok {
+		return "", false
+	}
+
+	if r.Client != nil {
+		updated, current, err := r.transitionMonitorRedis(pair, direct, apply)
+		switch {
+		case err == nil:
+			r.mutexMonitorPairs.Lock()
+			if _, exists := r.MonitorPairs[localKey]; exists {
+				r.MonitorPairs[localKey] = updated
+			}
+			r.mutexMonitorPairs.Unlock()
+			return current, true
+		case errors.Is(err, errStateConflict):
+			return "", false
+		default:
+			log.Printf("在Redis中切换监控状态失败 %s，只切换本地状态: %v", localKey, err)
+		}
+	}
+
+	r.mutexMonitorPairs.Lock()
+	data, exists = r.MonitorPairs[localKey]
+	if !exists {
+		r.mutexMonitorPairs.Unlock()
+		return "", false
+	}
+	current, ok := apply(&data)
+	if !ok {
+		r.mutexMonitorPairs.Unlock()
+		return "", false
+	}
+	r.MonitorPairs[localKey] = data
+	r.mutexMonitorPairs.Unlock()
+
+	if err := r.updatePairDataToRedis(data, direct); err != nil {
+		log.Printf("同步监控状态到Redis失败 %s: %v", localKey, err)
+	}
+	return current, true
+}
+
+// transitionMonitorRedis 在 WATCH/MULTI 事务中读取 Redis 中的监控、检查并写回，保留原有过期时间。
+// 事务期间监控被其他客户端修改时重试
+func (r *RedisController) transitionMonitorRedis(pair, direct string, apply func(data *model.PairMonitorData) (string, bool)) (model.PairMonitorData, string, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:%s:%s", MonitorKey, pair, direct)
+
+	var data model.PairMonitorData
+	var current string
+	txf := func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return errStateConflict
+		}
+		if err != nil {
+			return err
+		}
+		data = model.PairMonitorData{}
+		if err := json.Unmarshal([]byte(val), &data); err != nil {
+			return err
+		}
+		var ok bool
+		if current, ok = apply(&data); !ok {
+			return errStateConflict
+		}
+		dataBytes, err := json.Marshal(&data)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, string(dataBytes), redis.KeepTTL)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		err := r.Client.Watch(ctx, txf, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return data, current, err
+		}
+	}
+	return data, "", errStateConflict
+}
+
+// stateIn 判断状态是否在给定列表中
+func stateIn(state string, states []string) bool {
+	for i := range states {
+		if state == states[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// ListMonitorPairs 获取本地所有监控数据的副本
+func (r *RedisController) ListMonitorPairs() []model.PairMonitorData {
+	r.mutexMonitorPairs.RLock()
+	defer r.mutexMonitorPairs.RUnlock()
+
+	pairsData := make([]model.PairMonitorData, 0, len(r.MonitorPairs))
+	for _, data := range r.MonitorPairs {
+		pairsData = append(pairsData, data)
+	}
+	return pairsData
+}
+
+// SyntheticPairsForLeg 返回以 leg 为分子或分母、且设置了监控的合成交易对
+func (r *RedisController) SyntheticPairsForLeg(leg string) []string {
+	r.mutexMonitorPairs.RLock()
+	defer r.mutexMonitorPairs.RUnlock()
+
+	var pairs []string
+	seen := make(map[string]bool)
+	for _, data := range r.MonitorPairs {
+		if seen[data.Pair] {
+			continue
+		}
+		base, quote, ok := model.ParseSyntheticPair(data.Pair)
+		if !ok || (base != leg && quote != leg) {
+			continue
+		}
+		seen[data.Pair] = true
+		pairs = append(pairs, data.Pair)
+	}
+	return pairs
+}
+
+// UpdateMonitorTrailExtreme 更新追踪入场记录的极值，只有更极端的值才会写入（做空取更高，做多取更低）。
+// 与状态切换一样在 Redis 中比较并写入，监控已不是 armed 或已被删除时不写入
+func (r *RedisController) UpdateMonitorTrailExtreme(pair, direct string, extreme float64) bool {
+	if extreme <= 0 {
+		return false
+	}
+	_, ok := r.transitionMonitor(pair, direct, func(data *model.PairMonitorData) (string, bool) {
+		if !data.IsArmed() {
+			return data.State, false
+		}
+		if data.TrailExtreme > 0 {
+			if (direct == "short" && extreme <= data.TrailExtreme) || (direct == "long" && extreme >= data.TrailExtreme) {
+				return data.State, false
+			}
+		}
+		data.TrailExtreme = extreme
+		return data.State, true
+	})
+	return ok
+}
+
+// UpdateMonitorPrice 更新监控的限价，用于随 ATR 变化的限价，写入规则与 UpdateMonitorTrailExtreme 相同
+func (r *RedisController) UpdateMonitorPrice(pair, direct string, price float64) bool {
+	if price <= 0 {
+		return false
+	}
+	_, ok := r.transitionMonitor(pair, direct, func(data *model.PairMonitorData) (string, bool) {
+		if !data.IsArmed() || data.Price == price {
+			return data.State, false
+		}
+		data.Price = price
+		return data.State, true
+	})
+	return ok
+}
+
+// UpdateMonitorPriceSide 更新提醒记录的价格位置，只修改这一个字段，不改变监控状态，监控已被删除时不写入
+func (r *RedisController) UpdateMonitorPriceSide(pair, direct, side string) bool {
+	_, ok := r.transitionMonitor(pair, direct, func(data *model.PairMonitorData) (string, bool) {
+		if data.PriceSide == side {
+			return data.State, false
+		}
+		data.PriceSide = side
+		return data.State, true
+	})
+	return ok
+}
+
+// GetConfirmProgress 获取监控的确认窗口进度
+func (r *RedisController) GetConfirmProgress(pair, direct string) model.ConfirmProgress {
+	r.mutexProgress.RLock()
+	defer r.mutexProgress.RUnlock()
+
+	return r.ConfirmProgress[fmt.Sprintf("%s:%s", pair, direct)]
+}
+
+// SetConfirmProgress 保存监控的确认窗口进度，进度为空时删除
+func (r *RedisController) SetConfirmProgress(pair, direct string, progress model.ConfirmProgress) {
+	r.mutexProgress.Lock()
+	defer r.mutexProgress.Unlock()
+
+	localKey := fmt.Sprintf("%s:%s", pair, direct)
+	if progress == (model.ConfirmProgress{}) {
+		delete(r.ConfirmProgress, localKey)
+		return
+	}
+	r.ConfirmProgress[localKey] = progress
+}
+
 // HasMonitorPair 检查是否存在监控数据
 func (r *RedisController) HasMonitorPair(pair, direct string) bool {
 	r.mutexMonitorPairs.RLock()
@@ -109,6 +422,10 @@ func (r *RedisController) GetAllMonitorPairsData(direct string) []model.PairMoni
 
 // deletePairDataRedis 从 Redis 删除单个交易对数据（内部方法）
 func (r *RedisController) deletePairDataRedis(pair string, direct string) {
+	if r.Client == nil {
+		// 本地模式（回测）不同步Redis
+		return
+	}
 	ctx := context.Background()
 	key := fmt.Sprintf("%s:%s:%s", MonitorKey, pair, direct)
 	r.Client.Del(ctx, key).Result()
@@ -116,6 +433,9 @@ func (r *RedisController) deletePairDataRedis(pair string, direct string) {
 
 // setPairDataToRedis Redis 设置单个交易对数据（内部方法）
 func (r *RedisController) SetPairDataToRedis(data model.PairMonitorData, direct string) error {
+	if r.Client == nil {
+		return nil
+	}
 	ctx := context.Background()
 	key := fmt.Sprintf("%s:%s:%s", MonitorKey, data.Pair, direct)
 
@@ -133,3 +453,20 @@ func (r *RedisController) SetPairDataToRedis(data model.PairMonitorData, direct
 	_, err = r.Client.Set(ctx, key, string(dataBytes), time.Duration(randomExpire)*time.Second).Result()
 	return err
 }
+
+// updatePairDataToRedis 更新Redis中已存在的监控数据，保留原有过期时间
+func (r *RedisController) updatePairDataToRedis(data model.PairMonitorData, direct string) error {
+	if r.Client == nil {
+		return nil
+	}
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:%s:%s", MonitorKey, data.Pair, direct)
+
+	dataBytes, err := json.Marshal(&data)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Client.Set(ctx, key, string(dataBytes), redis.KeepTTL).Result()
+	return err
+}
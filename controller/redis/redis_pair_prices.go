@@ -24,6 +24,16 @@ func (r *RedisController) GetPairPrice(pair string) model.PairData {
 		return model.PairData{}
 	}
 
+	// 合成交易对由两条腿的最新价格实时计算
+	if base, quote, ok := model.ParseSyntheticPair(pair); ok {
+		baseData, baseExists := r.PairPrices[base]
+		quoteData, quoteExists := r.PairPrices[quote]
+		if !baseExists || !quoteExists {
+			return model.PairData{}
+		}
+		return model.SyntheticPairData(pair, *baseData, *quoteData)
+	}
+
 	if data, exists := r.PairPrices[pair]; exists {
 		return *data
 	}
@@ -47,3 +57,15 @@ func (r *RedisController) GetAllPairPricesData() ([]model.PairData, error) {
 	}
 	return pairsData, nil
 }
+
+// ListPairPrices 获取所有交易对价格数据，包括不在监听列表中的交易对
+func (r *RedisController) ListPairPrices() []model.PairData {
+	r.mutexPairPrices.RLock()
+	defer r.mutexPairPrices.RUnlock()
+
+	pairsData := make([]model.PairData, 0, len(r.PairPrices))
+	for _, data := range r.PairPrices {
+		pairsData = append(pairsData, *data)
+	}
+	return pairsData
+}
@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"monitor-trade/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PaperKey 模拟交易数据在Redis中的键
+const PaperKey = "paper:trades"
+
+// LoadPaperState 从Redis读取模拟交易数据，不存在时返回空数据
+func (r *RedisController) LoadPaperState() (model.PaperState, error) {
+	var state model.PaperState
+	if r.Client == nil {
+		// 本地模式每次从空数据开始
+		return state, nil
+	}
+	val, err := r.Client.Get(context.Background(), PaperKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return state, nil
+		}
+		return state, err
+	}
+	err = json.Unmarshal([]byte(val), &state)
+	return state, err
+}
+
+// SavePaperState 保存模拟交易数据到Redis，不设置过期时间
+func (r *RedisController) SavePaperState(state model.PaperState) error {
+	if r.Client == nil {
+		return nil
+	}
+	jsonData, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return r.Client.Set(context.Background(), PaperKey, jsonData, 0).Err()
+}
@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"monitor-trade/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PauseKey 自动交易暂停状态在Redis中的键，通过keyspace事件同步到所有实例
+const PauseKey = "control:paused"
+
+// LoadPauseState 从Redis读取暂停状态到本地
+func (r *RedisController) LoadPauseState() error {
+	var state model.PauseState
+	val, err := r.Client.Get(context.Background(), PauseKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			r.setLocalPause(nil)
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal([]byte(val), &state); err != nil {
+		return err
+	}
+	r.setLocalPause(&state)
+	return nil
+}
+
+// Pause 暂停自动交易，不设置过期时间
+func (r *RedisController) Pause(source string, at int64) error {
+	state := model.PauseState{Source: source, Time: at}
+	if r.Client == nil {
+		// 本地模式只保存在本地
+		r.setLocalPause(&state)
+		return nil
+	}
+	jsonData, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := r.Client.Set(context.Background(), PauseKey, jsonData, 0).Err(); err != nil {
+		return err
+	}
+	r.setLocalPause(&state)
+	return nil
+}
+
+// Resume 恢复自动交易
+func (r *RedisController) Resume() error {
+	if r.Client == nil {
+		r.setLocalPause(nil)
+		return nil
+	}
+	if err := r.Client.Del(context.Background(), PauseKey).Err(); err != nil {
+		return err
+	}
+	r.setLocalPause(nil)
+	return nil
+}
+
+// IsPaused 本地缓存的暂停状态，价格推送时调用，不访问Redis
+func (r *RedisController) IsPaused() bool {
+	r.mutexPause.RLock()
+	defer r.mutexPause.RUnlock()
+	return r.pause != nil
+}
+
+// PauseState 返回本地缓存的暂停状态
+func (r *RedisController) PauseState() (model.PauseState, bool) {
+	r.mutexPause.RLock()
+	defer r.mutexPause.RUnlock()
+	if r.pause == nil {
+		return model.PauseState{}, false
+	}
+	return *r.pause, true
+}
+
+// setLocalPause 更新本地缓存的暂停状态
+func (r *RedisController) setLocalPause(state *model.PauseState) {
+	r.mutexPause.Lock()
+	defer r.mutexPause.Unlock()
+	r.pause = state
+}
+
+// syncPauseFromRedis 收到暂停键的keyspace事件后重新读取暂停状态
+func (r *RedisController) syncPauseFromRedis() {
+	if err := r.LoadPauseState(); err != nil {
+		log.Printf("同步暂停状态失败: %v", err)
+		return
+	}
+	log.Printf("同步暂停状态: paused=%v", r.IsPaused())
+}
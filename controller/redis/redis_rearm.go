@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"monitor-trade/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RearmKey 等待重新设置的监控在Redis中的键前缀，完整键为 rearm:pair:direct
+const RearmKey = "rearm"
+
+func rearmKey(pair, direct string) string {
+	return fmt.Sprintf("%s:%s:%s", RearmKey, pair, direct)
+}
+
+// SetRearmTimer 保存等待重新设置的监控，不设置过期时间
+func (r *RedisController) SetRearmTimer(timer model.RearmTimer) error {
+	if r.Client == nil {
+		// 本地模式只保存在本地
+		r.mutexRearm.Lock()
+		defer r.mutexRearm.Unlock()
+		r.rearmTimers[rearmKey(timer.Monitor.Pair, timer.Monitor.Direct)] = timer
+		return nil
+	}
+	jsonData, err := json.Marshal(timer)
+	if err != nil {
+		return err
+	}
+	return r.Client.Set(context.Background(), rearmKey(timer.Monitor.Pair, timer.Monitor.Direct), jsonData, 0).Err()
+}
+
+// GetRearmTimer 读取交易对指定方向等待重新设置的监控
+func (r *RedisController) GetRearmTimer(pair, direct string) (model.RearmTimer, bool, error) {
+	var timer model.RearmTimer
+	if r.Client == nil {
+		r.mutexRearm.Lock()
+		defer r.mutexRearm.Unlock()
+		timer, exists := r.rearmTimers[rearmKey(pair, direct)]
+		return timer, exists, nil
+	}
+	val, err := r.Client.Get(context.Background(), rearmKey(pair, direct)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return timer, false, nil
+		}
+		return timer, false, err
+	}
+	if err := json.Unmarshal([]byte(val), &timer); err != nil {
+		return timer, false, err
+	}
+	return timer, true, nil
+}
+
+// ListRearmTimers 读取所有等待重新设置的监控
+func (r *RedisController) ListRearmTimers() ([]model.RearmTimer, error) {
+	if r.Client == nil {
+		r.mutexRearm.Lock()
+		defer r.mutexRearm.Unlock()
+		timers := make([]model.RearmTimer, 0, len(r.rearmTimers))
+		for _, timer := range r.rearmTimers {
+			timers = append(timers, timer)
+		}
+		return timers, nil
+	}
+	ctx := context.Background()
+	keys, err := r.Client.Keys(ctx, RearmKey+":*").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	timers := make([]model.RearmTimer, 0, len(keys))
+	for _, key := range keys {
+		val, err := r.Client.Get(ctx, key).Result()
+		if err != nil {
+			log.Printf("获取Redis键 %s 的值失败: %v", key, err)
+			continue
+		}
+		var timer model.RearmTimer
+		if err := json.Unmarshal([]byte(val), &timer); err != nil {
+			log.Printf("解析Redis键 %s 的值失败: %v", key, err)
+			continue
+		}
+		timers = append(timers, timer)
+	}
+	return timers, nil
+}
+
+// DeleteRearmTimer 删除等待重新设置的监控，返回是否存在
+func (r *RedisController) DeleteRearmTimer(pair, direct string) bool {
+	if r.Client == nil {
+		r.mutexRearm.Lock()
+		defer r.mutexRearm.Unlock()
+		key := rearmKey(pair, direct)
+		_, exists := r.rearmTimers[key]
+		delete(r.rearmTimers, key)
+		return exists
+	}
+	count, err := r.Client.Del(context.Background(), rearmKey(pair, direct)).Result()
+	if err != nil {
+		log.Printf("删除重新设置计时失败 %s %s: %v", pair, direct, err)
+		return false
+	}
+	return count > 0
+}
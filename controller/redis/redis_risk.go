@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"monitor-trade/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// CircuitBreakerKey 风控熔断状态在Redis中的键
+const CircuitBreakerKey = "risk:breaker"
+
+// GetCircuitBreaker 读取风控熔断状态，返回是否已触发
+func (r *RedisController) GetCircuitBreaker() (model.CircuitBreaker, bool, error) {
+	var breaker model.CircuitBreaker
+	if r.Client == nil {
+		// 本地模式的熔断状态只保存在内存中
+		r.mutexBreaker.Lock()
+		defer r.mutexBreaker.Unlock()
+		if r.breaker == nil {
+			return breaker, false, nil
+		}
+		return *r.breaker, true, nil
+	}
+	val, err := r.Client.Get(context.Background(), CircuitBreakerKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return breaker, false, nil
+		}
+		return breaker, false, err
+	}
+	if err := json.Unmarshal([]byte(val), &breaker); err != nil {
+		return breaker, false, err
+	}
+	return breaker, true, nil
+}
+
+// SetCircuitBreaker 保存风控熔断状态，不设置过期时间
+func (r *RedisController) SetCircuitBreaker(breaker model.CircuitBreaker) error {
+	jsonData, err := json.Marshal(breaker)
+	if err != nil {
+		return err
+	}
+	if r.Client == nil {
+		r.mutexBreaker.Lock()
+		r.breaker = &breaker
+		r.mutexBreaker.Unlock()
+		return nil
+	}
+	return r.Client.Set(context.Background(), CircuitBreakerKey, jsonData, 0).Err()
+}
+
+// ResetCircuitBreaker 重置风控熔断状态
+func (r *RedisController) ResetCircuitBreaker() error {
+	if r.Client == nil {
+		r.mutexBreaker.Lock()
+		r.breaker = nil
+		r.mutexBreaker.Unlock()
+		return nil
+	}
+	return r.Client.Del(context.Background(), CircuitBreakerKey).Err()
+}
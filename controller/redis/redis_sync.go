@@ -47,6 +47,7 @@ func (r *RedisController) LoadMonitorPairsFromRedis() error {
 	}
 
 	log.Printf("监控数据加载完成，本地缓存 %d 条记录", len(r.MonitorPairs))
+	r.notifyPairsChanged()
 	return nil
 }
 
@@ -77,6 +78,7 @@ func (r *RedisController) syncMonitorPairFromRedis(redisKey string) {
 	localKey := fmt.Sprintf("%s:%s", pairData.Pair, pairData.Direct)
 	r.MonitorPairs[localKey] = pairData
 	log.Printf("同步监控数据: %s", localKey)
+	r.notifyPairsChanged()
 }
 
 // removeMonitorPairByRedisKey 根据Redis键从本地删除监控数据
@@ -91,6 +93,7 @@ func (r *RedisController) removeMonitorPairByRedisKey(redisKey string) {
 		if _, exists := r.MonitorPairs[localKey]; exists {
 			delete(r.MonitorPairs, localKey)
 			log.Printf("删除本地监控数据: %s", localKey)
+			r.notifyPairsChanged()
 		}
 	}
 }
@@ -128,12 +131,13 @@ func (r *RedisController) EnableRedisKeyspaceNotifications() error {
 func (r *RedisController) StartRedisSync() {
 	ctx := r.Client.Context()
 
-	// 订阅keyspace事件，监听所有monitor:*键的变化
+	// 订阅keyspace事件，监听所有monitor:*键和暂停状态键的变化
 	pattern := fmt.Sprintf("__keyspace@0__:%s:*", MonitorKey)
-	pubsub := r.Client.PSubscribe(ctx, pattern)
+	pausePattern := fmt.Sprintf("__keyspace@0__:%s", PauseKey)
+	pubsub := r.Client.PSubscribe(ctx, pattern, pausePattern)
 	defer pubsub.Close()
 
-	log.Printf("开始监听Redis keyspace事件: %s", pattern)
+	log.Printf("开始监听Redis keyspace事件: %s, %s", pattern, pausePattern)
 
 	for {
 		msg, err := pubsub.ReceiveMessage(ctx)
@@ -150,6 +154,11 @@ func (r *RedisController) StartRedisSync() {
 
 		log.Printf("收到Redis事件: 键=%s, 操作=%s", pairKey, msg.Payload)
 
+		if pairKey == PauseKey {
+			go r.syncPauseFromRedis()
+			continue
+		}
+
 		// 处理不同的Redis事件
 		switch msg.Payload {
 		case "set":
@@ -1,11 +1,17 @@
 package redis
 
+import (
+	"monitor-trade/model"
+	"sort"
+)
+
 // SetWatchedPairs 设置需要监听的交易对
 func (r *RedisController) SetWatchedPairs(pairs []string) {
 	r.mutexWatchedPairs.Lock()
 	defer r.mutexWatchedPairs.Unlock()
 
 	r.WatchedPairs = pairs
+	r.notifyPairsChanged()
 }
 
 // GetWatchedPairs 获取需要监听的交易对
@@ -32,3 +38,64 @@ func (r *RedisController) IsWatchedPair(pair string) bool {
 	}
 	return false
 }
+
+// IsSubscribedPair 检查交易对是否需要处理行情：监听列表中的交易对、设置了监控的交易对，以及合成交易对的腿，与 SubscribedPairs 一致
+func (r *RedisController) IsSubscribedPair(pair string) bool {
+	if r.IsWatchedPair(pair) {
+		return true
+	}
+
+	r.mutexMonitorPairs.RLock()
+	defer r.mutexMonitorPairs.RUnlock()
+	for _, data := range r.MonitorPairs {
+		if data.Pair == pair {
+			return true
+		}
+		if base, quote, ok := model.ParseSyntheticPair(data.Pair); ok && (base == pair || quote == pair) {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscribedPairs 需要订阅行情的交易对：监听列表中的交易对、设置了监控的交易对，以及合成交易对的两条腿
+func (r *RedisController) SubscribedPairs() []string {
+	seen := make(map[string]bool)
+	add := func(pair string) {
+		if base, quote, ok := model.ParseSyntheticPair(pair); ok {
+			seen[base] = true
+			seen[quote] = true
+			return
+		}
+		seen[pair] = true
+	}
+
+	for _, pair := range r.GetWatchedPairs() {
+		add(pair)
+	}
+	r.mutexMonitorPairs.RLock()
+	for _, data := range r.MonitorPairs {
+		add(data.Pair)
+	}
+	r.mutexMonitorPairs.RUnlock()
+
+	pairs := make([]string, 0, len(seen))
+	for pair := range seen {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// PairsChanged 监听列表或监控的交易对发生变化时收到通知，多次变化会合并为一次
+func (r *RedisController) PairsChanged() <-chan struct{} {
+	return r.pairsChanged
+}
+
+// notifyPairsChanged 通知交易对变化，已有未处理的通知时直接返回，不会阻塞
+func (r *RedisController) notifyPairsChanged() {
+	select {
+	case r.pairsChanged <- struct{}{}:
+	default:
+	}
+}
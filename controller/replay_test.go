@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"monitor-trade/config"
+	"monitor-trade/controller/binance"
+	"monitor-trade/controller/redis"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordAsks 按每秒一条录制 BTCUSDT 的卖一价，返回录制文件的通配符
+func recordAsks(t *testing.T, start time.Time, asks []string) string {
+	t.Helper()
+	dir := t.TempDir()
+	recorder, err := binance.NewTickRecorder(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("创建录制器失败: %v", err)
+	}
+	for i, ask := range asks {
+		ts := start.Add(time.Duration(i) * time.Second)
+		recorder.Record(model.BookTickerData{
+			EventType:       "bookTicker",
+			Symbol:          "BTCUSDT",
+			BidPrice:        "64970",
+			AskPrice:        ask,
+			EventTime:       ts.UnixMilli(),
+			TransactionTime: ts.UnixMilli(),
+		}, ts)
+	}
+	recorder.Close()
+	return filepath.Join(dir, "*.jsonl.gz")
+}
+
+// startReplay 用本地监控和回放时钟启动处理流程并回放录制文件，返回交易通道
+func startReplay(t *testing.T, files string, monitors ...model.PairMonitorData) (*redis.RedisController, chan model.ForceBuyPayload) {
+	t.Helper()
+	conf := &config.Config{FundingRate: -0.1, PriceMaxAgeMs: 5000}
+	redisController := redis.NewLocalRedisController(conf)
+	for _, data := range monitors {
+		redisController.SetLocalMonitorPair(data)
+	}
+
+	binanceController := binance.NewBinanceController()
+	t.Cleanup(binanceController.Stop)
+	binanceController.SetRedisController(redisController)
+
+	tradeChan := make(chan model.ForceBuyPayload, 10)
+	mainController := NewMainController(nil, redisController, conf, binanceController, nil, tradeChan)
+	mainController.UseRecordedTime()
+	go mainController.Start()
+
+	if err := binanceController.Replay(mainController.WatchKey, 0, files); err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	return redisController, tradeChan
+}
+
+// TestReplayTriggersShortOnce 回放录制的行情，验证做空监控只在卖价突破限价的那一条推送触发一次，做多监控不触发。
+// 测试不连接 Binance 和 Redis，监控直接写入本地缓存
+func TestReplayTriggersShortOnce(t *testing.T) {
+	start := time.Date(2024, 1, 1, 3, 12, 0, 0, time.UTC)
+	files := recordAsks(t, start, []string{"64980", "64995", "65000", "65020", "65030", "64990"})
+
+	level := &model.Condition{Type: model.ConditionLevel}
+	pair := "BTC/USDT:USDT"
+	redisController, tradeChan := startReplay(t, files,
+		model.PairMonitorData{Pair: pair, Direct: tg.ShortDirect, Price: 65000, Condition: level},
+		model.PairMonitorData{Pair: pair, Direct: tg.LongDirect, Price: 64000, Condition: level})
+
+	select {
+	case payload := <-tradeChan:
+		if payload.Pair != pair || payload.Side != "short" || payload.Price != 65020 {
+			t.Errorf("交易请求错误: %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("卖价突破限价后应该触发做空")
+	}
+
+	select {
+	case payload := <-tradeChan:
+		t.Errorf("监控只应触发一次，多出交易请求: %+v", payload)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	data, _ := redisController.GetMonitorPair(pair, tg.ShortDirect)
+	if data.State != model.MonitorStateTriggered {
+		t.Errorf("做空监控状态应为 triggered，实际 %q", data.State)
+	}
+	// 状态时间为录制时的时间，而不是回放时刻
+	if data.StateTime != start.Add(3*time.Second).Unix() {
+		t.Errorf("状态时间应为录制时间 %d，实际 %d", start.Add(3*time.Second).Unix(), data.StateTime)
+	}
+	if data, _ := redisController.GetMonitorPair(pair, tg.LongDirect); !data.IsArmed() {
+		t.Errorf("做多监控不应触发，实际状态 %q", data.State)
+	}
+}
+
+// TestReplayConfirmUsesRecordedTime 不等待回放时确认窗口按录制时间计算，与录制时的触发时刻一致
+func TestReplayConfirmUsesRecordedTime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 3, 12, 0, 0, time.UTC)
+	files := recordAsks(t, start, []string{"64980", "65020", "65025", "65030", "64990"})
+
+	pair := "BTC/USDT:USDT"
+	_, tradeChan := startReplay(t, files, model.PairMonitorData{Pair: pair, Direct: tg.ShortDirect, Price: 65000,
+		Condition: &model.Condition{Type: model.ConditionLevel}, ConfirmSeconds: 2})
+
+	select {
+	case payload := <-tradeChan:
+		// 65020 开始满足条件，录制时间 2 秒后的 65030 才确认
+		if payload.Price != 65030 {
+			t.Errorf("应在满足条件 2 秒后的推送触发，实际价格 %v", payload.Price)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("确认窗口按录制时间满足后应该触发做空")
+	}
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"monitor-trade/model"
+	"strings"
+)
+
+// dispatchSynthetic 腿的价格更新后，重新计算设置了监控的合成交易对并交给对应的处理协程
+func (c *MainController) dispatchSynthetic(leg model.PairData) {
+	for _, pair := range c.RedisController.SyntheticPairsForLeg(leg.Pair) {
+		pairData := c.RedisController.GetPairPrice(pair)
+		if pairData.BidPrice <= 0 || pairData.AskPrice <= 0 {
+			continue
+		}
+		c.RedisController.UpdateCandles(pair, &pairData, c.now())
+		c.dispatch(pairData)
+	}
+}
+
+// syntheticLegPayloads 生成合成交易对监控触发后各条腿的交易请求，做多腿按买一价、做空腿按卖一价挂限价单。
+// 任意一条腿没有价格时返回错误
+func syntheticLegPayloads(data model.PairMonitorData, prices func(pair string) model.PairData) ([]model.ForceBuyPayload, error) {
+	legs := data.SyntheticLegs()
+	if len(legs) == 0 {
+		return nil, fmt.Errorf("%s 不是合成交易对", data.Pair)
+	}
+
+	payloads := make([]model.ForceBuyPayload, 0, len(legs))
+	for _, leg := range legs {
+		legData := prices(leg.Pair)
+		price := legData.BidPrice
+		if leg.Side == "short" {
+			price = legData.AskPrice
+		}
+		if price <= 0 {
+			return nil, fmt.Errorf("无法获取 %s 的最新价格", leg.Pair)
+		}
+		payloads = append(payloads, model.ForceBuyPayload{
+			Pair:        leg.Pair,
+			Price:       price,
+			Side:        leg.Side,
+			EntryTag:    "force_entry",
+			OrderType:   "limit",
+			Monitor:     data.Direct,
+			MonitorPair: data.Pair,
+		})
+	}
+	return payloads, nil
+}
+
+// submitSyntheticLegs 合成交易对监控触发后，通过 Freqtrade 分别开仓配置的腿
+func (c *MainController) submitSyntheticLegs(data model.PairMonitorData, pairData *model.PairData) {
+	payloads, err := syntheticLegPayloads(data, c.RedisController.GetPairPrice)
+	if err != nil {
+		log.Printf("❌ 合成交易对 %s %s腿生成失败: %v", data.Pair, data.Direct, err)
+		c.RedisController.TransitionMonitorState(data.Pair, data.Direct, model.MonitorStateFailed, model.MonitorStateTriggered)
+		c.TgController.SendMessage(fmt.Sprintf("❌ %s %s操作失败: %v，监控已标记为失败", data.Pair, data.Direct, err))
+		return
+	}
+
+	legs := make([]string, 0, len(payloads))
+	for _, payload := range payloads {
+		legs = append(legs, fmt.Sprintf("%s %s %.6f", payload.Pair, payload.Side, payload.Price))
+	}
+	log.Printf("时间戳 %s 合成交易对 %s 比价 %.6f/%.6f 满足%s条件，限价 %.6f，开仓: %s",
+		pairData.Timestamp, data.Pair, pairData.BidPrice, pairData.AskPrice, data.Direct, data.Price, strings.Join(legs, ", "))
+	c.TgController.SendMessage(fmt.Sprintf("🔀 %s 比价满足%s条件，限价 %.6f，开仓: %s",
+		data.Pair, data.Direct, data.Price, strings.Join(legs, ", ")))
+
+	for _, payload := range payloads {
+		c.submitTrade(payload)
+	}
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"math"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+	"testing"
+)
+
+// TestSyntheticPairData 测试合成交易对的比价计算
+func TestSyntheticPairData(t *testing.T) {
+	base, quote, ok := model.ParseSyntheticPair("ETH/BTC")
+	if !ok || base != "ETH/USDT:USDT" || quote != "BTC/USDT:USDT" {
+		t.Fatalf("解析合成交易对失败: %s %s %v", base, quote, ok)
+	}
+	for _, pair := range []string{"BTC/USDT:USDT", "BTC/USDT", "ETH", "ETH/ETH"} {
+		if model.IsSyntheticPair(pair) {
+			t.Errorf("%s 不应该是合成交易对", pair)
+		}
+	}
+
+	data := model.SyntheticPairData("ETH/BTC",
+		model.PairData{BidPrice: 3000, AskPrice: 3001},
+		model.PairData{BidPrice: 59990, AskPrice: 60000})
+	if math.Abs(data.BidPrice-0.05) > 1e-9 || math.Abs(data.AskPrice-3001.0/59990) > 1e-9 {
+		t.Errorf("比价计算错误: %.8f %.8f", data.BidPrice, data.AskPrice)
+	}
+
+	// 任意一条腿没有价格时不产生比价
+	if data := model.SyntheticPairData("ETH/BTC", model.PairData{BidPrice: 3000, AskPrice: 3001}, model.PairData{}); data.BidPrice != 0 {
+		t.Errorf("缺少分母价格时不应产生比价: %+v", data)
+	}
+}
+
+// TestSyntheticLegPayloads 测试合成交易对触发后各条腿的方向和价格
+func TestSyntheticLegPayloads(t *testing.T) {
+	prices := map[string]model.PairData{
+		"ETH/USDT:USDT": {BidPrice: 3000, AskPrice: 3001},
+		"BTC/USDT:USDT": {BidPrice: 59990, AskPrice: 60000},
+	}
+	getPrice := func(pair string) model.PairData { return prices[pair] }
+
+	testCases := []struct {
+		direct   string
+		legs     string
+		expected []model.ForceBuyPayload
+	}{
+		{tg.LongDirect, "", []model.ForceBuyPayload{
+			{Pair: "ETH/USDT:USDT", Side: "long", Price: 3000},
+			{Pair: "BTC/USDT:USDT", Side: "short", Price: 60000},
+		}},
+		{tg.ShortDirect, model.SyntheticLegBase, []model.ForceBuyPayload{
+			{Pair: "ETH/USDT:USDT", Side: "short", Price: 3001},
+		}},
+		{tg.ShortDirect, model.SyntheticLegQuote, []model.ForceBuyPayload{
+			{Pair: "BTC/USDT:USDT", Side: "long", Price: 59990},
+		}},
+	}
+
+	for _, tc := range testCases {
+		data := model.PairMonitorData{Pair: "ETH/BTC", Direct: tc.direct, Legs: tc.legs}
+		payloads, err := syntheticLegPayloads(data, getPrice)
+		if err != nil {
+			t.Fatalf("生成交易请求失败: %v", err)
+		}
+		if len(payloads) != len(tc.expected) {
+			t.Fatalf("%s leg=%s: 期望 %d 条腿，实际 %d", tc.direct, tc.legs, len(tc.expected), len(payloads))
+		}
+		for i, expected := range tc.expected {
+			payload := payloads[i]
+			if payload.Pair != expected.Pair || payload.Side != expected.Side || payload.Price != expected.Price {
+				t.Errorf("%s leg=%s: 期望 %s %s %.2f，实际 %s %s %.2f", tc.direct, tc.legs,
+					expected.Pair, expected.Side, expected.Price, payload.Pair, payload.Side, payload.Price)
+			}
+			if payload.MonitorPair != "ETH/BTC" || payload.Monitor != tc.direct {
+				t.Errorf("交易请求应关联到合成交易对监控: %+v", payload)
+			}
+		}
+	}
+
+	delete(prices, "BTC/USDT:USDT")
+	if _, err := syntheticLegPayloads(model.PairMonitorData{Pair: "ETH/BTC", Direct: tg.LongDirect}, getPrice); err == nil {
+		t.Error("缺少腿的价格时应该返回错误")
+	}
+}
@@ -1,6 +1,7 @@
 package tg
 
 import (
+	"fmt"
 	"log"
 	"strconv"
 	"strings"
@@ -30,35 +31,141 @@ func (tg *TgController) HandleCommand() {
 			args := update.Message.CommandArguments()
 			parts := strings.Split(args, " ")
 			if len(parts) < 2 {
-				msg.Text = "用法: /s [pair] [price]"
+				msg.Text = "用法: /s [pair] [price|+1.5%|+0.8|+2atr] [参数...] 或 /s [pair] [price:stake]..."
+			} else {
+				pair := tg.HandlePair(parts[0])
+				if strings.Contains(parts[1], ":") {
+					msg.Text = tg.handleLadderArgs(pair, ShortDirect, parts[1:])
+					break
+				}
+				// 价格支持相对当前价的写法，在处理命令时解析
+				monitorArgs, condErr := ParseMonitorArgs(parts[2:])
+				if condErr != nil {
+					msg.Text = fmt.Sprintf("❌ %v", condErr)
+				} else {
+					msg.Text = tg.handleShortCommand(pair, parts[1], monitorArgs)
+				}
+			}
+		case "l", "long":
+			args := update.Message.CommandArguments()
+			parts := strings.Split(args, " ")
+			if len(parts) < 2 {
+				msg.Text = "用法: /l [pair] [price|-3%|-0.8|-2atr] [参数...] 或 /l [pair] [price:stake]..."
+			} else {
+				pair := tg.HandlePair(parts[0])
+				if strings.Contains(parts[1], ":") {
+					msg.Text = tg.handleLadderArgs(pair, LongDirect, parts[1:])
+					break
+				}
+				// 价格支持相对当前价的写法，在处理命令时解析
+				monitorArgs, condErr := ParseMonitorArgs(parts[2:])
+				if condErr != nil {
+					msg.Text = fmt.Sprintf("❌ %v", condErr)
+				} else {
+					msg.Text = tg.handleLongCommand(pair, parts[1], monitorArgs)
+				}
+			}
+		case "ts", "tl":
+			args := update.Message.CommandArguments()
+			parts := strings.Split(args, " ")
+			direct := ShortDirect
+			if update.Message.Command() == "tl" {
+				direct = LongDirect
+			}
+			if len(parts) < 3 {
+				msg.Text = fmt.Sprintf("用法: /%s [pair] [激活价] [回撤%%] [参数...]", update.Message.Command())
+			} else {
+				pair := tg.HandlePair(parts[0])
+				price, err1 := strconv.ParseFloat(parts[1], 64)
+				percent, err2 := strconv.ParseFloat(strings.TrimSuffix(parts[2], "%"), 64)
+				monitorArgs, condErr := ParseMonitorArgs(parts[3:])
+				if err1 != nil || err2 != nil {
+					msg.Text = "激活价和回撤百分比必须是有效的数字"
+				} else if condErr != nil {
+					msg.Text = fmt.Sprintf("❌ %v", condErr)
+				} else {
+					msg.Text = tg.handleTrailingCommand(pair, direct, price, percent, monitorArgs)
+				}
+			}
+		case "tp", "sl":
+			args := update.Message.CommandArguments()
+			parts := strings.Split(args, " ")
+			direct := TakeProfitDirect
+			if update.Message.Command() == "sl" {
+				direct = StopLossDirect
+			}
+			if len(parts) < 2 {
+				msg.Text = fmt.Sprintf("用法: /%s [pair] [price] [平仓比例%%] [参数...]", update.Message.Command())
 			} else {
 				pair := tg.HandlePair(parts[0])
 				price, err := strconv.ParseFloat(parts[1], 64)
-				if err != nil {
+				rest := parts[2:]
+				// 平仓比例可选，不填时全部平仓
+				percent := 0.0
+				var percentErr error
+				if len(rest) > 0 && strings.HasSuffix(rest[0], "%") {
+					percent, percentErr = strconv.ParseFloat(strings.TrimSuffix(rest[0], "%"), 64)
+					rest = rest[1:]
+				}
+				monitorArgs, condErr := ParseMonitorArgs(rest)
+				if err != nil || percentErr != nil {
+					msg.Text = "价格和平仓比例必须是有效的数字"
+				} else if condErr != nil {
+					msg.Text = fmt.Sprintf("❌ %v", condErr)
+				} else {
+					msg.Text = tg.handleExitCommand(pair, direct, price, percent, monitorArgs)
+				}
+			}
+		case "oco":
+			args := update.Message.CommandArguments()
+			parts := strings.Split(args, " ")
+			if len(parts) < 3 {
+				msg.Text = "用法: /oco [pair] [做多价] [做空价] [参数...]"
+			} else {
+				pair := tg.HandlePair(parts[0])
+				longPrice, err1 := strconv.ParseFloat(parts[1], 64)
+				shortPrice, err2 := strconv.ParseFloat(parts[2], 64)
+				monitorArgs, condErr := ParseMonitorArgs(parts[3:])
+				if err1 != nil || err2 != nil {
 					msg.Text = "价格必须是有效的数字"
+				} else if condErr != nil {
+					msg.Text = fmt.Sprintf("❌ %v", condErr)
 				} else {
-					msg.Text = tg.handleShortCommand(pair, price)
+					msg.Text = tg.handleOcoCommand(pair, longPrice, shortPrice, monitorArgs)
 				}
 			}
-		case "l", "long":
+		case "alert":
 			args := update.Message.CommandArguments()
 			parts := strings.Split(args, " ")
 			if len(parts) < 2 {
-				msg.Text = "用法: /l [pair] [price]"
+				msg.Text = "用法: /alert [pair] [price] [repeat=10m] [参数...]"
 			} else {
 				pair := tg.HandlePair(parts[0])
 				price, err := strconv.ParseFloat(parts[1], 64)
+				repeatSeconds, rest, repeatErr := ParseRepeatArg(parts[2:])
 				if err != nil {
 					msg.Text = "价格必须是有效的数字"
+				} else if repeatErr != nil {
+					msg.Text = fmt.Sprintf("❌ %v", repeatErr)
+				} else if monitorArgs, condErr := ParseMonitorArgs(rest); condErr != nil {
+					msg.Text = fmt.Sprintf("❌ %v", condErr)
 				} else {
-					msg.Text = tg.handleLongCommand(pair, price)
+					msg.Text = tg.handleAlertCommand(pair, price, repeatSeconds, monitorArgs)
 				}
 			}
 		case "c", "cancel":
 			args := update.Message.CommandArguments()
 			parts := strings.Split(args, " ")
 			if len(parts) < 2 {
-				msg.Text = "用法: /c [pair] [direction]"
+				msg.Text = "用法: /c [pair] [direction] [档位价格]"
+			} else if len(parts) >= 3 {
+				pair := tg.HandlePair(parts[0])
+				price, err := strconv.ParseFloat(parts[2], 64)
+				if err != nil {
+					msg.Text = "档位价格必须是有效的数字"
+				} else {
+					msg.Text = tg.handleCancelRungCommand(pair, parts[1], price)
+				}
 			} else {
 				pair := tg.HandlePair(parts[0])
 				direct := parts[1]
@@ -76,6 +183,16 @@ func (tg *TgController) HandleCommand() {
 		case "adjust":
 			// /adjust 现在显示仓位信息
 			msg.Text = tg.handleShowPositionsCommand()
+		case "risk":
+			if strings.TrimSpace(update.Message.CommandArguments()) == "reset" {
+				msg.Text = tg.handleRiskResetCommand()
+			} else {
+				msg.Text = tg.handleRiskCommand()
+			}
+		case "pause":
+			msg.Text = tg.handlePauseCommand()
+		case "resume":
+			msg.Text = tg.handleResumeCommand()
 		case "whitelist":
 			// 处理白名单命令
 			msg.Text = tg.handleWhiteList()
@@ -106,6 +223,24 @@ func (tg *TgController) HandleCommand() {
 					}
 				}
 			}
+		case "adl":
+			args := update.Message.CommandArguments()
+			parts := strings.Split(args, " ")
+			if len(parts) < 3 {
+				msg.Text = "用法: /adl [pair] [price] [num] [参数...]"
+			} else {
+				pair := tg.HandlePair(parts[0])
+				price, err1 := strconv.ParseFloat(parts[1], 64)
+				stakeAmount, err2 := strconv.ParseFloat(parts[2], 64)
+				monitorArgs, condErr := ParseMonitorArgs(parts[3:])
+				if err1 != nil || err2 != nil {
+					msg.Text = "价格和金额必须是有效的数字"
+				} else if condErr != nil {
+					msg.Text = fmt.Sprintf("❌ %v", condErr)
+				} else {
+					msg.Text = tg.handleADLCommand(pair, price, stakeAmount, monitorArgs)
+				}
+			}
 		case "pc":
 			args := update.Message.CommandArguments()
 			parts := strings.Split(args, " ")
@@ -121,7 +256,7 @@ func (tg *TgController) HandleCommand() {
 				}
 			}
 		default:
-			msg.Text = "未知命令。支持的命令: /short /s, /long /l, /cancel /c, /show, /whitelist, /adjust, /ad, /pc"
+			msg.Text = "未知命令。支持的命令: /short /s, /long /l, /ts, /tl, /oco, /tp, /sl, /alert, /cancel /c, /show, /whitelist, /adjust, /ad, /adl, /pc, /risk, /pause, /resume"
 		}
 
 		log.Println(msg.Text)
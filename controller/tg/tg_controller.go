@@ -3,6 +3,7 @@ package tg
 import (
 	"log"
 	"monitor-trade/config"
+	"monitor-trade/controller/feed"
 	"monitor-trade/controller/freqtrade"
 	"monitor-trade/controller/redis"
 
@@ -12,6 +13,13 @@ import (
 const (
 	LongDirect  = "long"
 	ShortDirect = "short"
+
+	TakeProfitDirect = "tp"  // 止盈离场监控
+	StopLossDirect   = "sl"  // 止损离场监控
+	AddDirect        = "adl" // 价格触发的加仓监控
+
+	AlertUpDirect   = "alert_up"   // 价格上穿提醒，只发送通知不交易
+	AlertDownDirect = "alert_down" // 价格下穿提醒，只发送通知不交易
 )
 
 type TgController struct {
@@ -20,10 +28,12 @@ type TgController struct {
 	Bot                 *tgbotapi.BotAPI
 	RedisController     *redis.RedisController
 	FreqtradeController *freqtrade.FreqtradeController
+	Feed                feed.PriceFeed // 用于拉取计算 ATR 的历史K线和未订阅交易对的价格
 	Conf                *config.Config
 }
 
-func NewTgController(botToken string, tgId int64, controller *redis.RedisController, freqtradeController *freqtrade.FreqtradeController, conf *config.Config) *TgController {
+func NewTgController(botToken string, tgId int64, controller *redis.RedisController, freqtradeController *freqtrade.FreqtradeController,
+	priceFeed feed.PriceFeed, conf *config.Config) *TgController {
 	// 初始化 Telegram 机器人
 	bot, err := tgbotapi.NewBotAPI(botToken)
 	if err != nil {
@@ -37,6 +47,7 @@ func NewTgController(botToken string, tgId int64, controller *redis.RedisControl
 		Bot:                 bot,
 		RedisController:     controller,
 		FreqtradeController: freqtradeController,
+		Feed:                priceFeed,
 		Conf:                conf,
 	}
 }
@@ -4,33 +4,55 @@ import (
 	"fmt"
 	"log"
 	"monitor-trade/model"
+	"sort"
 	"strings"
+	"time"
 )
 
 // 处理 /short 命令
-func (tg *TgController) handleShortCommand(pair string, price float64) string {
-	data, _ := tg.RedisController.GetMonitorPair(pair, ShortDirect)
-	data.Pair = pair
+func (tg *TgController) handleShortCommand(pair string, priceExpr string, args MonitorArgs) string {
+	if args.Legs != "" && !model.IsSyntheticPair(pair) {
+		return "❌ leg= 只适用于合成交易对，如 ETH/BTC"
+	}
+	if args.Rearm && model.IsSyntheticPair(pair) {
+		return "❌ 合成交易对暂不支持平仓后重新设置"
+	}
+	if err := tg.Feed.CheckSymbol(pair); err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	// 重新设置时从空白监控开始，之前的分批入场、OCO 等参数不保留，旧限价只用于提示
+	oldData, _ := tg.RedisController.GetMonitorPair(pair, ShortDirect)
+	data := model.PairMonitorData{Pair: pair}
 	resultMsg := ""
 
-	dataPair := tg.RedisController.GetPairPrice(data.Pair)
+	dataPair := tg.pairPrice(data.Pair)
 	// 计算中间价作为当前价格
 	currentPrice := (dataPair.BidPrice + dataPair.AskPrice) / 2
 	if currentPrice <= 0 {
 		return fmt.Sprintf("❌ 无法获取 %s 的最新价格，请检查交易对是否存在", pair)
 	}
+	level, err := tg.resolvePriceLevel(pair, priceExpr, currentPrice, args)
+	if err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	price := level.Price
 	if currentPrice > price {
 		return fmt.Sprintf("❌ 当前价格 %.6f 大于设置的限价 %.6f，请调整限价", currentPrice, price)
 	}
 
-	if data.Price > 0 {
-		oldPrice := data.Price
-		data.Price = price
+	if oldData.Price > 0 {
+		oldPrice := oldData.Price
+		level.apply(&data)
 		resultMsg = fmt.Sprintf("🟢 %s 做空监听，新限价: %.6f，旧限价: %.6f", pair, data.Price, oldPrice)
 	} else {
-		data.Price = price
+		level.apply(&data)
 		resultMsg = fmt.Sprintf("🟢 %s 做空监听，限价: %.6f", pair, data.Price)
 	}
+	resultMsg += formatPriceExpr(data)
+	if level.ATR > 0 {
+		resultMsg += fmt.Sprintf("，ATR: %.6f", level.ATR)
+	}
+	resultMsg += tg.applyMonitorArgs(&data, ShortDirect, args)
 
 	if err := tg.RedisController.SetMonitorPair(data, ShortDirect); err != nil {
 		resultMsg = fmt.Sprintf("设置 %s 做空监听失败: %v", pair, err)
@@ -41,30 +63,50 @@ func (tg *TgController) handleShortCommand(pair string, price float64) string {
 }
 
 // 处理 /long 命令
-func (tg *TgController) handleLongCommand(pair string, price float64) string {
-	data, _ := tg.RedisController.GetMonitorPair(pair, LongDirect)
-	data.Pair = pair
+func (tg *TgController) handleLongCommand(pair string, priceExpr string, args MonitorArgs) string {
+	if args.Legs != "" && !model.IsSyntheticPair(pair) {
+		return "❌ leg= 只适用于合成交易对，如 ETH/BTC"
+	}
+	if args.Rearm && model.IsSyntheticPair(pair) {
+		return "❌ 合成交易对暂不支持平仓后重新设置"
+	}
+	if err := tg.Feed.CheckSymbol(pair); err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	// 重新设置时从空白监控开始，之前的分批入场、OCO 等参数不保留，旧限价只用于提示
+	oldData, _ := tg.RedisController.GetMonitorPair(pair, LongDirect)
+	data := model.PairMonitorData{Pair: pair}
 
 	// 获取当前交易对的最新价格
-	dataPair := tg.RedisController.GetPairPrice(data.Pair)
+	dataPair := tg.pairPrice(data.Pair)
 	// 计算中间价作为当前价格
 	currentPrice := (dataPair.BidPrice + dataPair.AskPrice) / 2
 	if currentPrice <= 0 {
 		return fmt.Sprintf("❌ 无法获取 %s 的最新价格，请检查交易对是否存在", pair)
 	}
+	level, err := tg.resolvePriceLevel(pair, priceExpr, currentPrice, args)
+	if err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	price := level.Price
 	if currentPrice < price {
 		return fmt.Sprintf("❌ 当前价格 %.6f 小于设置的限价 %.6f，请调整限价", currentPrice, price)
 	}
 
 	resultMsg := ""
-	if data.Price > 0 {
-		oldPrice := data.Price
-		data.Price = price
+	if oldData.Price > 0 {
+		oldPrice := oldData.Price
+		level.apply(&data)
 		resultMsg = fmt.Sprintf("🟢 %s 做多监听，新限价: %.6f，旧限价: %.6f", pair, data.Price, oldPrice)
 	} else {
-		data.Price = price
+		level.apply(&data)
 		resultMsg = fmt.Sprintf("🟢 %s 做多监听，限价: %.6f", pair, data.Price)
 	}
+	resultMsg += formatPriceExpr(data)
+	if level.ATR > 0 {
+		resultMsg += fmt.Sprintf("，ATR: %.6f", level.ATR)
+	}
+	resultMsg += tg.applyMonitorArgs(&data, LongDirect, args)
 	if err := tg.RedisController.SetMonitorPair(data, LongDirect); err != nil {
 		resultMsg = fmt.Sprintf("设置 %s 做多监听失败: %v", pair, err)
 	} else {
@@ -73,6 +115,405 @@ func (tg *TgController) handleLongCommand(pair string, price float64) string {
 	return resultMsg
 }
 
+// 处理 /ts /tl 命令（追踪入场）
+func (tg *TgController) handleTrailingCommand(pair, direct string, price, percent float64, args MonitorArgs) string {
+	if percent <= 0 || percent >= 100 {
+		return "❌ 回撤百分比必须在 0 到 100 之间"
+	}
+	if err := tg.Feed.CheckSymbol(pair); err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	price = tg.Feed.RoundPrice(pair, price)
+
+	dataPair := tg.pairPrice(pair)
+	currentPrice := (dataPair.BidPrice + dataPair.AskPrice) / 2
+	if currentPrice <= 0 {
+		return fmt.Sprintf("❌ 无法获取 %s 的最新价格，请检查交易对是否存在", pair)
+	}
+
+	directName := "做多"
+	if direct == ShortDirect {
+		directName = "做空"
+		if currentPrice > price {
+			return fmt.Sprintf("❌ 当前价格 %.6f 大于设置的激活价 %.6f，请调整激活价", currentPrice, price)
+		}
+	} else if currentPrice < price {
+		return fmt.Sprintf("❌ 当前价格 %.6f 小于设置的激活价 %.6f，请调整激活价", currentPrice, price)
+	}
+
+	data := model.PairMonitorData{
+		Pair:         pair,
+		Price:        price,
+		TrailPercent: percent,
+	}
+	resultMsg := fmt.Sprintf("🟢 %s %s追踪监听，激活价: %.6f，回撤: %.2f%%", pair, directName, price, percent)
+	resultMsg += tg.applyMonitorArgs(&data, direct, args)
+	if err := tg.RedisController.SetMonitorPair(data, direct); err != nil {
+		return fmt.Sprintf("设置 %s %s追踪监听失败: %v", pair, directName, err)
+	}
+	resultMsg += fmt.Sprintf(", 当前价格: %.6f", currentPrice)
+	return resultMsg
+}
+
+// handleLadderArgs 解析 /s /l 的分批入场参数
+func (tg *TgController) handleLadderArgs(pair, direct string, args []string) string {
+	rungs, rest, err := ParseLadderArgs(args)
+	if err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	monitorArgs, err := ParseMonitorArgs(rest)
+	if err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	return tg.handleLadderCommand(pair, direct, rungs, monitorArgs)
+}
+
+// 处理分批入场命令，第一档开仓，之后的档位对同一仓位加仓
+func (tg *TgController) handleLadderCommand(pair, direct string, rungs []model.LadderRung, args MonitorArgs) string {
+	if len(rungs) == 0 {
+		return "❌ 至少需要一个档位"
+	}
+	if args.ConfirmTicks > 0 || args.ConfirmSeconds > 0 || args.CandleClose != "" {
+		return "❌ 分批入场暂不支持确认窗口和K线收盘触发"
+	}
+	if model.IsSyntheticPair(pair) {
+		return "❌ 合成交易对暂不支持分批入场"
+	}
+	if args.Rearm {
+		return "❌ 分批入场暂不支持平仓后重新设置"
+	}
+	if err := tg.Feed.CheckSymbol(pair); err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	for i := range rungs {
+		rungs[i].Price = tg.Feed.RoundPrice(pair, rungs[i].Price)
+	}
+
+	dataPair := tg.pairPrice(pair)
+	currentPrice := (dataPair.BidPrice + dataPair.AskPrice) / 2
+	if currentPrice <= 0 {
+		return fmt.Sprintf("❌ 无法获取 %s 的最新价格，请检查交易对是否存在", pair)
+	}
+
+	directName := "做多"
+	if direct == ShortDirect {
+		directName = "做空"
+	}
+	for _, rung := range rungs {
+		if direct == ShortDirect && currentPrice > rung.Price {
+			return fmt.Sprintf("❌ 当前价格 %.6f 大于档位价格 %.6f，请调整档位", currentPrice, rung.Price)
+		}
+		if direct == LongDirect && currentPrice < rung.Price {
+			return fmt.Sprintf("❌ 当前价格 %.6f 小于档位价格 %.6f，请调整档位", currentPrice, rung.Price)
+		}
+	}
+
+	// 档位按离当前价格由近到远排列：做多从高到低，做空从低到高
+	sort.Slice(rungs, func(i, j int) bool {
+		if direct == ShortDirect {
+			return rungs[i].Price < rungs[j].Price
+		}
+		return rungs[i].Price > rungs[j].Price
+	})
+	for i := 1; i < len(rungs); i++ {
+		if rungs[i].Price == rungs[i-1].Price {
+			return fmt.Sprintf("❌ 档位价格 %.6f 重复", rungs[i].Price)
+		}
+	}
+
+	data := model.PairMonitorData{
+		Pair:   pair,
+		Price:  rungs[0].Price,
+		Ladder: rungs,
+	}
+	total := 0.0
+	for _, rung := range rungs {
+		total += rung.StakeAmount
+	}
+	resultMsg := fmt.Sprintf("🟢 %s %s分批监听，%d档，总金额: %.2f", pair, directName, len(rungs), total)
+	resultMsg += tg.applyMonitorArgs(&data, direct, args)
+	if err := tg.RedisController.SetMonitorPair(data, direct); err != nil {
+		return fmt.Sprintf("设置 %s %s分批监听失败: %v", pair, directName, err)
+	}
+	resultMsg += fmt.Sprintf(", 当前价格: %.6f\n", currentPrice)
+	resultMsg += formatLadder(data)
+	return resultMsg
+}
+
+// 处理 /oco 命令，同时设置做多和做空监听，一方的交易提交成功后自动取消另一方
+func (tg *TgController) handleOcoCommand(pair string, longPrice, shortPrice float64, args MonitorArgs) string {
+	if err := tg.Feed.CheckSymbol(pair); err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	longPrice = tg.Feed.RoundPrice(pair, longPrice)
+	shortPrice = tg.Feed.RoundPrice(pair, shortPrice)
+	if longPrice >= shortPrice {
+		return fmt.Sprintf("❌ 做多限价 %.6f 必须小于做空限价 %.6f", longPrice, shortPrice)
+	}
+
+	dataPair := tg.pairPrice(pair)
+	currentPrice := (dataPair.BidPrice + dataPair.AskPrice) / 2
+	if currentPrice <= 0 {
+		return fmt.Sprintf("❌ 无法获取 %s 的最新价格，请检查交易对是否存在", pair)
+	}
+	if currentPrice < longPrice || currentPrice > shortPrice {
+		return fmt.Sprintf("❌ 当前价格 %.6f 不在做多限价 %.6f 和做空限价 %.6f 之间，请调整限价", currentPrice, longPrice, shortPrice)
+	}
+
+	longData := model.PairMonitorData{Pair: pair, Price: longPrice, OCO: ShortDirect}
+	shortData := model.PairMonitorData{Pair: pair, Price: shortPrice, OCO: LongDirect}
+	longDesc := tg.applyMonitorArgs(&longData, LongDirect, args)
+	shortDesc := tg.applyMonitorArgs(&shortData, ShortDirect, args)
+
+	if err := tg.RedisController.SetMonitorPair(longData, LongDirect); err != nil {
+		return fmt.Sprintf("设置 %s 做多监听失败: %v", pair, err)
+	}
+	if err := tg.RedisController.SetMonitorPair(shortData, ShortDirect); err != nil {
+		tg.RedisController.DeleteMonitorPair(pair, LongDirect)
+		return fmt.Sprintf("设置 %s 做空监听失败: %v", pair, err)
+	}
+
+	resultMsg := fmt.Sprintf("🔗 %s OCO 监听，一方的交易提交成功后自动取消另一方，当前价格: %.6f\n", pair, currentPrice)
+	resultMsg += fmt.Sprintf("🟢 做多限价: %.6f%s\n", longPrice, longDesc)
+	resultMsg += fmt.Sprintf("🟢 做空限价: %.6f%s", shortPrice, shortDesc)
+	return resultMsg
+}
+
+// 处理 /tp /sl 命令，为已开仓的交易设置止盈/止损离场监控
+func (tg *TgController) handleExitCommand(pair, direct string, price, percent float64, args MonitorArgs) string {
+	if percent < 0 || percent > 100 {
+		return "❌ 平仓比例必须在 0 到 100 之间"
+	}
+	if err := tg.Feed.CheckSymbol(pair); err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	price = tg.Feed.RoundPrice(pair, price)
+	name := "止损"
+	if direct == TakeProfitDirect {
+		name = "止盈"
+	}
+
+	trade, exists := tg.FreqtradeController.FindOpenTrade(pair)
+	if !exists {
+		return fmt.Sprintf("❌ %s 没有开仓", pair)
+	}
+	side := LongDirect
+	if trade.IsShort {
+		side = ShortDirect
+	}
+
+	dataPair := tg.pairPrice(pair)
+	currentPrice := (dataPair.BidPrice + dataPair.AskPrice) / 2
+	if currentPrice <= 0 {
+		return fmt.Sprintf("❌ 无法获取 %s 的最新价格，请检查交易对是否存在", pair)
+	}
+	// 多头止盈在上方、止损在下方，空头相反
+	above := (direct == TakeProfitDirect) == (side == LongDirect)
+	if above && currentPrice >= price {
+		return fmt.Sprintf("❌ 当前价格 %.6f 已高于%s价 %.6f，请调整价格", currentPrice, name, price)
+	}
+	if !above && currentPrice <= price {
+		return fmt.Sprintf("❌ 当前价格 %.6f 已低于%s价 %.6f，请调整价格", currentPrice, name, price)
+	}
+
+	data := model.PairMonitorData{
+		Pair:        pair,
+		Price:       price,
+		TradeId:     trade.TradeId,
+		Side:        side,
+		ExitPercent: percent,
+	}
+	resultMsg := fmt.Sprintf("🟢 %s %s监听，交易ID: %d，仓位方向: %s，%s价: %.6f，平仓比例: %s",
+		pair, name, trade.TradeId, side, name, price, FormatExitPercent(percent))
+	resultMsg += tg.applyMonitorArgs(&data, direct, args)
+	if err := tg.RedisController.SetMonitorPair(data, direct); err != nil {
+		return fmt.Sprintf("设置 %s %s监听失败: %v", pair, name, err)
+	}
+	resultMsg += fmt.Sprintf(", 当前价格: %.6f", currentPrice)
+	return resultMsg
+}
+
+// 处理 /adl 命令，价格到达时对已有仓位加仓
+func (tg *TgController) handleADLCommand(pair string, price, stakeAmount float64, args MonitorArgs) string {
+	if stakeAmount <= 0 {
+		return "❌ 加仓金额必须大于0"
+	}
+	if err := tg.Feed.CheckSymbol(pair); err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	price = tg.Feed.RoundPrice(pair, price)
+
+	trade, exists := tg.FreqtradeController.FindOpenTrade(pair)
+	if !exists {
+		return fmt.Sprintf("❌ %s 没有持仓", pair)
+	}
+	side := LongDirect
+	directName := "做多"
+	if trade.IsShort {
+		side = ShortDirect
+		directName = "做空"
+	}
+
+	dataPair := tg.pairPrice(pair)
+	currentPrice := (dataPair.BidPrice + dataPair.AskPrice) / 2
+	if currentPrice <= 0 {
+		return fmt.Sprintf("❌ 无法获取 %s 的最新价格，请检查交易对是否存在", pair)
+	}
+	if side == ShortDirect && currentPrice > price {
+		return fmt.Sprintf("❌ 当前价格 %.6f 大于设置的加仓价 %.6f，请调整加仓价", currentPrice, price)
+	}
+	if side == LongDirect && currentPrice < price {
+		return fmt.Sprintf("❌ 当前价格 %.6f 小于设置的加仓价 %.6f，请调整加仓价", currentPrice, price)
+	}
+
+	data := model.PairMonitorData{
+		Pair:        pair,
+		Price:       price,
+		TradeId:     trade.TradeId,
+		Side:        side,
+		StakeAmount: stakeAmount,
+	}
+	resultMsg := fmt.Sprintf("🟢 %s %s加仓监听，交易ID: %d，加仓价: %.6f，金额: %.2f",
+		pair, directName, trade.TradeId, price, stakeAmount)
+	resultMsg += tg.applyMonitorArgs(&data, AddDirect, args)
+	if err := tg.RedisController.SetMonitorPair(data, AddDirect); err != nil {
+		return fmt.Sprintf("设置 %s 加仓监听失败: %v", pair, err)
+	}
+	resultMsg += fmt.Sprintf(", 当前价格: %.6f", currentPrice)
+	return resultMsg
+}
+
+// 处理 /alert 命令，只发送提醒不交易。提醒价高于当前价时为上穿提醒，否则为下穿提醒，
+// 同时记录当前价格所在的一侧，价格越过提醒价才提醒
+func (tg *TgController) handleAlertCommand(pair string, price float64, repeatSeconds int, args MonitorArgs) string {
+	if err := tg.Feed.CheckSymbol(pair); err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	dataPair := tg.pairPrice(pair)
+	currentPrice := (dataPair.BidPrice + dataPair.AskPrice) / 2
+	if currentPrice <= 0 {
+		return fmt.Sprintf("❌ 无法获取 %s 的最新价格，请检查交易对是否存在", pair)
+	}
+	if price == currentPrice {
+		return fmt.Sprintf("❌ 提醒价不能等于当前价格 %.6f", currentPrice)
+	}
+
+	direct := AlertUpDirect
+	action := "上穿"
+	if price < currentPrice {
+		direct = AlertDownDirect
+		action = "下穿"
+	}
+
+	data := model.PairMonitorData{
+		Pair:          pair,
+		Price:         price,
+		RepeatSeconds: repeatSeconds,
+		PriceSide:     model.PriceSideOf(currentPrice, price),
+	}
+	resultMsg := fmt.Sprintf("🔔 %s 价格%s提醒，提醒价: %.6f", pair, action, price)
+	if repeatSeconds > 0 {
+		resultMsg += fmt.Sprintf("，每 %d 秒重复提醒", repeatSeconds)
+	}
+	resultMsg += tg.applyMonitorArgs(&data, direct, args)
+	if err := tg.RedisController.SetMonitorPair(data, direct); err != nil {
+		return fmt.Sprintf("设置 %s 价格提醒失败: %v", pair, err)
+	}
+	resultMsg += fmt.Sprintf(", 当前价格: %.6f", currentPrice)
+	return resultMsg
+}
+
+// 处理 /risk 命令，显示风控限制和熔断状态
+func (tg *TgController) handleRiskCommand() string {
+	risk := tg.FreqtradeController.RiskConfig()
+	resultMsg := "🛡 风控限制（0 表示不限制）:\n"
+	resultMsg += fmt.Sprintf("最大总投入: %.2f\n", risk.MaxTotalStake)
+	resultMsg += fmt.Sprintf("每小时最多开仓: %d\n", risk.MaxEntriesPerHour)
+	resultMsg += fmt.Sprintf("同方向最多持仓: %d\n", risk.MaxSameDirection)
+	resultMsg += fmt.Sprintf("当日亏损上限: %.2f\n", risk.DailyLossLimit)
+
+	breaker, tripped, err := tg.FreqtradeController.CircuitBreaker()
+	switch {
+	case err != nil:
+		resultMsg += fmt.Sprintf("❌ 读取熔断状态失败: %v", err)
+	case tripped:
+		resultMsg += fmt.Sprintf("🚨 熔断中: %s (%s)\n使用 /risk reset 恢复自动开仓",
+			breaker.Reason, time.Unix(breaker.Time, 0).Format("01-02 15:04:05"))
+	default:
+		resultMsg += "✅ 未熔断"
+	}
+	return resultMsg
+}
+
+// 处理 /risk reset 命令，手动重置风控熔断
+func (tg *TgController) handleRiskResetCommand() string {
+	if err := tg.FreqtradeController.ResetCircuitBreaker(); err != nil {
+		return fmt.Sprintf("❌ 重置风控熔断失败: %v", err)
+	}
+	log.Println("风控熔断已手动重置")
+	return "✅ 风控熔断已重置，恢复自动开仓"
+}
+
+// 处理 /pause 命令，暂停自动交易，所有共享Redis的实例都会停止提交入场交易
+func (tg *TgController) handlePauseCommand() string {
+	if err := tg.RedisController.Pause("telegram", time.Now().Unix()); err != nil {
+		return fmt.Sprintf("❌ 暂停自动交易失败: %v", err)
+	}
+	log.Println("⏸ 自动交易已通过 Telegram 暂停")
+	return "⏸ 自动交易已暂停，价格照常更新，入场和加仓监控不会触发\n使用 /resume 恢复"
+}
+
+// 处理 /resume 命令，恢复自动交易
+func (tg *TgController) handleResumeCommand() string {
+	if err := tg.RedisController.Resume(); err != nil {
+		return fmt.Sprintf("❌ 恢复自动交易失败: %v", err)
+	}
+	log.Println("▶️ 自动交易已通过 Telegram 恢复")
+	return "▶️ 自动交易已恢复"
+}
+
+// formatATRLevel 显示 ATR 限价当前的 ATR 和有效限价，固定限价返回空字符串
+func (tg *TgController) formatATRLevel(data model.PairMonitorData) string {
+	if data.ATRMultiple == 0 {
+		return ""
+	}
+	atr, ok := tg.RedisController.GetATR(data.Pair, data.ATRTimeframe)
+	if !ok {
+		return fmt.Sprintf("ATR(%s): K线不足，沿用限价 %.6f\n", data.ATRTimeframe, data.Price)
+	}
+	return fmt.Sprintf("ATR(%s): %.6f，有效限价: %.6f\n", data.ATRTimeframe, atr, data.ATRLevel(atr))
+}
+
+// formatRearmTimer 显示等待重新设置的监控及冷却剩余时间，没有时返回空字符串
+func (tg *TgController) formatRearmTimer(pair, direct string) string {
+	timer, exists, err := tg.RedisController.GetRearmTimer(pair, direct)
+	if err != nil {
+		return fmt.Sprintf("❌ 读取 %s 重新设置计时失败: %v\n", direct, err)
+	}
+	if !exists {
+		return ""
+	}
+	if timer.ExitTime == 0 {
+		return fmt.Sprintf("🔁 %s 等待交易 %d 平仓后重新设置，限价: %.6f，冷却: %s\n",
+			direct, timer.TradeId, timer.Monitor.Price, time.Duration(timer.Monitor.RearmCooldown)*time.Second)
+	}
+	remaining := time.Until(time.Unix(timer.ReadyTime(), 0)).Truncate(time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return fmt.Sprintf("🔁 %s 冷却中，%s 后重新设置，限价: %.6f\n", direct, remaining, timer.Monitor.Price)
+}
+
+// formatPauseState 暂停状态的描述，未暂停时返回空字符串
+func (tg *TgController) formatPauseState() string {
+	state, paused := tg.RedisController.PauseState()
+	if !paused {
+		return ""
+	}
+	return fmt.Sprintf("⏸ 自动交易已暂停 (%s, %s)\n",
+		state.Source, time.Unix(state.Time, 0).Format("01-02 15:04:05"))
+}
+
 // 处理 /cancel 命令
 func (tg *TgController) handleCancelCommand(pair string, direct string) string {
 	resultMsg := ""
@@ -83,17 +524,66 @@ func (tg *TgController) handleCancelCommand(pair string, direct string) string {
 	case ShortDirect:
 		resultMsg = fmt.Sprintf("✅ %s 做空监听已取消", pair)
 		log.Printf("取消 %s 做空监听", pair)
+	case TakeProfitDirect:
+		resultMsg = fmt.Sprintf("✅ %s 止盈监听已取消", pair)
+		log.Printf("取消 %s 止盈监听", pair)
+	case StopLossDirect:
+		resultMsg = fmt.Sprintf("✅ %s 止损监听已取消", pair)
+		log.Printf("取消 %s 止损监听", pair)
+	case AddDirect:
+		resultMsg = fmt.Sprintf("✅ %s 加仓监听已取消", pair)
+		log.Printf("取消 %s 加仓监听", pair)
+	case AlertUpDirect, AlertDownDirect:
+		resultMsg = fmt.Sprintf("✅ %s 价格提醒(%s)已取消", pair, direct)
+		log.Printf("取消 %s 价格提醒 %s", pair, direct)
 	default:
-		resultMsg = fmt.Sprintf("❌ 无效的方向: %s。请使用 'long' 或 'short'", direct)
+		resultMsg = fmt.Sprintf("❌ 无效的方向: %s。请使用 'long'、'short'、'tp'、'sl'、'adl'、'alert_up' 或 'alert_down'", direct)
 		log.Printf("无效的方向: %s", direct)
 		return resultMsg
 	}
 	tg.RedisController.DeleteMonitorPair(pair, direct)
+	if (direct == LongDirect || direct == ShortDirect) && tg.RedisController.DeleteRearmTimer(pair, direct) {
+		resultMsg += "，等待中的重新设置也已取消"
+	}
 	return resultMsg
 }
 
+// 处理 /cancel [pair] [direction] [price] 命令，取消分批入场中的单个档位
+func (tg *TgController) handleCancelRungCommand(pair, direct string, price float64) string {
+	data, exists := tg.RedisController.GetMonitorPair(pair, direct)
+	if !exists || len(data.Ladder) == 0 {
+		return fmt.Sprintf("❌ %s 没有 %s 分批监听", pair, direct)
+	}
+
+	index := -1
+	for i := range data.Ladder {
+		if data.Ladder[i].Price == price {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Sprintf("❌ %s 没有价格为 %.6f 的档位", pair, price)
+	}
+	if !tg.RedisController.TransitionRungState(pair, direct, index, model.MonitorStateCancelled, model.MonitorStateArmed) {
+		return fmt.Sprintf("❌ %s 第%d档当前状态为 %s，无法取消", pair, index+1, data.Ladder[index].State)
+	}
+	log.Printf("取消 %s %s 第%d档", pair, direct, index+1)
+
+	// 没有等待触发的档位时删除整个监听
+	data, _ = tg.RedisController.GetMonitorPair(pair, direct)
+	for i := range data.Ladder {
+		switch data.Ladder[i].State {
+		case "", model.MonitorStateArmed, model.MonitorStateTriggered, model.MonitorStateSubmitted:
+			return fmt.Sprintf("✅ %s %s 第%d档（%.6f）已取消", pair, direct, index+1, price)
+		}
+	}
+	tg.RedisController.DeleteMonitorPair(pair, direct)
+	return fmt.Sprintf("✅ %s %s 第%d档（%.6f）已取消，没有剩余档位，监听已删除", pair, direct, index+1, price)
+}
+
 func (tg *TgController) handleShowConfigCommand() string {
-	resultMsg := ""
+	resultMsg := tg.formatPauseState()
 	// 查找所有交易对
 	pairsData, err := tg.RedisController.GetAllPairPricesData()
 	if err != nil {
@@ -191,19 +681,118 @@ func (tg *TgController) handleShowConfigCommand() string {
 }
 
 func (tg *TgController) handleShowCommand(pair string) string {
-	resultMsg := ""
+	resultMsg := tg.formatPauseState()
 	// 查找所有交易对
-	pairsData := tg.RedisController.GetPairPrice(pair)
+	pairsData := tg.pairPrice(pair)
 
 	// 查看被监听的交易对
 	monitorLongData, _ := tg.RedisController.GetMonitorPair(pair, LongDirect)
 	monitorShortData, _ := tg.RedisController.GetMonitorPair(pair, ShortDirect)
 
 	if monitorLongData.Price > 0 {
-		resultMsg += fmt.Sprintf("%s 做多监听，限价: %.6f\n", pair, monitorLongData.Price)
+		resultMsg += fmt.Sprintf("%s 做多监听，限价: %.6f%s\n", pair, monitorLongData.Price, formatPriceExpr(monitorLongData))
+		resultMsg += fmt.Sprintf("状态: %s\n", formatMonitorState(monitorLongData))
+		resultMsg += tg.formatATRLevel(monitorLongData)
+		if monitorLongData.Rearm {
+			resultMsg += fmt.Sprintf("平仓冷却 %s 后重新设置\n", time.Duration(monitorLongData.RearmCooldown)*time.Second)
+		}
+		if model.IsSyntheticPair(pair) {
+			resultMsg += fmt.Sprintf("开仓: %s\n", formatSyntheticLegs(monitorLongData, LongDirect))
+		}
+		if monitorLongData.OCO != "" {
+			resultMsg += fmt.Sprintf("OCO: 触发后取消 %s 监听\n", monitorLongData.OCO)
+		}
+		if confirm := formatConfirm(monitorLongData); confirm != "" {
+			progress := tg.RedisController.GetConfirmProgress(pair, LongDirect)
+			resultMsg += fmt.Sprintf("确认: %s，进度: %s\n", confirm, formatConfirmProgress(monitorLongData, progress))
+		}
+		if monitorLongData.CandleClose != "" {
+			resultMsg += fmt.Sprintf("按 %s K线收盘触发\n", monitorLongData.CandleClose)
+		}
+		if monitorLongData.TrailPercent > 0 {
+			resultMsg += formatTrailing(monitorLongData)
+		}
+		if len(monitorLongData.Ladder) > 0 {
+			resultMsg += formatLadder(monitorLongData)
+		}
+		if monitorLongData.Condition != nil {
+			resultMsg += fmt.Sprintf("条件: %s\n", FormatCondition(*monitorLongData.Condition))
+		}
 	}
 	if monitorShortData.Price > 0 {
-		resultMsg += fmt.Sprintf("%s 做空监听，限价: %.6f\n", pair, monitorShortData.Price)
+		resultMsg += fmt.Sprintf("%s 做空监听，限价: %.6f%s\n", pair, monitorShortData.Price, formatPriceExpr(monitorShortData))
+		resultMsg += fmt.Sprintf("状态: %s\n", formatMonitorState(monitorShortData))
+		resultMsg += tg.formatATRLevel(monitorShortData)
+		if monitorShortData.Rearm {
+			resultMsg += fmt.Sprintf("平仓冷却 %s 后重新设置\n", time.Duration(monitorShortData.RearmCooldown)*time.Second)
+		}
+		if model.IsSyntheticPair(pair) {
+			resultMsg += fmt.Sprintf("开仓: %s\n", formatSyntheticLegs(monitorShortData, ShortDirect))
+		}
+		if monitorShortData.OCO != "" {
+			resultMsg += fmt.Sprintf("OCO: 触发后取消 %s 监听\n", monitorShortData.OCO)
+		}
+		if confirm := formatConfirm(monitorShortData); confirm != "" {
+			progress := tg.RedisController.GetConfirmProgress(pair, ShortDirect)
+			resultMsg += fmt.Sprintf("确认: %s，进度: %s\n", confirm, formatConfirmProgress(monitorShortData, progress))
+		}
+		if monitorShortData.CandleClose != "" {
+			resultMsg += fmt.Sprintf("按 %s K线收盘触发\n", monitorShortData.CandleClose)
+		}
+		if monitorShortData.TrailPercent > 0 {
+			resultMsg += formatTrailing(monitorShortData)
+		}
+		if len(monitorShortData.Ladder) > 0 {
+			resultMsg += formatLadder(monitorShortData)
+		}
+		if monitorShortData.Condition != nil {
+			resultMsg += fmt.Sprintf("条件: %s\n", FormatCondition(*monitorShortData.Condition))
+		}
+	}
+	for _, direct := range []string{LongDirect, ShortDirect} {
+		resultMsg += tg.formatRearmTimer(pair, direct)
+	}
+	for _, direct := range []string{TakeProfitDirect, StopLossDirect} {
+		exitData, exists := tg.RedisController.GetMonitorPair(pair, direct)
+		if !exists || exitData.Price <= 0 {
+			continue
+		}
+		name := "止损"
+		if direct == TakeProfitDirect {
+			name = "止盈"
+		}
+		resultMsg += fmt.Sprintf("%s %s监听，交易ID: %d，%s价: %.6f，平仓比例: %s\n",
+			pair, name, exitData.TradeId, name, exitData.Price, FormatExitPercent(exitData.ExitPercent))
+		resultMsg += fmt.Sprintf("状态: %s\n", formatMonitorState(exitData))
+		if exitData.Condition != nil {
+			resultMsg += fmt.Sprintf("条件: %s\n", FormatCondition(*exitData.Condition))
+		}
+	}
+	if addData, exists := tg.RedisController.GetMonitorPair(pair, AddDirect); exists && addData.Price > 0 {
+		resultMsg += fmt.Sprintf("%s 加仓监听，交易ID: %d，加仓价: %.6f，金额: %.2f\n",
+			pair, addData.TradeId, addData.Price, addData.StakeAmount)
+		resultMsg += fmt.Sprintf("状态: %s\n", formatMonitorState(addData))
+		if addData.Condition != nil {
+			resultMsg += fmt.Sprintf("条件: %s\n", FormatCondition(*addData.Condition))
+		}
+	}
+	for _, direct := range []string{AlertUpDirect, AlertDownDirect} {
+		alertData, exists := tg.RedisController.GetMonitorPair(pair, direct)
+		if !exists || alertData.Price <= 0 {
+			continue
+		}
+		action := "上穿"
+		if direct == AlertDownDirect {
+			action = "下穿"
+		}
+		resultMsg += fmt.Sprintf("%s 价格%s提醒，提醒价: %.6f", pair, action, alertData.Price)
+		if alertData.RepeatSeconds > 0 {
+			resultMsg += fmt.Sprintf("，每 %d 秒重复", alertData.RepeatSeconds)
+		}
+		resultMsg += fmt.Sprintf("\n状态: %s\n", formatMonitorState(alertData))
+		if alertData.Condition != nil {
+			resultMsg += fmt.Sprintf("条件: %s\n", FormatCondition(*alertData.Condition))
+		}
 	}
 	// 计算中间价作为当前价格
 	currentPrice := (pairsData.BidPrice + pairsData.AskPrice) / 2
@@ -211,6 +800,55 @@ func (tg *TgController) handleShowCommand(pair string) string {
 	return resultMsg
 }
 
+// formatMonitorState 显示监控的生命周期状态
+func formatMonitorState(data model.PairMonitorData) string {
+	state := data.State
+	if state == "" {
+		state = model.MonitorStateArmed
+	}
+	if data.StateTime > 0 {
+		return fmt.Sprintf("%s (%s)", state, time.Unix(data.StateTime, 0).Format("01-02 15:04:05"))
+	}
+	return state
+}
+
+// formatConfirmProgress 显示确认窗口的当前进度
+func formatConfirmProgress(data model.PairMonitorData, progress model.ConfirmProgress) string {
+	if progress.Ticks == 0 {
+		return "未开始"
+	}
+	elapsed := time.Since(time.UnixMilli(progress.Since)).Seconds()
+	parts := []string{}
+	if data.ConfirmTicks > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d次", progress.Ticks, data.ConfirmTicks))
+	}
+	if data.ConfirmSeconds > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f/%d秒", elapsed, data.ConfirmSeconds))
+	}
+	return strings.Join(parts, "，")
+}
+
+// formatLadder 显示分批入场各档位的状态
+func formatLadder(data model.PairMonitorData) string {
+	resultMsg := ""
+	for i, rung := range data.Ladder {
+		state := rung.State
+		if state == "" {
+			state = model.MonitorStateArmed
+		}
+		resultMsg += fmt.Sprintf("第%d档: %.6f，金额: %.2f，状态: %s\n", i+1, rung.Price, rung.StakeAmount, state)
+	}
+	return resultMsg
+}
+
+// formatTrailing 显示追踪入场的状态
+func formatTrailing(data model.PairMonitorData) string {
+	if data.TrailExtreme <= 0 {
+		return fmt.Sprintf("追踪回撤: %.2f%%，未激活\n", data.TrailPercent)
+	}
+	return fmt.Sprintf("追踪回撤: %.2f%%，已激活，极值: %.6f\n", data.TrailPercent, data.TrailExtreme)
+}
+
 func (tg *TgController) handleWhiteList() string {
 	resultMsg := ""
 	// 查找所有交易对
@@ -230,14 +868,14 @@ func (tg *TgController) handleWhiteList() string {
 // 处理 /ad 命令
 func (tg *TgController) handleADCommand(pair string, stakeAmount float64, price float64) string {
 	// 获取当前交易对的最新价格
-	dataPair := tg.RedisController.GetPairPrice(pair)
+	dataPair := tg.pairPrice(pair)
 	if dataPair.BidPrice <= 0 || dataPair.AskPrice <= 0 {
 		return fmt.Sprintf("❌ 无法获取 %s 的最新价格，请检查交易对是否存在", pair)
 	}
 
 	hasTrade := false
 	isShort := false
-	tradeStatus := tg.FreqtradeController.TradeStatus
+	tradeStatus := tg.FreqtradeController.GetTradeStatus()
 	for i := range tradeStatus {
 		if tradeStatus[i].Pair == pair {
 			hasTrade = true
@@ -282,7 +920,7 @@ func (tg *TgController) handleADCommand(pair string, stakeAmount float64, price
 // 处理 /pc 命令（平仓）
 func (tg *TgController) handlePCCommand(pair string, amount float64) string {
 	// 获取当前交易状态
-	tradeStatus := tg.FreqtradeController.TradeStatus
+	tradeStatus := tg.FreqtradeController.GetTradeStatus()
 
 	var targetTrade *model.TradePosition
 	for i := range tradeStatus {
@@ -335,7 +973,10 @@ func (tg *TgController) handleShowPositionsCommand() string {
 	resultMsg := ""
 
 	// 获取 Freqtrade 实际交易状态
-	tradeStatus := tg.FreqtradeController.TradeStatus
+	tradeStatus := tg.FreqtradeController.GetTradeStatus()
+	if tg.FreqtradeController.IsPaper() {
+		resultMsg += fmt.Sprintf("🧪 模拟盘，已实现盈亏: %.2f\n", tg.FreqtradeController.PaperRealizedProfit())
+	}
 
 	if len(tradeStatus) == 0 {
 		resultMsg += "无仓位\n"
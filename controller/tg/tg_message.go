@@ -21,6 +21,11 @@ func (tg *TgController) SendWelcomeWithButtons() error {
 }
 
 func (tg *TgController) SendMessage(msg string) {
+	if tg == nil || tg.Bot == nil {
+		// 没有配置 Telegram（如回测）时只记录日志
+		log.Printf("Telegram 消息: %s", msg)
+		return
+	}
 	tgMsg := tgbotapi.NewMessage(tg.TgId, msg)
 	if _, err := tg.Bot.Send(tgMsg); err != nil {
 		log.Printf("发送消息到 Telegram 失败: %v", err)
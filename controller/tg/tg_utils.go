@@ -2,13 +2,483 @@ package tg
 
 import (
 	"fmt"
+	"monitor-trade/model"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// HandlePair 将命令中的交易对解析为 Freqtrade 交易对，如 pepe -> 1000PEPE/USDT:USDT
 func (tg *TgController) HandlePair(pair string) string {
-	pair = strings.ToUpper(pair)
-	if strings.HasSuffix(pair, "/USDT:USDT") {
-		return pair
+	return tg.Feed.ResolvePair(pair)
+}
+
+// MonitorArgs 监控命令价格之后的附加参数
+type MonitorArgs struct {
+	Conditions     []model.Condition
+	ConfirmTicks   int    // 连续满足条件的推送次数
+	ConfirmSeconds int    // 持续满足条件的秒数
+	CandleClose    string // 按K线收盘价触发的周期
+	Legs           string // 合成交易对触发后开仓的腿
+	ATRTimeframe   string // ATR 限价使用的K线周期
+	Rearm          bool   // 平仓后自动重新设置监控
+	RearmCooldown  int    // 重新设置前的冷却秒数
+}
+
+// ParseMonitorArgs 解析监控命令的附加参数：
+// confirm=3 表示连续 3 次推送满足条件，confirm=30s 表示持续 30 秒满足条件，
+// close=5m 表示按 5 分钟K线收盘价判断，leg=base 表示合成交易对只开分子，
+// atr=1h 表示 ATR 限价使用 1 小时K线，rearm=30m 表示平仓冷却 30 分钟后重新设置监控，其余参数按条件解析
+func ParseMonitorArgs(args []string) (MonitorArgs, error) {
+	var monitorArgs MonitorArgs
+	var conditionArgs []string
+	for _, arg := range args {
+		arg = strings.ToLower(strings.TrimSpace(arg))
+		if strings.HasPrefix(arg, "close=") {
+			timeframe := strings.TrimPrefix(arg, "close=")
+			if _, ok := model.TimeframeDuration(timeframe); !ok {
+				return MonitorArgs{}, fmt.Errorf("不支持的K线周期: %s，可选: %s", timeframe, strings.Join(model.CandleTimeframes, " "))
+			}
+			monitorArgs.CandleClose = timeframe
+			continue
+		}
+		if strings.HasPrefix(arg, "atr=") {
+			timeframe := strings.TrimPrefix(arg, "atr=")
+			if _, ok := model.TimeframeDuration(timeframe); !ok {
+				return MonitorArgs{}, fmt.Errorf("不支持的K线周期: %s，可选: %s", timeframe, strings.Join(model.CandleTimeframes, " "))
+			}
+			monitorArgs.ATRTimeframe = timeframe
+			continue
+		}
+		if arg == "rearm" || strings.HasPrefix(arg, "rearm=") {
+			monitorArgs.Rearm = true
+			if value := strings.TrimPrefix(arg, "rearm"); value != "" {
+				duration, err := time.ParseDuration(strings.TrimPrefix(value, "="))
+				if err != nil || duration < 0 {
+					return MonitorArgs{}, fmt.Errorf("无效的重新设置参数: %s，示例: rearm=30m", arg)
+				}
+				monitorArgs.RearmCooldown = int(duration.Seconds())
+			}
+			continue
+		}
+		if strings.HasPrefix(arg, "leg=") {
+			legs := strings.TrimPrefix(arg, "leg=")
+			switch legs {
+			case model.SyntheticLegBase, model.SyntheticLegQuote, model.SyntheticLegBoth:
+				monitorArgs.Legs = legs
+			default:
+				return MonitorArgs{}, fmt.Errorf("无效的腿参数: %s，可选: leg=base leg=quote leg=both", arg)
+			}
+			continue
+		}
+		if !strings.HasPrefix(arg, "confirm=") {
+			conditionArgs = append(conditionArgs, arg)
+			continue
+		}
+
+		value := strings.TrimPrefix(arg, "confirm=")
+		if ticks, err := strconv.Atoi(value); err == nil && ticks > 0 {
+			monitorArgs.ConfirmTicks = ticks
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration < time.Second {
+			return MonitorArgs{}, fmt.Errorf("无效的确认参数: %s，示例: confirm=3 或 confirm=30s", arg)
+		}
+		monitorArgs.ConfirmSeconds = int(duration.Seconds())
+	}
+
+	conditions, err := ParseConditionArgs(conditionArgs)
+	if err != nil {
+		return MonitorArgs{}, err
+	}
+	monitorArgs.Conditions = conditions
+	return monitorArgs, nil
+}
+
+// applyMonitorArgs 将附加参数写入监控数据，返回用于回复的描述
+func (tg *TgController) applyMonitorArgs(data *model.PairMonitorData, direct string, args MonitorArgs) string {
+	data.Condition = tg.buildMonitorCondition(data.Pair, direct, args.Conditions)
+	data.ConfirmTicks = args.ConfirmTicks
+	data.ConfirmSeconds = args.ConfirmSeconds
+	data.CandleClose = args.CandleClose
+	data.Legs = ""
+	data.Rearm = false
+	data.RearmCooldown = 0
+
+	desc := ""
+	if model.IsSyntheticPair(data.Pair) && (direct == LongDirect || direct == ShortDirect) {
+		data.Legs = args.Legs
+		desc += fmt.Sprintf("，开仓: %s", formatSyntheticLegs(*data, direct))
+	}
+	if args.Rearm && (direct == LongDirect || direct == ShortDirect) {
+		data.Rearm = true
+		data.RearmCooldown = args.RearmCooldown
+		desc += fmt.Sprintf("，平仓冷却 %s 后重新设置", time.Duration(data.RearmCooldown)*time.Second)
+	}
+	if data.Condition != nil {
+		desc += fmt.Sprintf("，条件: %s", FormatCondition(*data.Condition))
+	}
+	if data.CandleClose != "" {
+		desc += fmt.Sprintf("，按 %s K线收盘触发", data.CandleClose)
+	}
+	if confirm := formatConfirm(*data); confirm != "" {
+		desc += fmt.Sprintf("，确认: %s", confirm)
+	}
+	return desc
+}
+
+// ParseLadderArgs 解析分批入场档位参数，格式为 价格:金额，例如 60000:50 59000:100。
+// 返回档位以及剩余的附加参数
+func ParseLadderArgs(args []string) ([]model.LadderRung, []string, error) {
+	var rungs []model.LadderRung
+	for i, arg := range args {
+		arg = strings.TrimSpace(arg)
+		if !strings.Contains(arg, ":") {
+			return rungs, args[i:], nil
+		}
+		priceStr, stakeStr, _ := strings.Cut(arg, ":")
+		price, err1 := strconv.ParseFloat(priceStr, 64)
+		stake, err2 := strconv.ParseFloat(stakeStr, 64)
+		if err1 != nil || err2 != nil || price <= 0 || stake <= 0 {
+			return nil, nil, fmt.Errorf("无效的档位参数: %s，示例: 60000:50", arg)
+		}
+		rungs = append(rungs, model.LadderRung{Price: price, StakeAmount: stake})
+	}
+	return rungs, nil, nil
+}
+
+// ParseRepeatArg 从附加参数中取出提醒的重复间隔，例如 repeat=10m，返回间隔秒数和剩余参数
+func ParseRepeatArg(args []string) (int, []string, error) {
+	repeatSeconds := 0
+	var rest []string
+	for _, arg := range args {
+		if !strings.HasPrefix(strings.ToLower(arg), "repeat=") {
+			rest = append(rest, arg)
+			continue
+		}
+		duration, err := time.ParseDuration(strings.TrimPrefix(strings.ToLower(arg), "repeat="))
+		if err != nil || duration < time.Second {
+			return 0, nil, fmt.Errorf("无效的重复间隔: %s，示例: repeat=10m", arg)
+		}
+		repeatSeconds = int(duration.Seconds())
+	}
+	return repeatSeconds, rest, nil
+}
+
+// ParsePriceExpr 解析限价参数，支持绝对价格 60000、相对百分比 -3%/+1.5% 和绝对偏移 -0.8，
+// 相对价格以 current 为基准，返回解析后的限价以及是否为相对价格
+func ParsePriceExpr(expr string, current float64) (float64, bool, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "+") && !strings.HasPrefix(expr, "-") {
+		price, err := strconv.ParseFloat(expr, 64)
+		if err != nil || price <= 0 {
+			return 0, false, fmt.Errorf("价格必须是有效的数字")
+		}
+		return price, false, nil
+	}
+
+	if current <= 0 {
+		return 0, true, fmt.Errorf("无法获取当前价格，不能使用相对价格 %s", expr)
+	}
+	var price float64
+	if strings.HasSuffix(expr, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(expr, "%"), 64)
+		if err != nil {
+			return 0, true, fmt.Errorf("无效的相对价格: %s，示例: -3%%", expr)
+		}
+		price = current * (1 + percent/100)
+	} else {
+		offset, err := strconv.ParseFloat(expr, 64)
+		if err != nil {
+			return 0, true, fmt.Errorf("无效的相对价格: %s，示例: -0.8", expr)
+		}
+		price = current + offset
+	}
+	if price <= 0 {
+		return 0, true, fmt.Errorf("相对价格 %s 解析后的限价 %.6f 无效", expr, price)
+	}
+	return price, true, nil
+}
+
+// ParseATRExpr 解析 ATR 限价，如 -2atr 表示比基准价低 2 倍 ATR，返回带符号的倍数
+func ParseATRExpr(expr string) (float64, bool) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	if !strings.HasSuffix(expr, "atr") || (!strings.HasPrefix(expr, "+") && !strings.HasPrefix(expr, "-")) {
+		return 0, false
+	}
+	multiple, err := strconv.ParseFloat(strings.TrimSuffix(expr, "atr"), 64)
+	if err != nil || multiple == 0 {
+		return 0, false
+	}
+	return multiple, true
+}
+
+// priceLevel 解析后的限价及其来源
+type priceLevel struct {
+	Price        float64
+	Expr         string  // 相对价格的原始输入，绝对价格为空
+	Ref          float64 // 相对价格的基准中间价
+	ATRMultiple  float64
+	ATRTimeframe string
+	ATR          float64 // 创建时的 ATR
+}
+
+// apply 将限价及其来源写入监控数据
+func (l priceLevel) apply(data *model.PairMonitorData) {
+	data.Price = l.Price
+	data.PriceExpr = l.Expr
+	data.PriceRef = l.Ref
+	data.ATRMultiple = l.ATRMultiple
+	data.ATRTimeframe = l.ATRTimeframe
+}
+
+// resolvePriceLevel 按当前价格解析 /s /l 的限价参数，支持绝对价格、相对价格和 ATR 倍数，限价按 tickSize 取整
+func (tg *TgController) resolvePriceLevel(pair, expr string, current float64, args MonitorArgs) (priceLevel, error) {
+	multiple, isATR := ParseATRExpr(expr)
+	if !isATR {
+		if args.ATRTimeframe != "" {
+			return priceLevel{}, fmt.Errorf("atr= 只适用于 ATR 限价，如 -2atr")
+		}
+		price, relative, err := ParsePriceExpr(expr, current)
+		if err != nil {
+			return priceLevel{}, err
+		}
+		price = tg.Feed.RoundPrice(pair, price)
+		if !relative {
+			return priceLevel{Price: price}, nil
+		}
+		return priceLevel{Price: price, Expr: expr, Ref: current}, nil
+	}
+
+	timeframe := args.ATRTimeframe
+	if timeframe == "" {
+		timeframe = model.DefaultATRTimeframe
+	}
+	atr, err := tg.loadATR(pair, timeframe)
+	if err != nil {
+		return priceLevel{}, err
+	}
+	price := tg.Feed.RoundPrice(pair, current+multiple*atr)
+	if price <= 0 {
+		return priceLevel{}, fmt.Errorf("ATR 限价 %s 解析后的限价 %.6f 无效", expr, price)
+	}
+	return priceLevel{
+		Price:        price,
+		Expr:         strings.ToLower(expr),
+		Ref:          current,
+		ATRMultiple:  multiple,
+		ATRTimeframe: timeframe,
+		ATR:          atr,
+	}, nil
+}
+
+// loadATR 获取交易对的 ATR，本地K线不足时从行情源拉取历史K线
+func (tg *TgController) loadATR(pair, timeframe string) (float64, error) {
+	if atr, ok := tg.RedisController.GetATR(pair, timeframe); ok {
+		return atr, nil
+	}
+	if model.IsSyntheticPair(pair) {
+		return 0, fmt.Errorf("%s 本地 %s K线不足 %d 根，暂时无法计算 ATR", pair, timeframe, model.ATRPeriod+1)
+	}
+	if err := tg.Feed.LoadCandles(pair, timeframe); err != nil {
+		return 0, fmt.Errorf("获取 %s 历史K线失败: %v", pair, err)
+	}
+	atr, ok := tg.RedisController.GetATR(pair, timeframe)
+	if !ok {
+		return 0, fmt.Errorf("%s %s K线不足 %d 根，无法计算 ATR", pair, timeframe, model.ATRPeriod+1)
+	}
+	return atr, nil
+}
+
+// pairPrice 获取交易对最新价格，行情推送只订阅监听列表和监控中的交易对，其他交易对通过 REST 接口获取
+func (tg *TgController) pairPrice(pair string) model.PairData {
+	return tg.Feed.PairPrice(pair)
+}
+
+// formatPriceExpr 显示相对价格的来源，绝对价格返回空字符串
+func formatPriceExpr(data model.PairMonitorData) string {
+	if data.PriceExpr == "" {
+		return ""
+	}
+	if data.ATRMultiple != 0 {
+		return fmt.Sprintf("（基准 %.6f %s，ATR 周期: %s）", data.PriceRef, data.PriceExpr, data.ATRTimeframe)
+	}
+	return fmt.Sprintf("（当前价 %s，基准: %.6f）", data.PriceExpr, data.PriceRef)
+}
+
+// formatSyntheticLegs 显示合成交易对触发后开仓的腿，如 ETH/USDT:USDT long + BTC/USDT:USDT short
+func formatSyntheticLegs(data model.PairMonitorData, direct string) string {
+	data.Direct = direct
+	var legs []string
+	for _, leg := range data.SyntheticLegs() {
+		legs = append(legs, fmt.Sprintf("%s %s", leg.Pair, leg.Side))
+	}
+	return strings.Join(legs, " + ")
+}
+
+// FormatExitPercent 显示离场监控的平仓比例
+func FormatExitPercent(percent float64) string {
+	if percent <= 0 || percent >= 100 {
+		return "全部"
+	}
+	return fmt.Sprintf("%.2f%%", percent)
+}
+
+// formatConfirm 显示确认窗口设置
+func formatConfirm(data model.PairMonitorData) string {
+	var parts []string
+	if data.ConfirmTicks > 0 {
+		parts = append(parts, fmt.Sprintf("连续%d次", data.ConfirmTicks))
+	}
+	if data.ConfirmSeconds > 0 {
+		parts = append(parts, fmt.Sprintf("持续%d秒", data.ConfirmSeconds))
+	}
+	return strings.Join(parts, "且")
+}
+
+// ParseConditionArgs 解析命令中附加的条件参数，多个参数之间为"且"关系，
+// 单个参数内用 | 分隔表示"或"，如 funding>0.01 spread<0.05|cross>65000
+func ParseConditionArgs(args []string) ([]model.Condition, error) {
+	var conditions []model.Condition
+	for _, arg := range args {
+		arg = strings.TrimSpace(arg)
+		if arg == "" {
+			continue
+		}
+
+		parts := strings.Split(arg, "|")
+		var group []model.Condition
+		for _, part := range parts {
+			cond, err := parseConditionArg(part)
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, cond)
+		}
+
+		if len(group) == 1 {
+			conditions = append(conditions, group[0])
+		} else {
+			conditions = append(conditions, model.Condition{Type: model.ConditionOr, Conditions: group})
+		}
+	}
+	return conditions, nil
+}
+
+// parseConditionArg 解析单个条件参数，支持:
+// funding>X funding<X spread<X cross>X cross<X pct>X@REF pct<X@REF
+func parseConditionArg(arg string) (model.Condition, error) {
+	arg = strings.ToLower(strings.TrimSpace(arg))
+	op := strings.IndexAny(arg, "<>")
+	if op <= 0 || op == len(arg)-1 {
+		return model.Condition{}, fmt.Errorf("无效的条件: %s", arg)
+	}
+	name, above, valueStr := arg[:op], arg[op] == '>', arg[op+1:]
+
+	reference := 0.0
+	if name == "pct" {
+		at := strings.Index(valueStr, "@")
+		if at <= 0 {
+			return model.Condition{}, fmt.Errorf("百分比条件需要参考价，如 pct>2@60000")
+		}
+		ref, err := strconv.ParseFloat(valueStr[at+1:], 64)
+		if err != nil || ref <= 0 {
+			return model.Condition{}, fmt.Errorf("无效的参考价: %s", valueStr[at+1:])
+		}
+		reference = ref
+		valueStr = valueStr[:at]
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSuffix(valueStr, "%"), 64)
+	if err != nil {
+		return model.Condition{}, fmt.Errorf("无效的条件数值: %s", arg)
+	}
+
+	switch {
+	case name == "funding" && above:
+		return model.Condition{Type: model.ConditionFundingAbove, Value: value}, nil
+	case name == "funding":
+		return model.Condition{Type: model.ConditionFundingBelow, Value: value}, nil
+	case name == "spread" && !above:
+		return model.Condition{Type: model.ConditionSpreadBelow, Value: value}, nil
+	case name == "cross" && above:
+		return model.Condition{Type: model.ConditionCrossUp, Value: value}, nil
+	case name == "cross":
+		return model.Condition{Type: model.ConditionCrossDown, Value: value}, nil
+	case name == "pct" && above:
+		return model.Condition{Type: model.ConditionPercentAbove, Value: value, Reference: reference}, nil
+	case name == "pct":
+		return model.Condition{Type: model.ConditionPercentBelow, Value: value, Reference: reference}, nil
+	}
+	return model.Condition{}, fmt.Errorf("不支持的条件: %s", arg)
+}
+
+// buildMonitorCondition 将附加条件与限价条件组合；没有附加条件时返回 nil 使用默认规则
+func (tg *TgController) buildMonitorCondition(pair, direct string, extra []model.Condition) *model.Condition {
+	if len(extra) == 0 {
+		return nil
+	}
+
+	conditions := []model.Condition{{Type: model.ConditionLevel}}
+	if direct == ShortDirect && !hasFundingCondition(extra) && !model.IsSyntheticPair(pair) {
+		// 做空默认要求资金费率高于阈值，合成交易对没有资金费率
+		conditions = append(conditions, model.Condition{Type: model.ConditionFundingAbove, Value: tg.Conf.FundingRate})
+	}
+	conditions = append(conditions, extra...)
+	return &model.Condition{Type: model.ConditionAnd, Conditions: conditions}
+}
+
+func hasFundingCondition(conditions []model.Condition) bool {
+	for i := range conditions {
+		switch conditions[i].Type {
+		case model.ConditionFundingAbove, model.ConditionFundingBelow:
+			return true
+		}
+		if hasFundingCondition(conditions[i].Conditions) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatCondition 将条件转换为可读文本
+func FormatCondition(cond model.Condition) string {
+	switch cond.Type {
+	case model.ConditionLevel:
+		return "限价"
+	case model.ConditionPriceAbove:
+		return fmt.Sprintf("ask>%g", cond.Value)
+	case model.ConditionPriceBelow:
+		return fmt.Sprintf("bid<%g", cond.Value)
+	case model.ConditionCrossUp:
+		return fmt.Sprintf("上穿%g", cond.Value)
+	case model.ConditionCrossDown:
+		return fmt.Sprintf("下穿%g", cond.Value)
+	case model.ConditionPercentAbove:
+		return fmt.Sprintf("高于%g %g%%", cond.Reference, cond.Value)
+	case model.ConditionPercentBelow:
+		return fmt.Sprintf("低于%g %g%%", cond.Reference, cond.Value)
+	case model.ConditionSpreadBelow:
+		return fmt.Sprintf("spread<%g%%", cond.Value)
+	case model.ConditionFundingAbove:
+		return fmt.Sprintf("funding>%g%%", cond.Value)
+	case model.ConditionFundingBelow:
+		return fmt.Sprintf("funding<%g%%", cond.Value)
+	case model.ConditionAnd, model.ConditionOr:
+		sep := " 且 "
+		if cond.Type == model.ConditionOr {
+			sep = " 或 "
+		}
+		parts := make([]string, 0, len(cond.Conditions))
+		for i := range cond.Conditions {
+			part := FormatCondition(cond.Conditions[i])
+			if len(cond.Conditions[i].Conditions) > 0 {
+				part = "(" + part + ")"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, sep)
 	}
-	return fmt.Sprintf("%s/USDT:USDT", pair)
+	return cond.Type
 }
@@ -0,0 +1,74 @@
+package tg
+
+import (
+	"math"
+	"testing"
+)
+
+// TestParsePriceExpr 测试解析绝对价格、相对百分比和相对偏移，以及无效输入
+func TestParsePriceExpr(t *testing.T) {
+	tests := []struct {
+		expr     string
+		current  float64
+		expected float64
+		relative bool
+		wantErr  bool
+	}{
+		{"60000", 0, 60000, false, false},
+		{" 0.0512 ", 100, 0.0512, false, false},
+		{"+3%", 100, 103, true, false},
+		{"-2%", 100, 98, true, false},
+		{"+1.5%", 60000, 60900, true, false},
+		{"-0.8", 10, 9.2, true, false},
+		{"+1.5", 10, 11.5, true, false},
+		{"0", 100, 0, false, true},      // 限价必须大于 0
+		{"0%", 100, 0, false, true},     // 百分比必须带符号
+		{"-100%", 100, 0, true, true},   // 解析后的限价为 0
+		{"-20", 10, 0, true, true},      // 偏移后为负数
+		{"+3%", 0, 0, true, true},       // 没有当前价格时不能使用相对价格
+		{"abc", 100, 0, false, true},    // 不是数字
+		{"+x%", 100, 0, true, true},     // 百分比不是数字
+		{"-2atr", 100, 0, true, true},   // ATR 倍数由 ParseATRExpr 解析
+		{"60000%", 100, 0, false, true}, // 绝对价格不能带百分号
+	}
+	for _, tt := range tests {
+		price, relative, err := ParsePriceExpr(tt.expr, tt.current)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParsePriceExpr(%q, %v) 应返回错误，实际 %v", tt.expr, tt.current, price)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePriceExpr(%q, %v) 不应返回错误: %v", tt.expr, tt.current, err)
+			continue
+		}
+		if math.Abs(price-tt.expected) > 1e-9 || relative != tt.relative {
+			t.Errorf("ParsePriceExpr(%q, %v) = %v, %v，期望 %v, %v", tt.expr, tt.current, price, relative, tt.expected, tt.relative)
+		}
+	}
+}
+
+// TestParseATRExpr 测试解析 ATR 倍数，必须带符号且倍数不为 0
+func TestParseATRExpr(t *testing.T) {
+	tests := []struct {
+		expr     string
+		multiple float64
+		ok       bool
+	}{
+		{"-2atr", -2, true},
+		{"+1.5ATR", 1.5, true},
+		{" -0.5atr ", -0.5, true},
+		{"2atr", 0, false},  // 没有符号
+		{"-0atr", 0, false}, // 倍数为 0
+		{"-xatr", 0, false},
+		{"-2%", 0, false},
+		{"60000", 0, false},
+	}
+	for _, tt := range tests {
+		multiple, ok := ParseATRExpr(tt.expr)
+		if ok != tt.ok || multiple != tt.multiple {
+			t.Errorf("ParseATRExpr(%q) = %v, %v，期望 %v, %v", tt.expr, multiple, ok, tt.multiple, tt.ok)
+		}
+	}
+}
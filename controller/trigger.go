@@ -0,0 +1,344 @@
+package controller
+
+import (
+	"fmt"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+	"time"
+)
+
+// TriggerContext 单次条件评估所需的行情上下文
+type TriggerContext struct {
+	PairData    *model.PairData
+	LastPrice   float64                 // 上一次推送的中间价，0 表示未知
+	Level       float64                 // 监控的限价
+	Direct      string                  // 监控方向
+	Side        string                  // 离场监控对应仓位的方向
+	FundingRate func() (float64, error) // 资金费率获取函数，只在条件需要时调用
+	Now         time.Time               // 推送对应的时间，为空时使用当前时间
+	Candle      *model.Candle           // 监控K线周期最近一根已收盘的K线，仅收盘触发的监控使用
+
+	trailing       bool // 追踪入场时限价条件由回撤判断代替
+	trailFired     bool
+	fundingRate    float64
+	fundingFetched bool
+}
+
+// funding 获取资金费率，同一次评估内只请求一次
+func (tc *TriggerContext) funding() (float64, error) {
+	if tc.fundingFetched {
+		return tc.fundingRate, nil
+	}
+	if tc.FundingRate == nil {
+		return 0, fmt.Errorf("未配置资金费率数据源")
+	}
+	rate, err := tc.FundingRate()
+	if err != nil {
+		return 0, fmt.Errorf("获取资金费率失败: %v", err)
+	}
+	tc.fundingRate = rate
+	tc.fundingFetched = true
+	return rate, nil
+}
+
+// now 返回本次评估的时间
+func (tc *TriggerContext) now() time.Time {
+	if tc.Now.IsZero() {
+		return time.Now()
+	}
+	return tc.Now
+}
+
+// midPrice 计算中间价
+func midPrice(pairData *model.PairData) float64 {
+	return (pairData.BidPrice + pairData.AskPrice) / 2
+}
+
+// DefaultCondition 未设置自定义条件时的默认规则：
+// 做空要求卖一价高于限价且资金费率高于阈值，做多要求买一价低于限价
+func DefaultCondition(direct string, fundingThreshold float64) model.Condition {
+	if direct == tg.ShortDirect {
+		return model.Condition{
+			Type: model.ConditionAnd,
+			Conditions: []model.Condition{
+				{Type: model.ConditionLevel},
+				{Type: model.ConditionFundingAbove, Value: fundingThreshold},
+			},
+		}
+	}
+	return model.Condition{Type: model.ConditionLevel}
+}
+
+// MonitorCondition 返回监控实际使用的触发条件
+func MonitorCondition(data model.PairMonitorData, fundingThreshold float64) model.Condition {
+	if data.Condition != nil {
+		return *data.Condition
+	}
+	if model.IsSyntheticPair(data.Pair) {
+		// 合成交易对没有资金费率，只按比价判断
+		return model.Condition{Type: model.ConditionLevel}
+	}
+	return DefaultCondition(data.Direct, fundingThreshold)
+}
+
+// StepTrailing 推进追踪入场状态，返回是否触发。
+// 价格越过激活价后开始记录极值，从极值回撤 TrailPercent% 时触发
+func StepTrailing(data *model.PairMonitorData, pairData *model.PairData) bool {
+	if data.TrailPercent <= 0 || data.Price <= 0 {
+		return false
+	}
+
+	switch data.Direct {
+	case tg.ShortDirect:
+		price := pairData.AskPrice
+		if data.TrailExtreme <= 0 {
+			if price > data.Price {
+				data.TrailExtreme = price
+			}
+			return false
+		}
+		if price > data.TrailExtreme {
+			data.TrailExtreme = price
+			return false
+		}
+		return price <= data.TrailExtreme*(1-data.TrailPercent/100)
+	case tg.LongDirect:
+		price := pairData.BidPrice
+		if price <= 0 {
+			return false
+		}
+		if data.TrailExtreme <= 0 {
+			if price < data.Price {
+				data.TrailExtreme = price
+			}
+			return false
+		}
+		if price < data.TrailExtreme {
+			data.TrailExtreme = price
+			return false
+		}
+		return price >= data.TrailExtreme*(1+data.TrailPercent/100)
+	}
+	return false
+}
+
+// StepConfirm 推进确认窗口，met 为本次推送是否满足条件，返回是否完成确认。
+// 条件中断时进度清零；同时设置次数和时长时两者都需要满足
+func StepConfirm(data model.PairMonitorData, progress *model.ConfirmProgress, met bool, now time.Time) bool {
+	if data.ConfirmTicks <= 0 && data.ConfirmSeconds <= 0 {
+		return met
+	}
+	if !met {
+		*progress = model.ConfirmProgress{CandleTime: progress.CandleTime}
+		return false
+	}
+
+	if progress.Ticks == 0 {
+		progress.Since = now.UnixMilli()
+	}
+	progress.Ticks++
+
+	if data.ConfirmTicks > 0 && progress.Ticks < data.ConfirmTicks {
+		return false
+	}
+	if data.ConfirmSeconds > 0 && now.UnixMilli()-progress.Since < int64(data.ConfirmSeconds)*1000 {
+		return false
+	}
+	return true
+}
+
+// EvaluateMonitor 对单个监控执行一次完整的触发判断（K线收盘、条件、追踪入场、确认窗口），
+// 追踪入场的极值直接更新到 data 中，确认进度更新到 progress 中
+func EvaluateMonitor(data *model.PairMonitorData, progress *model.ConfirmProgress, tc *TriggerContext, fundingThreshold float64) (bool, error) {
+	tc.Level = data.Price
+	tc.Direct = data.Direct
+	tc.Side = data.Side
+
+	if data.CandleClose != "" {
+		// 每根K线收盘只评估一次，买卖价都取收盘价，穿越以开盘价为起点
+		if tc.Candle == nil || tc.Candle.OpenTime <= progress.CandleTime {
+			return false, nil
+		}
+		// 监控设置之前就已收盘的K线不参与判断
+		duration, _ := model.TimeframeDuration(data.CandleClose)
+		if tc.Candle.OpenTime+duration.Milliseconds() <= data.StateTime*1000 {
+			return false, nil
+		}
+		progress.CandleTime = tc.Candle.OpenTime
+		tc.PairData = &model.PairData{
+			Timestamp:   tc.PairData.Timestamp,
+			Pair:        tc.PairData.Pair,
+			BidPrice:    tc.Candle.Close,
+			AskPrice:    tc.Candle.Close,
+			Close:       tc.Candle.Close,
+			EventTime:   tc.PairData.EventTime,
+			ReceiveTime: tc.PairData.ReceiveTime,
+		}
+		tc.LastPrice = tc.Candle.Open
+	}
+	if data.TrailPercent > 0 {
+		tc.trailing = true
+		tc.trailFired = StepTrailing(data, tc.PairData)
+	}
+
+	met, err := EvaluateCondition(MonitorCondition(*data, fundingThreshold), tc)
+	if err != nil {
+		return false, err
+	}
+	return StepConfirm(*data, progress, met, tc.now()), nil
+}
+
+// NextLadderRung 返回本次推送应触发的分批入场档位序号，没有则返回 -1。
+// 档位按离当前价格由近到远排列，最近的待触发档位未满足时更远的档位也不会满足，每次最多触发一档
+func NextLadderRung(data model.PairMonitorData, tc *TriggerContext, fundingThreshold float64) (int, error) {
+	cond := MonitorCondition(data, fundingThreshold)
+	tc.Direct = data.Direct
+	for i := range data.Ladder {
+		if !data.Ladder[i].IsArmed() {
+			continue
+		}
+		tc.Level = data.Ladder[i].Price
+		met, err := EvaluateCondition(cond, tc)
+		if err != nil || !met {
+			return -1, err
+		}
+		return i, nil
+	}
+	return -1, nil
+}
+
+// LadderEntryPending 判断除 index 外是否还有已触发或已提交、尚未成交的档位，此时后续档位的加仓需要等待成交
+func LadderEntryPending(data model.PairMonitorData, index int) bool {
+	for i := range data.Ladder {
+		if i == index {
+			continue
+		}
+		switch data.Ladder[i].State {
+		case model.MonitorStateTriggered, model.MonitorStateSubmitted:
+			return true
+		}
+	}
+	return false
+}
+
+// LadderRungIsAdjust 判断档位是否应作为加仓提交：已有其他档位触发或成交时使用加仓，否则开仓
+func LadderRungIsAdjust(data model.PairMonitorData, index int) bool {
+	for i := range data.Ladder {
+		if i == index {
+			continue
+		}
+		switch data.Ladder[i].State {
+		case model.MonitorStateTriggered, model.MonitorStateSubmitted, model.MonitorStateFilled:
+			return true
+		}
+	}
+	return false
+}
+
+// exitLevelReached 判断离场监控是否到达限价：多头按买价判断，止盈涨到限价、止损跌到限价；空头按卖价反向判断
+func exitLevelReached(direct, side string, pairData *model.PairData, level float64) bool {
+	price := pairData.BidPrice
+	if side == tg.ShortDirect {
+		price = pairData.AskPrice
+	}
+	if price <= 0 {
+		return false
+	}
+	if (direct == tg.TakeProfitDirect) == (side == tg.ShortDirect) {
+		return price <= level
+	}
+	return price >= level
+}
+
+// EvaluateCondition 判断条件在当前行情下是否满足
+func EvaluateCondition(cond model.Condition, tc *TriggerContext) (bool, error) {
+	pairData := tc.PairData
+	mid := midPrice(pairData)
+
+	switch cond.Type {
+	case model.ConditionLevel:
+		if tc.trailing {
+			return tc.trailFired, nil
+		}
+		if tc.Level <= 0 {
+			return false, nil
+		}
+		switch tc.Direct {
+		case tg.ShortDirect:
+			return pairData.AskPrice > tc.Level, nil
+		case tg.LongDirect:
+			return pairData.BidPrice > 0 && pairData.BidPrice < tc.Level, nil
+		case tg.TakeProfitDirect, tg.StopLossDirect:
+			return exitLevelReached(tc.Direct, tc.Side, pairData, tc.Level), nil
+		case tg.AlertUpDirect:
+			return mid >= tc.Level, nil
+		case tg.AlertDownDirect:
+			return mid > 0 && mid <= tc.Level, nil
+		case tg.AddDirect:
+			// 加仓与同方向的入场判断方式相同
+			if tc.Side == tg.ShortDirect {
+				return pairData.AskPrice > tc.Level, nil
+			}
+			return pairData.BidPrice > 0 && pairData.BidPrice < tc.Level, nil
+		}
+		return false, nil
+	case model.ConditionPriceAbove:
+		return pairData.AskPrice > cond.Value, nil
+	case model.ConditionPriceBelow:
+		return pairData.BidPrice > 0 && pairData.BidPrice < cond.Value, nil
+	case model.ConditionCrossUp:
+		return tc.LastPrice > 0 && tc.LastPrice < cond.Value && mid >= cond.Value, nil
+	case model.ConditionCrossDown:
+		return tc.LastPrice > 0 && tc.LastPrice > cond.Value && mid <= cond.Value, nil
+	case model.ConditionPercentAbove:
+		if cond.Reference <= 0 {
+			return false, nil
+		}
+		return (mid-cond.Reference)/cond.Reference*100 >= cond.Value, nil
+	case model.ConditionPercentBelow:
+		if cond.Reference <= 0 {
+			return false, nil
+		}
+		return (cond.Reference-mid)/cond.Reference*100 >= cond.Value, nil
+	case model.ConditionSpreadBelow:
+		if mid <= 0 {
+			return false, nil
+		}
+		return (pairData.AskPrice-pairData.BidPrice)/mid*100 < cond.Value, nil
+	case model.ConditionFundingAbove:
+		rate, err := tc.funding()
+		if err != nil {
+			return false, err
+		}
+		return rate > cond.Value, nil
+	case model.ConditionFundingBelow:
+		rate, err := tc.funding()
+		if err != nil {
+			return false, err
+		}
+		return rate < cond.Value, nil
+	case model.ConditionAnd:
+		if len(cond.Conditions) == 0 {
+			return false, nil
+		}
+		for i := range cond.Conditions {
+			ok, err := EvaluateCondition(cond.Conditions[i], tc)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case model.ConditionOr:
+		for i := range cond.Conditions {
+			ok, err := EvaluateCondition(cond.Conditions[i], tc)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("未知的条件类型: %s", cond.Type)
+}
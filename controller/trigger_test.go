@@ -0,0 +1,337 @@
+package controller
+
+import (
+	"errors"
+	"monitor-trade/controller/tg"
+	"monitor-trade/model"
+	"testing"
+	"time"
+)
+
+// TestEvaluateDefaultCondition 测试默认规则与原有做多/做空逻辑一致
+func TestEvaluateDefaultCondition(t *testing.T) {
+	pairData := &model.PairData{Pair: "BTC/USDT:USDT", BidPrice: 64990, AskPrice: 65010}
+
+	testCases := []struct {
+		direct   string
+		level    float64
+		funding  float64
+		expected bool
+	}{
+		{tg.ShortDirect, 65000, 0.01, true},
+		{tg.ShortDirect, 65000, -0.2, false}, // 资金费率低于阈值
+		{tg.ShortDirect, 65100, 0.01, false},
+		{tg.LongDirect, 65000, 0, true},
+		{tg.LongDirect, 64900, 0, false},
+	}
+
+	for _, tc := range testCases {
+		funding := tc.funding
+		ctx := &TriggerContext{
+			PairData:    pairData,
+			Level:       tc.level,
+			Direct:      tc.direct,
+			FundingRate: func() (float64, error) { return funding, nil },
+		}
+		ok, err := EvaluateCondition(DefaultCondition(tc.direct, -0.1), ctx)
+		if err != nil {
+			t.Fatalf("评估失败: %v", err)
+		}
+		if ok != tc.expected {
+			t.Errorf("%s 限价 %.0f 资金费率 %.2f: 期望 %v，实际 %v", tc.direct, tc.level, tc.funding, tc.expected, ok)
+		}
+	}
+}
+
+// TestEvaluateCombinedCondition 测试组合条件
+func TestEvaluateCombinedCondition(t *testing.T) {
+	pairData := &model.PairData{Pair: "BTC/USDT:USDT", BidPrice: 65000, AskPrice: 65010}
+	fundingCalls := 0
+
+	cond := model.Condition{
+		Type: model.ConditionAnd,
+		Conditions: []model.Condition{
+			{Type: model.ConditionCrossUp, Value: 65000},
+			{Type: model.ConditionOr, Conditions: []model.Condition{
+				{Type: model.ConditionSpreadBelow, Value: 0.001},
+				{Type: model.ConditionFundingAbove, Value: 0.01},
+			}},
+		},
+	}
+
+	ctx := &TriggerContext{
+		PairData:  pairData,
+		LastPrice: 64980,
+		FundingRate: func() (float64, error) {
+			fundingCalls++
+			return 0.02, nil
+		},
+	}
+	ok, err := EvaluateCondition(cond, ctx)
+	if err != nil {
+		t.Fatalf("评估失败: %v", err)
+	}
+	if !ok {
+		t.Error("上穿且资金费率满足时应该触发")
+	}
+	if fundingCalls != 1 {
+		t.Errorf("期望请求资金费率 1 次，实际 %d 次", fundingCalls)
+	}
+
+	// 上一次价格已经在上方，不算穿越
+	ctx = &TriggerContext{PairData: pairData, LastPrice: 65001}
+	ok, _ = EvaluateCondition(cond, ctx)
+	if ok {
+		t.Error("没有发生穿越时不应该触发")
+	}
+}
+
+// TestEvaluateConditionFundingError 测试资金费率获取失败
+func TestEvaluateConditionFundingError(t *testing.T) {
+	ctx := &TriggerContext{
+		PairData:    &model.PairData{BidPrice: 100, AskPrice: 101},
+		FundingRate: func() (float64, error) { return 0, errors.New("timeout") },
+	}
+	_, err := EvaluateCondition(model.Condition{Type: model.ConditionFundingAbove}, ctx)
+	if err == nil {
+		t.Error("资金费率获取失败时应该返回错误")
+	}
+
+	_, err = EvaluateCondition(model.Condition{Type: "unknown"}, ctx)
+	if err == nil {
+		t.Error("未知条件类型应该返回错误")
+	}
+}
+
+// TestStepTrailingShort 测试做空追踪入场
+func TestStepTrailingShort(t *testing.T) {
+	data := &model.PairMonitorData{Pair: "BTC/USDT:USDT", Direct: tg.ShortDirect, Price: 65000, TrailPercent: 1}
+
+	steps := []struct {
+		ask      float64
+		fired    bool
+		expected float64 // 期望的极值
+	}{
+		{64900, false, 0},     // 未激活
+		{65100, false, 65100}, // 激活
+		{66000, false, 66000}, // 创新高
+		{65500, false, 66000}, // 回撤不足 1%
+		{65340, true, 66000},  // 回撤 1%
+	}
+
+	for i, step := range steps {
+		fired := StepTrailing(data, &model.PairData{BidPrice: step.ask - 10, AskPrice: step.ask})
+		if fired != step.fired {
+			t.Errorf("第 %d 步: 期望触发 %v，实际 %v", i, step.fired, fired)
+		}
+		if data.TrailExtreme != step.expected {
+			t.Errorf("第 %d 步: 期望极值 %.0f，实际 %.0f", i, step.expected, data.TrailExtreme)
+		}
+	}
+}
+
+// TestEvaluateMonitorTrailingLong 测试做多追踪入场与条件组合
+func TestEvaluateMonitorTrailingLong(t *testing.T) {
+	data := &model.PairMonitorData{
+		Pair:         "BTC/USDT:USDT",
+		Direct:       tg.LongDirect,
+		Price:        60000,
+		TrailPercent: 0.5,
+		TrailExtreme: 59000, // 重启后从Redis恢复的极值
+	}
+
+	// 低于限价但反弹不足，不应直接触发
+	tc := &TriggerContext{PairData: &model.PairData{BidPrice: 59200, AskPrice: 59210}}
+	fired, err := EvaluateMonitor(data, &model.ConfirmProgress{}, tc, -0.1)
+	if err != nil || fired {
+		t.Errorf("反弹不足时不应触发: fired=%v err=%v", fired, err)
+	}
+
+	tc = &TriggerContext{PairData: &model.PairData{BidPrice: 59295, AskPrice: 59300}}
+	fired, _ = EvaluateMonitor(data, &model.ConfirmProgress{}, tc, -0.1)
+	if !fired {
+		t.Error("从最低价反弹 0.5% 后应该触发")
+	}
+}
+
+// TestStepConfirm 测试确认窗口
+func TestStepConfirm(t *testing.T) {
+	data := model.PairMonitorData{ConfirmTicks: 3, ConfirmSeconds: 10}
+	progress := &model.ConfirmProgress{}
+	start := time.Unix(1700000000, 0)
+
+	if StepConfirm(data, progress, true, start) || StepConfirm(data, progress, true, start.Add(time.Second)) {
+		t.Error("次数不足时不应该确认")
+	}
+	// 单次插针后回落，进度清零
+	if StepConfirm(data, progress, false, start.Add(2*time.Second)) || progress.Ticks != 0 {
+		t.Errorf("条件中断后进度应清零，实际 %d", progress.Ticks)
+	}
+
+	for i := 0; i < 3; i++ {
+		if StepConfirm(data, progress, true, start.Add(time.Duration(3+i)*time.Second)) {
+			t.Error("次数满足但时长不足时不应该确认")
+		}
+	}
+	if !StepConfirm(data, progress, true, start.Add(13*time.Second)) {
+		t.Error("次数和时长都满足后应该确认")
+	}
+
+	// 未设置确认窗口时直接返回条件结果
+	if !StepConfirm(model.PairMonitorData{}, &model.ConfirmProgress{}, true, start) {
+		t.Error("未设置确认窗口时应直接触发")
+	}
+}
+
+// TestEvaluateMonitorCandleClose 测试按K线收盘触发
+func TestEvaluateMonitorCandleClose(t *testing.T) {
+	openTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := &model.PairMonitorData{
+		Pair:        "BTC/USDT:USDT",
+		Direct:      tg.LongDirect,
+		Price:       60000,
+		CandleClose: "1m",
+		StateTime:   openTime.Add(-time.Minute).Unix(),
+	}
+	progress := &model.ConfirmProgress{}
+	// 推送插针到限价以下，但K线收盘价仍在限价之上
+	tick := &model.PairData{Pair: data.Pair, BidPrice: 59000, AskPrice: 59010}
+
+	candle := &model.Candle{OpenTime: openTime.UnixMilli(), Open: 60200, Low: 59005, Close: 60100, Closed: true}
+	fired, _ := EvaluateMonitor(data, progress, &TriggerContext{PairData: tick, Candle: candle}, -0.1)
+	if fired {
+		t.Error("收盘价未越过限价时不应触发")
+	}
+
+	candle = &model.Candle{OpenTime: openTime.Add(time.Minute).UnixMilli(), Open: 60100, Close: 59900, Closed: true}
+	fired, _ = EvaluateMonitor(data, progress, &TriggerContext{PairData: tick, Candle: candle}, -0.1)
+	if !fired {
+		t.Error("收盘价越过限价时应该触发")
+	}
+
+	// 同一根K线不重复评估
+	fired, _ = EvaluateMonitor(data, progress, &TriggerContext{PairData: tick, Candle: candle}, -0.1)
+	if fired {
+		t.Error("同一根K线不应重复触发")
+	}
+}
+
+// TestNextLadderRung 测试分批入场档位选择
+func TestNextLadderRung(t *testing.T) {
+	data := model.PairMonitorData{
+		Pair:   "BTC/USDT:USDT",
+		Direct: tg.LongDirect,
+		Price:  60000,
+		Ladder: []model.LadderRung{
+			{Price: 60000, StakeAmount: 50},
+			{Price: 59000, StakeAmount: 100},
+			{Price: 58000, StakeAmount: 150},
+		},
+	}
+
+	tc := &TriggerContext{PairData: &model.PairData{BidPrice: 60500, AskPrice: 60510}}
+	if index, _ := NextLadderRung(data, tc, -0.1); index != -1 {
+		t.Errorf("价格未到第一档时不应触发，实际第 %d 档", index)
+	}
+
+	// 价格一次跌穿多档，每次只触发最近的一档
+	tc = &TriggerContext{PairData: &model.PairData{BidPrice: 57900, AskPrice: 57910}}
+	index, _ := NextLadderRung(data, tc, -0.1)
+	if index != 0 {
+		t.Errorf("期望触发第 0 档，实际第 %d 档", index)
+	}
+	if LadderRungIsAdjust(data, index) {
+		t.Error("第一个触发的档位应该开仓")
+	}
+
+	data.Ladder[0].State = model.MonitorStateSubmitted
+	index, _ = NextLadderRung(data, tc, -0.1)
+	if index != 1 {
+		t.Errorf("期望触发第 1 档，实际第 %d 档", index)
+	}
+	if !LadderRungIsAdjust(data, index) {
+		t.Error("已有档位提交后应该加仓")
+	}
+	if !LadderEntryPending(data, index) {
+		t.Error("之前的档位提交后未成交，加仓应该等待")
+	}
+	data.Ladder[0].State = model.MonitorStateFilled
+	if LadderEntryPending(data, index) || !LadderRungIsAdjust(data, index) {
+		t.Error("之前的档位成交后应该直接加仓")
+	}
+
+	// 已取消的档位跳过
+	data.Ladder[1].State = model.MonitorStateCancelled
+	if index, _ = NextLadderRung(data, tc, -0.1); index != 2 {
+		t.Errorf("期望跳过已取消档位触发第 2 档，实际第 %d 档", index)
+	}
+}
+
+// TestEvaluateExitMonitor 测试止盈/止损离场监控的触发方向
+func TestEvaluateExitMonitor(t *testing.T) {
+	pairData := &model.PairData{Pair: "BTC/USDT:USDT", BidPrice: 65000, AskPrice: 65010}
+
+	testCases := []struct {
+		direct   string
+		side     string
+		price    float64
+		expected bool
+	}{
+		{tg.TakeProfitDirect, tg.LongDirect, 64900, true},
+		{tg.TakeProfitDirect, tg.LongDirect, 65005, false}, // 只看买价
+		{tg.StopLossDirect, tg.LongDirect, 65000, true},
+		{tg.StopLossDirect, tg.LongDirect, 64900, false},
+		{tg.TakeProfitDirect, tg.ShortDirect, 65010, true},
+		{tg.TakeProfitDirect, tg.ShortDirect, 65005, false}, // 只看卖价
+		{tg.StopLossDirect, tg.ShortDirect, 65005, true},
+		{tg.StopLossDirect, tg.ShortDirect, 65100, false},
+	}
+
+	for _, tc := range testCases {
+		data := &model.PairMonitorData{Pair: pairData.Pair, Direct: tc.direct, Side: tc.side, Price: tc.price, TradeId: 1}
+		// 离场监控不受做空资金费率条件限制
+		ctx := &TriggerContext{
+			PairData:    pairData,
+			FundingRate: func() (float64, error) { return -1, nil },
+		}
+		fired, err := EvaluateMonitor(data, &model.ConfirmProgress{}, ctx, -0.1)
+		if err != nil {
+			t.Fatalf("评估失败: %v", err)
+		}
+		if fired != tc.expected {
+			t.Errorf("%s %s 限价 %.0f: 期望 %v，实际 %v", tc.side, tc.direct, tc.price, tc.expected, fired)
+		}
+	}
+}
+
+// TestEvaluateAddMonitor 测试加仓监控按仓位方向判断
+func TestEvaluateAddMonitor(t *testing.T) {
+	pairData := &model.PairData{Pair: "BTC/USDT:USDT", BidPrice: 57990, AskPrice: 58000}
+
+	long := &model.PairMonitorData{Pair: pairData.Pair, Direct: tg.AddDirect, Side: tg.LongDirect, Price: 58000, TradeId: 1}
+	fired, _ := EvaluateMonitor(long, &model.ConfirmProgress{}, &TriggerContext{PairData: pairData}, -0.1)
+	if !fired {
+		t.Error("多头买价低于加仓价时应该触发")
+	}
+
+	short := &model.PairMonitorData{Pair: pairData.Pair, Direct: tg.AddDirect, Side: tg.ShortDirect, Price: 58000, TradeId: 1}
+	fired, _ = EvaluateMonitor(short, &model.ConfirmProgress{}, &TriggerContext{PairData: pairData}, -0.1)
+	if fired {
+		t.Error("空头卖价未高于加仓价时不应触发")
+	}
+}
+
+// TestEvaluateAlertMonitor 测试价格提醒按中间价判断上穿/下穿
+func TestEvaluateAlertMonitor(t *testing.T) {
+	pairData := &model.PairData{Pair: "BTC/USDT:USDT", BidPrice: 69990, AskPrice: 70010}
+
+	up := &model.PairMonitorData{Pair: pairData.Pair, Direct: tg.AlertUpDirect, Price: 70000}
+	if fired, _ := EvaluateMonitor(up, &model.ConfirmProgress{}, &TriggerContext{PairData: pairData}, -0.1); !fired {
+		t.Error("中间价到达提醒价时上穿提醒应该触发")
+	}
+
+	down := &model.PairMonitorData{Pair: pairData.Pair, Direct: tg.AlertDownDirect, Price: 69995}
+	if fired, _ := EvaluateMonitor(down, &model.ConfirmProgress{}, &TriggerContext{PairData: pairData}, -0.1); fired {
+		t.Error("中间价高于提醒价时下穿提醒不应触发")
+	}
+}
@@ -6,18 +6,32 @@ import (
 	"monitor-trade/config"
 	"monitor-trade/controller"
 	"monitor-trade/controller/binance"
+	"monitor-trade/controller/bybit"
+	"monitor-trade/controller/feed"
 	"monitor-trade/controller/freqtrade"
 	"monitor-trade/controller/http"
 	"monitor-trade/controller/redis"
 	"monitor-trade/controller/tg"
 	"monitor-trade/model"
+	"os"
+	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		runBacktest(os.Args[2:])
+		return
+	}
+
 	// 创建主context用于优雅停止
 	ctx := context.Background()
 
 	conf := config.LoadFromEnv()
+	if conf.Record.ReplayFiles != "" {
+		// 回放模式离线运行，不连接 Redis、Telegram 和 Freqtrade
+		runReplay(conf)
+		return
+	}
 	redisController := redis.NewRedisController(conf)
 
 	// 启动时从Redis加载监控数据到本地
@@ -25,6 +39,13 @@ func main() {
 		log.Printf("加载Redis监控数据失败: %v", err)
 	}
 
+	// 读取自动交易暂停状态，重启后保持暂停
+	if err := redisController.LoadPauseState(); err != nil {
+		log.Printf("加载暂停状态失败: %v", err)
+	} else if redisController.IsPaused() {
+		log.Println("⏸ 自动交易处于暂停状态")
+	}
+
 	// 确保Redis keyspace事件已启用
 	if err := redisController.EnableRedisKeyspaceNotifications(); err != nil {
 		log.Printf("启用Redis keyspace事件失败: %v", err)
@@ -33,27 +54,69 @@ func main() {
 	// 启动Redis keyspace事件监听，自动同步本地数据
 	go redisController.StartRedisSync()
 
-	// 初始化Binance控制器
-	binanceController := binance.NewBinanceController()
-
-	// 设置 BinanceController 的 RedisController 引用
-	binanceController.SetRedisController(redisController)
-
 	// Tg 消息通知通道
 	messageChan := make(chan string, 1000)
+	staleTimeout := time.Duration(conf.FeedStaleSeconds) * time.Second
+
+	// 初始化行情数据源，行情停滞时通过 Telegram 告警
+	var priceFeed feed.PriceFeed
+	var binanceController *binance.BinanceController
+	var bybitController *bybit.BybitController
+	switch conf.Exchange {
+	case "bybit":
+		bybitController = bybit.NewBybitController()
+		bybitController.SetRedisController(redisController)
+		bybitController.SetMessageChan(messageChan)
+		bybitController.SetStaleTimeout(staleTimeout)
+		priceFeed = bybitController
+	default:
+		if conf.Exchange != "binance" {
+			log.Printf("不支持的行情数据源 %s，使用 binance", conf.Exchange)
+			conf.Exchange = "binance"
+		}
+		binanceController = binance.NewBinanceController()
+		binanceController.SetRedisController(redisController)
+		binanceController.SetMessageChan(messageChan)
+		binanceController.SetStaleTimeout(staleTimeout)
+		priceFeed = binanceController
+	}
+	log.Printf("行情数据源: %s", conf.Exchange)
+
 	tradeChan := make(chan model.ForceBuyPayload, 1000)
 	freqtradeController := freqtrade.NewFreqtradeController(conf.BotBaseUrl, conf.BotUsername, conf.BotPasswd, redisController)
+	freqtradeController.SetRiskConfig(conf.Risk)
+	if conf.Paper.Enabled {
+		// 模拟交易模式：交易请求由模拟执行器处理，不登录 Freqtrade，白名单取自 PAPER_WHITELIST
+		freqtradeController.SetPaperExecutor(freqtrade.NewPaperExecutor(redisController, conf.Paper))
+	}
 	freqtradeController.Init(messageChan)
 	go freqtradeController.HandleTradeChan(ctx, tradeChan)
 
-	// 使用Binance作为价格数据源的TgController
-	tgController := tg.NewTgController(conf.TelegramToken, conf.TelegramId, redisController, freqtradeController, conf)
+	tgController := tg.NewTgController(conf.TelegramToken, conf.TelegramId, redisController, freqtradeController, priceFeed, conf)
 	go tgController.SendMessageByChan(messageChan)
 	go tgController.HandleCommand()
 
-	mainController := controller.NewMainController(tgController, redisController, conf, binanceController, tradeChan)
-	// 使用Binance WebSocket监听价格变化
-	go binanceController.Watch(mainController.WatchKey)
+	mainController := controller.NewMainController(tgController, redisController, conf, priceFeed, freqtradeController, tradeChan)
+	// ATR 限价的监控需要历史K线
+	go mainController.LoadATRCandles()
+	// 加载合约交易规则，用于校验交易对和价格精度，每小时刷新
+	if bybitController != nil {
+		go bybitController.RefreshInstruments(time.Hour)
+		// 使用Bybit WebSocket监听价格变化，行情录制和回放只支持Binance
+		go bybitController.Watch(mainController.WatchKey)
+	} else {
+		go binanceController.RefreshExchangeInfo(time.Hour)
+		if conf.Record.Dir != "" {
+			recorder, err := binance.NewTickRecorder(conf.Record.Dir, time.Duration(conf.Record.RotateMinutes)*time.Minute)
+			if err != nil {
+				log.Printf("启用行情录制失败: %v", err)
+			} else {
+				binanceController.SetRecorder(recorder)
+			}
+		}
+		// 使用Binance WebSocket监听价格变化
+		go binanceController.Watch(mainController.WatchKey)
+	}
 	go mainController.Start()
 
 	httpHandler := http.NewHttpHandler(mainController, redisController, freqtradeController)
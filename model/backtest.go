@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// BacktestHorizons 回测统计触发后收益的时长
+var BacktestHorizons = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"4h", 4 * time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+// BacktestFire 回测中监控的一次触发
+type BacktestFire struct {
+	Pair    string             `json:"pair"`
+	Direct  string             `json:"direct"`
+	Rung    int                `json:"rung,omitempty"` // 分批入场的档位，从1开始
+	Level   float64            `json:"level"`          // 触发时的限价
+	Time    int64              `json:"time"`           // 触发时间（Unix毫秒）
+	Price   float64            `json:"price"`          // 提交的入场价格
+	Returns map[string]float64 `json:"returns"`        // 触发后各时长按方向计算的收益率(%)，历史数据不足时没有对应项
+}
+
+// BacktestReport 回测结果
+type BacktestReport struct {
+	Fires    []BacktestFire    `json:"fires"`
+	NotFired []PairMonitorData `json:"not_fired"` // 整个回测区间都没有触发的监控
+	Skipped  []PairMonitorData `json:"skipped"`   // 不支持回测的监控（非入场监控、合成交易对）或没有历史数据
+	Points   int               `json:"points"`    // 回放的价格点数量
+}
@@ -13,6 +13,60 @@ type BookTickerData struct {
 	AskQty          string `json:"A"` // 卖单最优挂单数量
 }
 
+// RecordedTicker 录制文件中的一行：本地接收时间(毫秒)和原始推送数据
+type RecordedTicker struct {
+	ReceiveTime int64          `json:"r"`
+	Ticker      BookTickerData `json:"d"`
+}
+
+// StreamRequest 订阅/取消订阅行情 stream 的控制消息
+type StreamRequest struct {
+	Method string   `json:"method"` // SUBSCRIBE / UNSUBSCRIBE
+	Params []string `json:"params"` // 如 btcusdt@bookTicker
+	ID     int64    `json:"id"`
+}
+
+// StreamResponse 控制消息的响应，成功时 result 为 null
+type StreamResponse struct {
+	ID    int64 `json:"id"`
+	Error *struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	} `json:"error"`
+}
+
+// ExchangeInfo 合约交易规则接口返回的数据结构
+type ExchangeInfo struct {
+	Symbols []ExchangeSymbol `json:"symbols"`
+}
+
+// ExchangeSymbol 单个合约的交易规则
+type ExchangeSymbol struct {
+	Symbol       string           `json:"symbol"`       // 交易对，如 BTCUSDT
+	ContractType string           `json:"contractType"` // 合约类型，永续为 PERPETUAL
+	Status       string           `json:"status"`       // 交易状态，如 TRADING、SETTLING
+	BaseAsset    string           `json:"baseAsset"`    // 标的资产
+	QuoteAsset   string           `json:"quoteAsset"`   // 报价资产
+	MarginAsset  string           `json:"marginAsset"`  // 保证金资产
+	Filters      []ExchangeFilter `json:"filters"`      // 价格、数量和名义价值限制
+}
+
+// ExchangeFilter 合约的交易限制，不同 filterType 使用不同字段
+type ExchangeFilter struct {
+	FilterType string `json:"filterType"` // PRICE_FILTER / LOT_SIZE / MIN_NOTIONAL 等
+	TickSize   string `json:"tickSize"`   // PRICE_FILTER: 价格最小变动单位
+	StepSize   string `json:"stepSize"`   // LOT_SIZE: 数量最小变动单位
+	Notional   string `json:"notional"`   // MIN_NOTIONAL: 最小名义价值
+}
+
+// BookTickerREST 最优挂单 REST 接口返回的数据结构
+type BookTickerREST struct {
+	Symbol   string `json:"symbol"`   // 交易对
+	BidPrice string `json:"bidPrice"` // 买单最优挂单价格
+	AskPrice string `json:"askPrice"` // 卖单最优挂单价格
+	Time     int64  `json:"time"`     // 撮合时间
+}
+
 // PremiumIndexData 标记价格和资金费率数据结构
 type PremiumIndexData struct {
 	Symbol               string `json:"symbol"`               // 交易对
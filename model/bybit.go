@@ -0,0 +1,119 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// BybitRequest Bybit v5 WebSocket 的控制消息
+type BybitRequest struct {
+	ReqID string   `json:"req_id,omitempty"`
+	Op    string   `json:"op"`             // subscribe / unsubscribe / ping
+	Args  []string `json:"args,omitempty"` // 如 tickers.BTCUSDT
+}
+
+// BybitMessage Bybit v5 公共频道的推送，行情推送带 topic，控制消息的响应带 op
+type BybitMessage struct {
+	Topic   string          `json:"topic"` // 如 tickers.BTCUSDT
+	Type    string          `json:"type"`  // snapshot / delta
+	Ts      int64           `json:"ts"`    // 推送时间（Unix毫秒）
+	Data    json.RawMessage `json:"data"`
+	Op      string          `json:"op"`
+	Success *bool           `json:"success"`
+	RetMsg  string          `json:"ret_msg"`
+}
+
+// BybitTicker tickers 频道的数据，delta 推送只包含变化的字段
+type BybitTicker struct {
+	Symbol          string `json:"symbol"`          // 合约名称，如 BTCUSDT
+	Bid1Price       string `json:"bid1Price"`       // 买一价
+	Ask1Price       string `json:"ask1Price"`       // 卖一价
+	LastPrice       string `json:"lastPrice"`       // 最新成交价
+	MarkPrice       string `json:"markPrice"`       // 标记价格
+	FundingRate     string `json:"fundingRate"`     // 资金费率
+	NextFundingTime string `json:"nextFundingTime"` // 下次资金费时间（Unix毫秒）
+}
+
+// Merge 将 delta 推送中非空的字段合并到当前数据
+func (t BybitTicker) Merge(delta BybitTicker) BybitTicker {
+	if delta.Bid1Price != "" {
+		t.Bid1Price = delta.Bid1Price
+	}
+	if delta.Ask1Price != "" {
+		t.Ask1Price = delta.Ask1Price
+	}
+	if delta.LastPrice != "" {
+		t.LastPrice = delta.LastPrice
+	}
+	if delta.MarkPrice != "" {
+		t.MarkPrice = delta.MarkPrice
+	}
+	if delta.FundingRate != "" {
+		t.FundingRate = delta.FundingRate
+	}
+	if delta.NextFundingTime != "" {
+		t.NextFundingTime = delta.NextFundingTime
+	}
+	return t
+}
+
+// BybitResponse Bybit v5 REST 接口的通用返回结构
+type BybitResponse struct {
+	RetCode int             `json:"retCode"`
+	RetMsg  string          `json:"retMsg"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// BybitTickerList tickers 接口的 result
+type BybitTickerList struct {
+	List []BybitTicker `json:"list"`
+}
+
+// BybitKlineList kline 接口的 result，每根K线为 [开盘时间, 开, 高, 低, 收, 成交量, 成交额]，按时间倒序
+type BybitKlineList struct {
+	List [][]string `json:"list"`
+}
+
+// BybitInstrumentList instruments-info 接口的 result
+type BybitInstrumentList struct {
+	List           []BybitInstrument `json:"list"`
+	NextPageCursor string            `json:"nextPageCursor"`
+}
+
+// BybitInstrument 单个合约的交易规则
+type BybitInstrument struct {
+	Symbol       string `json:"symbol"`
+	ContractType string `json:"contractType"` // 永续为 LinearPerpetual
+	Status       string `json:"status"`       // 交易状态，如 Trading、Settling
+	BaseCoin     string `json:"baseCoin"`
+	QuoteCoin    string `json:"quoteCoin"`
+	SettleCoin   string `json:"settleCoin"`
+	PriceFilter  struct {
+		TickSize string `json:"tickSize"`
+	} `json:"priceFilter"`
+	LotSizeFilter struct {
+		QtyStep          string `json:"qtyStep"`
+		MinNotionalValue string `json:"minNotionalValue"`
+	} `json:"lotSizeFilter"`
+}
+
+// NewBybitSymbolFilter 由 Bybit 合约交易规则生成 SymbolFilter，只支持 USDT/USDC 永续合约。
+// Bybit 的 Trading 状态对应 SymbolStatusTrading
+func NewBybitSymbolFilter(instrument BybitInstrument) (SymbolFilter, bool) {
+	if instrument.ContractType != "LinearPerpetual" || instrument.BaseCoin == "" || instrument.QuoteCoin == "" {
+		return SymbolFilter{}, false
+	}
+	filter := SymbolFilter{
+		Symbol:         instrument.Symbol,
+		Pair:           instrument.BaseCoin + "/" + instrument.QuoteCoin + ":" + instrument.SettleCoin,
+		Status:         instrument.Status,
+		PricePrecision: decimalPlaces(instrument.PriceFilter.TickSize),
+	}
+	if instrument.Status == "Trading" {
+		filter.Status = SymbolStatusTrading
+	}
+	filter.TickSize, _ = strconv.ParseFloat(instrument.PriceFilter.TickSize, 64)
+	filter.StepSize, _ = strconv.ParseFloat(instrument.LotSizeFilter.QtyStep, 64)
+	filter.MinNotional, _ = strconv.ParseFloat(instrument.LotSizeFilter.MinNotionalValue, 64)
+	return filter, true
+}
@@ -0,0 +1,77 @@
+package model
+
+import "time"
+
+// CandleTimeframes 支持的K线周期
+var CandleTimeframes = []string{"1m", "5m", "15m", "1h"}
+
+// ATR 限价的默认参数
+const (
+	ATRPeriod           = 14    // ATR 计算使用的K线数量
+	DefaultATRTimeframe = "15m" // 未指定周期时使用的K线周期
+)
+
+// TimeframeDuration 返回K线周期对应的时长
+func TimeframeDuration(timeframe string) (time.Duration, bool) {
+	switch timeframe {
+	case "1m":
+		return time.Minute, true
+	case "5m":
+		return 5 * time.Minute, true
+	case "15m":
+		return 15 * time.Minute, true
+	case "1h":
+		return time.Hour, true
+	}
+	return 0, false
+}
+
+// Candle 由最优挂单推送聚合的K线，OHLC 使用中间价
+type Candle struct {
+	OpenTime int64   `json:"open_time"` // 开盘时间（Unix毫秒）
+	Open     float64 `json:"open"`
+	High     float64 `json:"high"`
+	Low      float64 `json:"low"`
+	Close    float64 `json:"close"`
+	BidLow   float64 `json:"bid_low"`   // 周期内最低买一价
+	BidClose float64 `json:"bid_close"` // 收盘时的买一价
+	AskHigh  float64 `json:"ask_high"`  // 周期内最高卖一价
+	AskClose float64 `json:"ask_close"` // 收盘时的卖一价
+	Ticks    int     `json:"ticks"`     // 周期内的推送次数
+	Closed   bool    `json:"closed"`    // 是否已收盘
+}
+
+// ATR 用已收盘K线计算平均真实波幅（Wilder 平滑），需要至少 period+1 根已收盘K线
+func ATR(candles []Candle, period int) (float64, bool) {
+	if period <= 0 {
+		return 0, false
+	}
+	var closed []Candle
+	for i := range candles {
+		if candles[i].Closed {
+			closed = append(closed, candles[i])
+		}
+	}
+	if len(closed) < period+1 {
+		return 0, false
+	}
+
+	atr := 0.0
+	for i := 1; i < len(closed); i++ {
+		prevClose := closed[i-1].Close
+		tr := max(closed[i].High-closed[i].Low, abs(closed[i].High-prevClose), abs(closed[i].Low-prevClose))
+		if i <= period {
+			atr += tr / float64(period)
+			continue
+		}
+		atr = (atr*float64(period-1) + tr) / float64(period)
+	}
+	return atr, true
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
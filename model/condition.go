@@ -0,0 +1,25 @@
+package model
+
+// 触发条件类型
+const (
+	ConditionLevel        = "level"         // 价格越过监控的限价（做空看卖一价，做多看买一价）
+	ConditionPriceAbove   = "price_above"   // 卖一价高于 Value
+	ConditionPriceBelow   = "price_below"   // 买一价低于 Value
+	ConditionCrossUp      = "cross_up"      // 中间价由下向上穿越 Value
+	ConditionCrossDown    = "cross_down"    // 中间价由上向下穿越 Value
+	ConditionPercentAbove = "percent_above" // 中间价高于 Reference 至少 Value%
+	ConditionPercentBelow = "percent_below" // 中间价低于 Reference 至少 Value%
+	ConditionSpreadBelow  = "spread_below"  // 买卖价差占中间价的百分比低于 Value
+	ConditionFundingAbove = "funding_above" // 资金费率(%)高于 Value
+	ConditionFundingBelow = "funding_below" // 资金费率(%)低于 Value
+	ConditionAnd          = "and"           // 所有子条件均满足
+	ConditionOr           = "or"            // 任一子条件满足
+)
+
+// Condition 监控触发条件，可通过 and/or 组合
+type Condition struct {
+	Type       string      `json:"type"`
+	Value      float64     `json:"value,omitempty"`
+	Reference  float64     `json:"reference,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "encoding/json"
+
 type TradePosition struct {
 	TradeId              int          `json:"trade_id"`
 	Pair                 string       `json:"pair"`
@@ -74,6 +76,20 @@ type TradePosition struct {
 	TotalProfitRatio     float64      `json:"total_profit_ratio"`
 }
 
+// HasOpenEntryOrder 交易是否还有未成交的入场订单（开仓或加仓），做空的入场订单为 sell，做多为 buy
+func (t TradePosition) HasOpenEntryOrder() bool {
+	side := "buy"
+	if t.IsShort {
+		side = "sell"
+	}
+	for _, order := range t.Orders {
+		if order.IsOpen && order.FtOrderSide == side {
+			return true
+		}
+	}
+	return false
+}
+
 type TradeOrder struct {
 	Pair                 string   `json:"pair"`
 	OrderId              string   `json:"order_id"`
@@ -105,11 +121,20 @@ type PositionStatus struct {
 }
 
 type ForceBuyPayload struct {
-	Pair      string  `json:"pair"`      // 如 ETH/USDT:USDT
-	Price     float64 `json:"price"`     // 限价
-	OrderType string  `json:"ordertype"` // "limit" 或 "market"
-	Side      string  `json:"side"`      // "long" 或 "short"
-	EntryTag  string  `json:"entry_tag"` // 自定义标签，例如 "force_entry"
+	Pair        string  `json:"pair"`                  // 如 ETH/USDT:USDT
+	Price       float64 `json:"price"`                 // 限价
+	OrderType   string  `json:"ordertype"`             // "limit" 或 "market"
+	Side        string  `json:"side"`                  // "long" 或 "short"
+	EntryTag    string  `json:"entry_tag"`             // 自定义标签，例如 "force_entry"
+	StakeAmount float64 `json:"stakeamount,omitempty"` // 投入金额，为空时使用 Freqtrade 默认值
+
+	// 以下字段只在本地流转，不发送给 Freqtrade
+	Monitor string `json:"-"` // 触发交易的监控方向，为空时与 Side 相同
+	Rung    int    `json:"-"` // 分批入场的档位序号，从1开始，0 表示不是分批入场
+	Adjust  bool   `json:"-"` // 是否对已有仓位加仓
+	TradeId int    `json:"-"` // 加仓要求的交易ID，0 表示不限定
+
+	MonitorPair string `json:"-"` // 合成交易对的腿：触发交易的监控所在的合成交易对，为空时与 Pair 相同
 }
 
 type ForceAdjustBuyPayload struct {
@@ -122,9 +147,27 @@ type ForceAdjustBuyPayload struct {
 }
 
 type ForceSellPayload struct {
-	TradeId   string `json:"tradeid"`   // 交易ID
-	OrderType string `json:"ordertype"` // "limit" 或 "market"
-	Amount    string `json:"amount"`    // 卖出数量
+	TradeId   string `json:"tradeid"`          // 交易ID
+	OrderType string `json:"ordertype"`        // "limit" 或 "market"
+	Amount    string `json:"amount,omitempty"` // 卖出数量，为空时全部平仓
+}
+
+// DailyResponse daily接口响应结构
+type DailyResponse struct {
+	Data          []DailyProfit `json:"data"`
+	StakeCurrency string        `json:"stake_currency"`
+}
+
+// ShowConfigResponse show_config接口响应结构，只保留需要的字段
+type ShowConfigResponse struct {
+	StakeAmount json.RawMessage `json:"stake_amount"` // 每笔开仓金额，数字或 "unlimited"，部分版本以字符串返回
+}
+
+// DailyProfit 单日的已实现盈亏
+type DailyProfit struct {
+	Date       string  `json:"date"` // 2006-01-02，UTC
+	AbsProfit  float64 `json:"abs_profit"`
+	TradeCount int     `json:"trade_count"`
 }
 
 // WhitelistResponse whitelist接口响应结构
@@ -1,19 +1,137 @@
 package model
 
+import "time"
+
 // PairData 定义了从 Redis 获取的数据结构
 type PairData struct {
-	Timestamp string  `json:"timestamp"`
-	Pair      string  `json:"pair"`
-	BidPrice  float64 `json:"bid_price"`
-	AskPrice  float64 `json:"ask_price"`
-	Close     float64 `json:"close"`
+	Timestamp   string  `json:"timestamp"`
+	Pair        string  `json:"pair"`
+	BidPrice    float64 `json:"bid_price"`
+	AskPrice    float64 `json:"ask_price"`
+	Close       float64 `json:"close"`
+	EventTime   int64   `json:"event_time"`   // 交易所事件时间(毫秒)
+	ReceiveTime int64   `json:"receive_time"` // 本地收到推送的时间(毫秒)
+}
+
+// Age 价格数据距 now 的时长，以本地收到推送的时间为准，避免受本机与交易所时钟偏差影响；
+// 没有收到时间时退回到交易所事件时间，两者都没有时返回 -1
+func (d PairData) Age(now time.Time) time.Duration {
+	ts := d.ReceiveTime
+	if ts <= 0 {
+		ts = d.EventTime
+	}
+	if ts <= 0 {
+		return -1
+	}
+	return now.Sub(time.UnixMilli(ts))
+}
+
+// IsStale 价格数据是否超过 maxAge，maxAge 不大于 0 时不检查；没有时间信息的数据视为过期
+func (d PairData) IsStale(now time.Time, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	age := d.Age(now)
+	return age < 0 || age > maxAge
+}
+
+// 监控生命周期状态
+const (
+	MonitorStateArmed     = "armed"     // 等待触发
+	MonitorStateTriggered = "triggered" // 条件已满足，交易请求已发出
+	MonitorStateSubmitted = "submitted" // forcebuy 已提交到 Freqtrade
+	MonitorStateFilled    = "filled"    // 入场订单已成交
+	MonitorStateFailed    = "failed"    // 仓位校验或提交失败
+	MonitorStateExpired   = "expired"   // 提交后订单被取消或超时未成交
+	MonitorStateCancelled = "cancelled" // 已手动取消（分批入场的单个档位）
+)
+
+// 提醒记录的价格相对提醒价的位置
+const (
+	PriceSideBelow = "below" // 中间价低于提醒价
+	PriceSideAbove = "above" // 中间价高于提醒价
+)
+
+// PriceSideOf 中间价相对提醒价的位置，等于提醒价或没有价格时返回空字符串
+func PriceSideOf(mid, level float64) string {
+	switch {
+	case mid <= 0 || mid == level:
+		return ""
+	case mid < level:
+		return PriceSideBelow
+	default:
+		return PriceSideAbove
+	}
+}
+
+// LadderRung 分批入场的单个档位
+type LadderRung struct {
+	Price       float64 `json:"price"`
+	StakeAmount float64 `json:"stake_amount"`
+	State       string  `json:"state,omitempty"`
+	StateTime   int64   `json:"state_time,omitempty"`
+}
+
+// IsArmed 档位是否处于等待触发状态
+func (r LadderRung) IsArmed() bool {
+	return r.State == "" || r.State == MonitorStateArmed
 }
 
 type PairMonitorData struct {
-	Timestamp string  `json:"timestamp"`
-	Pair      string  `json:"pair"`
-	Direct    string  `json:"direct"`
-	Price     float64 `json:"price"`
+	Timestamp string     `json:"timestamp"`
+	Pair      string     `json:"pair"`
+	Direct    string     `json:"direct"`
+	Price     float64    `json:"price"`
+	Condition *Condition `json:"condition,omitempty"` // 自定义触发条件，为空时使用默认规则
+
+	PriceExpr string  `json:"price_expr,omitempty"` // 相对价格的原始输入，如 -3%、+1.5%、-0.8，为空表示绝对价格
+	PriceRef  float64 `json:"price_ref,omitempty"`  // 解析相对价格时的基准中间价
+
+	ATRMultiple  float64 `json:"atr_multiple,omitempty"`  // 限价距离基准价的 ATR 倍数，负数表示低于基准价，为0表示固定限价
+	ATRTimeframe string  `json:"atr_timeframe,omitempty"` // 计算 ATR 的K线周期
+
+	TrailPercent float64 `json:"trail_percent,omitempty"` // 追踪入场回撤百分比，大于0表示追踪入场
+	TrailExtreme float64 `json:"trail_extreme,omitempty"` // 激活后记录的极值：做空为最高卖价，做多为最低买价
+
+	Ladder []LadderRung `json:"ladder,omitempty"` // 分批入场档位，按离当前价格由近到远排列
+
+	OCO string `json:"oco,omitempty"` // 一方的交易提交成功后自动取消的另一方向监控
+
+	Legs string `json:"legs,omitempty"` // 合成交易对触发后开仓的腿 base/quote/both，为空时两条腿都开
+
+	TradeId     int     `json:"trade_id,omitempty"`     // 止盈/止损/加仓监控关联的 Freqtrade 交易ID
+	Side        string  `json:"side,omitempty"`         // 关联仓位的方向 long/short
+	ExitPercent float64 `json:"exit_percent,omitempty"` // 离场触发后平仓的比例(%)，为0时全部平仓
+	StakeAmount float64 `json:"stake_amount,omitempty"` // 加仓触发后投入的金额
+
+	RepeatSeconds int    `json:"repeat_seconds,omitempty"` // 提醒的重复间隔，为0时只提醒一次
+	PriceSide     string `json:"price_side,omitempty"`     // 提醒最近一次看到的价格在提醒价哪一侧 below/above，设置时记录，从另一侧越过提醒价才触发
+
+	Rearm         bool `json:"rearm,omitempty"`          // 入场成交的交易平仓后自动按原限价重新设置该监控
+	RearmCooldown int  `json:"rearm_cooldown,omitempty"` // 平仓后重新设置前的冷却秒数
+
+	CandleClose string `json:"candle_close,omitempty"` // 按该周期K线收盘价判断触发，为空时按每次推送判断
+
+	ConfirmTicks   int `json:"confirm_ticks,omitempty"`   // 需要连续满足条件的推送次数
+	ConfirmSeconds int `json:"confirm_seconds,omitempty"` // 需要持续满足条件的秒数
+
+	State     string `json:"state,omitempty"`      // 生命周期状态，为空视为 armed
+	StateTime int64  `json:"state_time,omitempty"` // 进入当前状态的时间（Unix秒）
+}
+
+// HasTrade 是否为关联已有交易的监控（止盈/止损/加仓）
+func (d PairMonitorData) HasTrade() bool {
+	return d.TradeId > 0
+}
+
+// ATRLevel 按当前 ATR 计算的有效限价
+func (d PairMonitorData) ATRLevel(atr float64) float64 {
+	return d.PriceRef + d.ATRMultiple*atr
+}
+
+// IsArmed 监控是否处于等待触发状态，兼容没有状态字段的旧数据
+func (d PairMonitorData) IsArmed() bool {
+	return d.State == "" || d.State == MonitorStateArmed
 }
 
 type PairMonitorDataDetail struct {
@@ -22,7 +140,26 @@ type PairMonitorDataDetail struct {
 	TTL             float64         `json:"ttl"` // TTL in seconds
 }
 
+// PairPriceAge 交易对价格数据的时效，用于排查行情推送中断
+type PairPriceAge struct {
+	Pair        string  `json:"pair"`
+	BidPrice    float64 `json:"bid_price"`
+	AskPrice    float64 `json:"ask_price"`
+	EventTime   int64   `json:"event_time"`
+	ReceiveTime int64   `json:"receive_time"`
+	AgeMs       int64   `json:"age_ms"`     // 距本地收到推送的毫秒数
+	LatencyMs   int64   `json:"latency_ms"` // 交易所事件时间到本地收到的毫秒数
+	Stale       bool    `json:"stale"`
+}
+
 type PairMonitorDataWithTTL struct {
 	PairMonitorData PairMonitorData
 	TTL             float64 `json:"ttl"` // TTL in seconds
 }
+
+// ConfirmProgress 确认窗口的进度，只保存在本地内存
+type ConfirmProgress struct {
+	Ticks      int   `json:"ticks"`       // 已连续满足条件的推送次数
+	Since      int64 `json:"since"`       // 开始满足条件的时间（Unix毫秒）
+	CandleTime int64 `json:"candle_time"` // 最近一次评估的K线开盘时间（Unix毫秒）
+}
@@ -0,0 +1,7 @@
+package model
+
+// PaperState 模拟交易的持久化数据，交易结构与 Freqtrade /status 返回的一致
+type PaperState struct {
+	NextTradeId int             `json:"next_trade_id"`
+	Trades      []TradePosition `json:"trades"` // 包含未平仓和已平仓的交易
+}
@@ -0,0 +1,16 @@
+package model
+
+// RearmTimer 入场成交后等待重新设置的监控：关联交易平仓后开始冷却，冷却结束后按原限价重新布防
+type RearmTimer struct {
+	Monitor  PairMonitorData `json:"monitor"`
+	TradeId  int             `json:"trade_id"`            // 入场成交的 Freqtrade 交易ID
+	ExitTime int64           `json:"exit_time,omitempty"` // 关联交易平仓的时间（Unix秒），0 表示仍在持仓
+}
+
+// ReadyTime 冷却结束、可以重新设置监控的时间（Unix秒），交易未平仓时返回 0
+func (t RearmTimer) ReadyTime() int64 {
+	if t.ExitTime == 0 {
+		return 0
+	}
+	return t.ExitTime + int64(t.Monitor.RearmCooldown)
+}
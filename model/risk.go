@@ -0,0 +1,13 @@
+package model
+
+// CircuitBreaker 风控熔断状态，触发后停止所有自动开仓，直到手动重置
+type CircuitBreaker struct {
+	Reason string `json:"reason"`
+	Time   int64  `json:"time"` // 触发时间（Unix秒）
+}
+
+// PauseState 自动交易暂停状态，暂停期间价格照常更新但不提交入场交易
+type PauseState struct {
+	Source string `json:"source"` // 暂停来源: telegram / http
+	Time   int64  `json:"time"`   // 暂停时间（Unix秒）
+}
@@ -0,0 +1,176 @@
+package model
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SymbolStatusTrading 合约可以正常交易的状态
+const SymbolStatusTrading = "TRADING"
+
+// SymbolFilter 交易所合约的交易规则
+type SymbolFilter struct {
+	Symbol         string  `json:"symbol"`          // 交易所合约名称，如 BTCUSDT
+	Pair           string  `json:"pair"`            // Freqtrade 交易对，如 BTC/USDT:USDT
+	Status         string  `json:"status"`          // 交易状态
+	TickSize       float64 `json:"tick_size"`       // 价格最小变动单位
+	StepSize       float64 `json:"step_size"`       // 数量最小变动单位
+	MinNotional    float64 `json:"min_notional"`    // 最小名义价值
+	PricePrecision int     `json:"price_precision"` // tickSize 的小数位数
+}
+
+// NewSymbolFilter 由交易所返回的交易规则生成 SymbolFilter，只支持永续合约
+func NewSymbolFilter(symbol ExchangeSymbol) (SymbolFilter, bool) {
+	if symbol.ContractType != "PERPETUAL" || symbol.BaseAsset == "" || symbol.QuoteAsset == "" {
+		return SymbolFilter{}, false
+	}
+	filter := SymbolFilter{
+		Symbol: symbol.Symbol,
+		Pair:   symbol.BaseAsset + "/" + symbol.QuoteAsset + ":" + symbol.MarginAsset,
+		Status: symbol.Status,
+	}
+	for _, f := range symbol.Filters {
+		switch f.FilterType {
+		case "PRICE_FILTER":
+			filter.TickSize, _ = strconv.ParseFloat(f.TickSize, 64)
+			filter.PricePrecision = decimalPlaces(f.TickSize)
+		case "LOT_SIZE":
+			filter.StepSize, _ = strconv.ParseFloat(f.StepSize, 64)
+		case "MIN_NOTIONAL":
+			filter.MinNotional, _ = strconv.ParseFloat(f.Notional, 64)
+		}
+	}
+	return filter, true
+}
+
+// IsTrading 合约是否可以交易
+func (f SymbolFilter) IsTrading() bool {
+	return f.Status == SymbolStatusTrading
+}
+
+// RoundPrice 将价格按 tickSize 四舍五入，并去掉浮点误差
+func (f SymbolFilter) RoundPrice(price float64) float64 {
+	if f.TickSize <= 0 {
+		return price
+	}
+	rounded := math.Round(price/f.TickSize) * f.TickSize
+	scale := math.Pow10(f.PricePrecision)
+	return math.Round(rounded*scale) / scale
+}
+
+// decimalPlaces 十进制字符串去掉末尾的 0 之后的小数位数，如 "0.0100" 为 2
+func decimalPlaces(value string) int {
+	_, fraction, found := strings.Cut(value, ".")
+	if !found {
+		return 0
+	}
+	return len(strings.TrimRight(fraction, "0"))
+}
+
+// 交易所合约的报价资产，按优先级排列，无法从元数据识别时用于拆分合约名称
+var quoteAssets = []string{"USDT", "USDC", "BTC", "ETH"}
+
+// 交易所对低价币种使用的数量前缀，如 PEPE 对应 1000PEPE
+var basePrefixes = []string{"", "1000", "10000", "1000000", "1M"}
+
+// SymbolRegistry Freqtrade 交易对（ccxt 格式，如 BTC/USDT:USDT）与交易所合约名称（如 BTCUSDT）的映射，
+// 由交易所元数据生成，创建后只读，可以在多个协程中共享
+type SymbolRegistry struct {
+	byPair   map[string]SymbolFilter
+	bySymbol map[string]string
+}
+
+// NewSymbolRegistry 由交易规则生成交易对映射
+func NewSymbolRegistry(filters []SymbolFilter) *SymbolRegistry {
+	r := &SymbolRegistry{
+		byPair:   make(map[string]SymbolFilter, len(filters)),
+		bySymbol: make(map[string]string, len(filters)),
+	}
+	for _, filter := range filters {
+		r.byPair[filter.Pair] = filter
+		r.bySymbol[filter.Symbol] = filter.Pair
+	}
+	return r
+}
+
+// Len 已知的合约数量，0 表示还没有加载交易所元数据
+func (r *SymbolRegistry) Len() int {
+	return len(r.byPair)
+}
+
+// Filter 获取交易对的交易规则
+func (r *SymbolRegistry) Filter(pair string) (SymbolFilter, bool) {
+	filter, exists := r.byPair[pair]
+	return filter, exists
+}
+
+// Pair 交易所合约名称对应的交易对，如 1000PEPEUSDT -> 1000PEPE/USDT:USDT，
+// 元数据中没有时按报价资产拆分，无法识别时原样返回
+func (r *SymbolRegistry) Pair(symbol string) string {
+	if pair, exists := r.bySymbol[symbol]; exists {
+		return pair
+	}
+	for _, quote := range quoteAssets {
+		if base, found := strings.CutSuffix(symbol, quote); found && base != "" {
+			return base + "/" + quote + ":" + quote
+		}
+	}
+	return symbol
+}
+
+// Symbol 交易对对应的交易所合约名称，如 BTC/USDT:USDT -> BTCUSDT
+func (r *SymbolRegistry) Symbol(pair string) string {
+	if filter, exists := r.byPair[pair]; exists {
+		return filter.Symbol
+	}
+	pair, _, _ = strings.Cut(pair, ":")
+	return strings.ReplaceAll(pair, "/", "")
+}
+
+// Resolve 将 Telegram 等处的输入解析为交易对，支持 pepe、1000PEPE、ETHUSDC、BTC/USDT 和完整的 BTC/USDT:USDT；
+// 斜杠两边都不是稳定币且交易所没有对应合约时视为合成交易对原样返回，如 ETH/BTC
+func (r *SymbolRegistry) Resolve(input string) string {
+	input = strings.ToUpper(strings.TrimSpace(input))
+	if strings.Contains(input, ":") {
+		return input
+	}
+	if base, quote, found := strings.Cut(input, "/"); found {
+		// 斜杠两边都不是稳定币时总是合成交易对，真实合约用 ETHBTC 或 ETH/BTC:BTC 指定
+		if IsSyntheticPair(input) {
+			return input
+		}
+		if pair, ok := r.resolveBase(base, quote); ok {
+			return pair
+		}
+		return base + "/" + quote + ":" + quote
+	}
+
+	// 完整的合约名称，如 ETHUSDC、1000PEPEUSDT
+	if pair, exists := r.bySymbol[input]; exists {
+		return pair
+	}
+	// 只输入币种，默认 USDT 永续
+	if pair, ok := r.resolveBase(input, "USDT"); ok {
+		return pair
+	}
+	if r.Len() == 0 {
+		// 还没有元数据时按合约名称的报价资产拆分
+		for _, quote := range quoteAssets {
+			if base, found := strings.CutSuffix(input, quote); found && base != "" {
+				return base + "/" + quote + ":" + quote
+			}
+		}
+	}
+	return input + "/USDT:USDT"
+}
+
+// resolveBase 按币种和报价资产查找合约，依次尝试交易所的数量前缀
+func (r *SymbolRegistry) resolveBase(base, quote string) (string, bool) {
+	for _, prefix := range basePrefixes {
+		if pair, exists := r.bySymbol[prefix+base+quote]; exists {
+			return pair, true
+		}
+	}
+	return "", false
+}
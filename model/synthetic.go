@@ -0,0 +1,87 @@
+package model
+
+import "strings"
+
+// 合成交易对触发后开仓的腿
+const (
+	SyntheticLegBase  = "base"  // 只开分子交易对
+	SyntheticLegQuote = "quote" // 只开分母交易对
+	SyntheticLegBoth  = "both"  // 两条腿同时开仓
+)
+
+// SyntheticLeg 合成交易对触发后的一条腿
+type SyntheticLeg struct {
+	Pair string // 如 ETH/USDT:USDT
+	Side string // long/short
+}
+
+// ParseSyntheticPair 解析合成交易对，如 ETH/BTC 由 ETH/USDT:USDT 和 BTC/USDT:USDT 计算（分母为稳定币时不是合成交易对），
+// 返回分子和分母对应的 USDT 永续交易对
+func ParseSyntheticPair(pair string) (string, string, bool) {
+	if strings.Contains(pair, ":") {
+		return "", "", false
+	}
+	base, quote, found := strings.Cut(pair, "/")
+	if !found || base == "" || quote == "" || quote == "USDT" || quote == "USDC" || base == quote || strings.Contains(quote, "/") {
+		return "", "", false
+	}
+	return base + "/USDT:USDT", quote + "/USDT:USDT", true
+}
+
+// IsSyntheticPair 是否为合成交易对
+func IsSyntheticPair(pair string) bool {
+	_, _, ok := ParseSyntheticPair(pair)
+	return ok
+}
+
+// SyntheticPairData 由两条腿的买卖价计算合成交易对的买卖价：
+// 卖出合成交易对即卖出分子买入分母，买一价为 分子买一/分母卖一，卖一价为 分子卖一/分母买一
+func SyntheticPairData(pair string, base, quote PairData) PairData {
+	if base.BidPrice <= 0 || base.AskPrice <= 0 || quote.BidPrice <= 0 || quote.AskPrice <= 0 {
+		return PairData{}
+	}
+	data := PairData{
+		Timestamp: base.Timestamp,
+		Pair:      pair,
+		BidPrice:  base.BidPrice / quote.AskPrice,
+		AskPrice:  base.AskPrice / quote.BidPrice,
+	}
+	if quote.Timestamp > data.Timestamp {
+		data.Timestamp = quote.Timestamp
+	}
+	// 比价的时效取决于较旧的一条腿
+	data.EventTime = olderTime(base.EventTime, quote.EventTime)
+	data.ReceiveTime = olderTime(base.ReceiveTime, quote.ReceiveTime)
+	data.Close = (data.BidPrice + data.AskPrice) / 2
+	return data
+}
+
+// SyntheticLegs 合成交易对监控触发后需要开仓的腿：
+// 做多比价为做多分子、做空分母，做空比价反之
+func (d PairMonitorData) SyntheticLegs() []SyntheticLeg {
+	base, quote, ok := ParseSyntheticPair(d.Pair)
+	if !ok {
+		return nil
+	}
+	baseSide, quoteSide := "long", "short"
+	if d.Direct == "short" {
+		baseSide, quoteSide = "short", "long"
+	}
+
+	var legs []SyntheticLeg
+	if d.Legs != SyntheticLegQuote {
+		legs = append(legs, SyntheticLeg{Pair: base, Side: baseSide})
+	}
+	if d.Legs != SyntheticLegBase {
+		legs = append(legs, SyntheticLeg{Pair: quote, Side: quoteSide})
+	}
+	return legs
+}
+
+// olderTime 返回两个毫秒时间中较早的一个，任一为 0 表示没有时间信息
+func olderTime(a, b int64) int64 {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	return min(a, b)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"monitor-trade/config"
+	"monitor-trade/controller"
+	"monitor-trade/controller/binance"
+	"monitor-trade/controller/freqtrade"
+	"monitor-trade/controller/redis"
+	"monitor-trade/model"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runReplay 回放模式：REPLAY_FILES=... REPLAY_MONITORS=monitors.json monitor-trade
+// 监控来自 REPLAY_MONITORS，只保存在本地；交易请求总是交给模拟执行器，不连接 Redis、Telegram 和 Freqtrade，
+// 回放结束后输出监控状态和模拟交易
+func runReplay(conf *config.Config) {
+	if conf.Exchange != "binance" {
+		log.Fatalf("行情回放只支持 binance，当前 EXCHANGE=%s", conf.Exchange)
+	}
+	if conf.Record.ReplayMonitors == "" {
+		log.Fatal("回放需要设置 REPLAY_MONITORS 指定监控定义文件")
+	}
+	monitors, err := readMonitors(conf.Record.ReplayMonitors)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 回放的交易请求不能发送到 Freqtrade
+	if !conf.Paper.Enabled {
+		log.Println("回放模式总是使用模拟交易")
+		conf.Paper.Enabled = true
+	}
+	redisController := redis.NewLocalRedisController(conf)
+	for _, data := range monitors {
+		redisController.SetLocalMonitorPair(data)
+	}
+
+	// Telegram 消息只写入日志
+	messageChan := make(chan string, 1000)
+	go func() {
+		for message := range messageChan {
+			log.Printf("Telegram 消息: %s", message)
+		}
+	}()
+
+	binanceController := binance.NewBinanceController()
+	binanceController.SetRedisController(redisController)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tradeChan := make(chan model.ForceBuyPayload, 1000)
+	freqtradeController := freqtrade.NewFreqtradeController(conf.BotBaseUrl, conf.BotUsername, conf.BotPasswd, redisController)
+	freqtradeController.SetRiskConfig(conf.Risk)
+	freqtradeController.SetPaperExecutor(freqtrade.NewPaperExecutor(redisController, conf.Paper))
+	freqtradeController.Init(messageChan)
+	go freqtradeController.HandleTradeChan(ctx, tradeChan)
+
+	mainController := controller.NewMainController(nil, redisController, conf, binanceController, freqtradeController, tradeChan)
+	mainController.UseRecordedTime()
+	mainController.SetFundingRate(conf.Record.ReplayFunding)
+	go mainController.Start()
+
+	if err := binanceController.Replay(mainController.WatchKey, conf.Record.ReplaySpeed, strings.Split(conf.Record.ReplayFiles, ",")...); err != nil {
+		log.Fatalf("回放录制文件失败: %v", err)
+	}
+	// 等待最后几条推送触发的交易请求处理完
+	time.Sleep(time.Second)
+	freqtradeController.Stop()
+
+	printReplaySummary(redisController.ListMonitorPairs(), freqtradeController.GetTradeStatus())
+}
+
+// printReplaySummary 以表格输出回放结束时的监控状态和模拟交易
+func printReplaySummary(monitors []model.PairMonitorData, trades []model.TradePosition) {
+	sort.Slice(monitors, func(i, j int) bool {
+		if monitors[i].Pair != monitors[j].Pair {
+			return monitors[i].Pair < monitors[j].Pair
+		}
+		return monitors[i].Direct < monitors[j].Direct
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "交易对\t方向\t限价\t状态\t状态时间")
+	for _, data := range monitors {
+		stateTime := "-"
+		if data.StateTime > 0 {
+			stateTime = time.Unix(data.StateTime, 0).UTC().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.6f\t%s\t%s\n", data.Pair, data.Direct, data.Price, data.State, stateTime)
+	}
+	w.Flush()
+
+	fmt.Printf("\n模拟交易: %d 笔\n", len(trades))
+	for _, trade := range trades {
+		side := "做多"
+		if trade.IsShort {
+			side = "做空"
+		}
+		status := "已平仓"
+		if trade.Amount == 0 {
+			status = "挂单未成交"
+		} else if trade.IsOpen {
+			status = "持仓中"
+		}
+		fmt.Printf("  #%d %s %s 开仓价 %.6f 数量 %.6f %s\n", trade.TradeId, trade.Pair, side, trade.OpenRate, trade.Amount, status)
+	}
+}